@@ -2,12 +2,14 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
@@ -25,6 +27,53 @@ var (
 	Timeout = flag.Int("timeout", 30, "The timeout for the HTTP client used to make requests to the datasource (seconds)")
 )
 
+const (
+	// cursorSigningKeysPathEnv is the environment variable set to the path of a file containing a JSON array
+	// of the keys used to sign and verify cursors, e.g. ["new-key", "previous-key"].
+	cursorSigningKeysPathEnv = "CURSOR_SIGNING_KEYS_PATH"
+
+	// cursorSigningKeysEnv is the environment variable set to a comma-separated list of the keys used to sign
+	// and verify cursors, if cursorSigningKeysPathEnv is not set.
+	cursorSigningKeysEnv = "CURSOR_SIGNING_KEYS"
+
+	// minCursorSigningKeyLength is the minimum length of a cursor signing key, in bytes.
+	minCursorSigningKeyLength = 32
+)
+
+// loadCursorSigningKeys loads the keys used to sign and verify cursors from the file or environment variable.
+// Cursors are signed with the first key, and verified with any of the keys.
+// Returns no keys if neither is set, in which case cursors are not signed.
+func loadCursorSigningKeys() ([][]byte, error) {
+	var values []string
+
+	if path := os.Getenv(cursorSigningKeysPathEnv); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", cursorSigningKeysPathEnv, err)
+		}
+
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("%s must contain a JSON array of strings: %w", cursorSigningKeysPathEnv, err)
+		}
+	} else if value := os.Getenv(cursorSigningKeysEnv); value != "" {
+		values = strings.Split(value, ",")
+	}
+
+	keys := make([][]byte, 0, len(values))
+
+	for i, value := range values {
+		if len(value) < minCursorSigningKeyLength {
+			return nil, fmt.Errorf(
+				"cursor signing key %d must be at least %d bytes long", i, minCursorSigningKeyLength,
+			)
+		}
+
+		keys = append(keys, []byte(value))
+	}
+
+	return keys, nil
+}
+
 func main() {
 	flag.Parse()
 
@@ -37,6 +86,15 @@ func main() {
 
 	timeout := time.Duration(*Timeout) * time.Second
 
+	cursorSigningKeys, err := loadCursorSigningKeys()
+	if err != nil {
+		logger.Fatalf("Failed to load cursor signing keys: %v", err)
+	}
+
+	if len(cursorSigningKeys) == 0 {
+		logger.Printf("Cursor signing is disabled. Set %s or %s to sign cursors", cursorSigningKeysPathEnv, cursorSigningKeysEnv)
+	}
+
 	s := grpc.NewServer()
 	stop := make(chan struct{})
 	adapterServer := server.New(stop)
@@ -45,9 +103,31 @@ func main() {
 	server.RegisterAdapter(
 		adapterServer,
 		"SCIM2.0-1.0.0",
-		scim.NewAdapter(scim.NewClient(&http.Client{
-			Timeout: timeout,
-		})),
+		scim.NewAdapter(
+			scim.NewClient(&http.Client{
+				Timeout: timeout,
+			}),
+			scim.WithWatermarkHandler(func(datasourceAddress, entityExternalID string, next scim.IncrementalSyncConfig) {
+				logger.Printf(
+					"Completed incremental sync of entity %s from %s with high-water mark %s. "+
+						"Set incrementalSync.%s.since to the high-water mark in the datasource config of the next sync",
+					entityExternalID, datasourceAddress, next.Since, entityExternalID,
+				)
+			}),
+			scim.WithCursorSigningKeys(cursorSigningKeys...),
+			scim.WithCoercionFailureHandler(func(datasourceAddress, entityExternalID, attribute string, count int, err error) {
+				logger.Printf(
+					"Removed %d values of attribute %s of entity %s from %s which could not be coerced: %v",
+					count, attribute, entityExternalID, datasourceAddress, err,
+				)
+			}),
+			scim.WithTruncatedGroupMembersHandler(func(datasourceAddress, entityExternalID, groupID string, members, maxMembers int) {
+				logger.Printf(
+					"Ingested only %d of the %d members of group %s of entity %s from %s",
+					maxMembers, members, groupID, entityExternalID, datasourceAddress,
+				)
+			}),
+		),
 	)
 
 	api_adapter_v1.RegisterAdapterServer(s, adapterServer)
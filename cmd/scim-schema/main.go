@@ -0,0 +1,157 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// Command scim-schema generates the entity configurations of a SCIM server's resource types from its
+// "/ResourceTypes" and "/Schemas" endpoints, in the JSON format of the "entity" of a GetPage request.
+//
+// Usage:
+//
+//	SCIM_AUTHORIZATION="Bearer {{token}}" scim-schema -address my.scim.server.com [-resource-types User,Group]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
+	"github.com/sgnl-ai/sample-adapter/pkg/scim"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var (
+	// Address is the address of the SCIM server.
+	Address = flag.String("address", "", "The address of the SCIM server, e.g. my.scim.server.com")
+
+	// ResourceTypes are the names of the resource types to generate entity configurations for.
+	ResourceTypes = flag.String(
+		"resource-types", "", "Comma-separated names of the resource types to generate, e.g. User,Group (default all)",
+	)
+
+	// Timeout is the timeout of each request to the SCIM server (seconds).
+	Timeout = flag.Int("timeout", 30, "The timeout of each request to the SCIM server (seconds)")
+)
+
+// authorizationEnv is the environment variable set to the Authorization header sent to the SCIM server,
+// e.g. "Bearer {{token}}", so that credentials are not passed on the command line.
+const authorizationEnv = "SCIM_AUTHORIZATION"
+
+func main() {
+	flag.Parse()
+
+	logger := log.New(os.Stderr, "scim-schema: ", 0)
+
+	if *Address == "" {
+		logger.Fatalf("-address is required")
+	}
+
+	// Like the adapter, plain HTTP is rejected, so that the Authorization header is not sent in cleartext.
+	if strings.HasPrefix(*Address, "http://") {
+		logger.Fatalf("-address must use HTTPS, as the Authorization header would be sent in cleartext")
+	}
+
+	baseURL := *Address
+	if !strings.HasPrefix(baseURL, "https://") {
+		baseURL = "https://" + baseURL
+	}
+
+	client := &scim.Datasource{
+		Client: &http.Client{
+			Timeout: time.Duration(*Timeout) * time.Second,
+		},
+	}
+
+	request := &scim.Request{
+		BaseURL:               strings.TrimSuffix(baseURL, "/"),
+		AuthorizationHeader:   os.Getenv(authorizationEnv),
+		RequestTimeoutSeconds: *Timeout,
+		MaxResponseBodyBytes:  scim.DefaultMaxResponseBodyBytes,
+	}
+
+	ctx := context.Background()
+
+	resourceTypes, err := client.GetResourceTypes(ctx, request)
+	if err != nil {
+		logger.Fatalf("Failed to get resource types: %s", err.Message)
+	}
+
+	schemas, err := client.GetSchemas(ctx, request)
+	if err != nil {
+		logger.Fatalf("Failed to get schemas: %s", err.Message)
+	}
+
+	var names []string
+	if *ResourceTypes != "" {
+		names = strings.Split(*ResourceTypes, ",")
+	}
+
+	entities := make([]json.RawMessage, 0, len(resourceTypes))
+
+	for i := range resourceTypes {
+		resourceType := &resourceTypes[i]
+
+		if names != nil && !slices.ContainsFunc(names, func(name string) bool {
+			return strings.EqualFold(strings.TrimSpace(name), resourceType.Name)
+		}) {
+			continue
+		}
+
+		entity, entityErr := scim.EntityConfig(resourceType, schemas)
+		if entityErr != nil {
+			logger.Fatalf("Failed to generate entity configuration: %v", entityErr)
+		}
+
+		data, marshalErr := protojson.MarshalOptions{UseProtoNames: true}.Marshal(entityConfigProto(entity, ""))
+		if marshalErr != nil {
+			logger.Fatalf("Failed to marshal entity configuration: %v", marshalErr)
+		}
+
+		entities = append(entities, data)
+	}
+
+	output, marshalErr := json.MarshalIndent(entities, "", "    ")
+	if marshalErr != nil {
+		logger.Fatalf("Failed to marshal entity configurations: %v", marshalErr)
+	}
+
+	fmt.Println(string(output))
+}
+
+// entityConfigProto converts an entity configuration into its GetPage request representation.
+// Entity and attribute IDs are generated by SGNL, so they are set to the external IDs, prefixed with the ID of
+// the parent entity for child entities, and with the ID of their entity for attributes, so that the IDs of
+// sub-attributes such as "value" are unique across child entities.
+func entityConfigProto(entity *framework.EntityConfig, parentID string) *api_adapter_v1.EntityConfig {
+	id := entity.ExternalId
+	if parentID != "" {
+		id = parentID + "." + id
+	}
+
+	config := &api_adapter_v1.EntityConfig{
+		Id:         id,
+		ExternalId: entity.ExternalId,
+	}
+
+	for _, attribute := range entity.Attributes {
+		config.Attributes = append(config.Attributes, &api_adapter_v1.AttributeConfig{
+			Id:         id + "." + attribute.ExternalId,
+			ExternalId: attribute.ExternalId,
+			Type:       api_adapter_v1.AttributeType(attribute.Type),
+			List:       attribute.List,
+			UniqueId:   attribute.UniqueId,
+		})
+	}
+
+	for _, childEntity := range entity.ChildEntities {
+		config.ChildEntities = append(config.ChildEntities, entityConfigProto(childEntity, id))
+	}
+
+	return config
+}
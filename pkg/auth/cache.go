@@ -0,0 +1,315 @@
+// Copyright 2025 SGNL.ai, Inc.
+package auth
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TokenExpiryMargin is the time before their expiry when cached access tokens are no longer used, so that
+// tokens do not expire while requests are in flight, or due to clock skew with the token endpoint.
+// Tokens whose lifetime is shorter than twice the margin are used for half their lifetime instead, so that they
+// are still cached.
+var TokenExpiryMargin = time.Minute
+
+// DefaultTokenCacheSize is the maximum number of access tokens cached by a TokenCache if MaxSize is not set.
+const DefaultTokenCacheSize = 1000
+
+// TokenCacheKey returns the key of the access tokens requested for a datasource address with an OAuth2Config,
+// i.e. a hash of the address and of the client's credentials, grant and requested scopes. The credentials are
+// hashed, so they are not retained by the TokenCache, and a change of credentials results in a new key.
+func TokenCacheKey(address string, config *OAuth2Config) string {
+	// An OAuth2Config only contains types which can always be marshalled.
+	credentials, _ := json.Marshal(config)
+
+	hash := sha256.New()
+	hash.Write([]byte(address))
+	hash.Write([]byte{0})
+	hash.Write(credentials)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// TokenCache caches access tokens in memory until shortly before they expire, and ensures that concurrent
+// requests for the access token of the same key only request one access token.
+// The zero value is ready to use.
+type TokenCache struct {
+	// MaxSize is the maximum number of cached access tokens. Once reached, the least recently used access
+	// token is evicted from the cache when another access token is cached.
+	// Optional. Defaults to DefaultTokenCacheSize if not set.
+	MaxSize int
+
+	mu sync.Mutex
+
+	// tokens are the elements of recent, keyed by key.
+	tokens map[string]*list.Element
+
+	// recent is the list of cached access tokens, from the most recently used to the least recently used.
+	recent list.List
+
+	// fetches are the access token requests in flight, keyed by key.
+	fetches map[string]*tokenFetch
+}
+
+// cachedToken is a cached access token.
+type cachedToken struct {
+	key   string
+	token *Token
+
+	// cachedAt is the time when the access token was cached.
+	cachedAt time.Time
+}
+
+// tokenFetch is an access token request in flight, whose result is shared by all the concurrent requests
+// for the access token of the same key.
+type tokenFetch struct {
+	// done is closed once the access token was requested.
+	done chan struct{}
+
+	token *Token
+	err   error
+
+	// canceled is true if the request was canceled by its caller's context.
+	canceled bool
+}
+
+// Get returns the cached access token of a key, if it is valid for at least TokenExpiryMargin, or for at least
+// half its lifetime if shorter.
+func (c *TokenCache) Get(key string) (*Token, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.get(key)
+}
+
+func (c *TokenCache) get(key string) (*Token, bool) {
+	element, found := c.tokens[key]
+	if !found {
+		return nil, false
+	}
+
+	cached := element.Value.(*cachedToken)
+
+	// The margin of short-lived tokens is clamped, otherwise they would expire as soon as they are cached.
+	margin := min(TokenExpiryMargin, cached.token.Expiry.Sub(cached.cachedAt)/2)
+
+	if !cached.token.ValidAt(time.Now().Add(margin)) {
+		c.remove(element)
+
+		return nil, false
+	}
+
+	c.recent.MoveToFront(element)
+
+	return cached.token, true
+}
+
+// Set caches the access token of a key, evicting the least recently used access token if the cache is full.
+func (c *TokenCache) Set(key string, token *Token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, token)
+}
+
+func (c *TokenCache) set(key string, token *Token) {
+	if element, found := c.tokens[key]; found {
+		cached := element.Value.(*cachedToken)
+		cached.token, cached.cachedAt = token, time.Now()
+		c.recent.MoveToFront(element)
+
+		return
+	}
+
+	if c.tokens == nil {
+		c.tokens = make(map[string]*list.Element)
+	}
+
+	c.tokens[key] = c.recent.PushFront(&cachedToken{key: key, token: token, cachedAt: time.Now()})
+
+	maxSize := c.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultTokenCacheSize
+	}
+
+	for c.recent.Len() > maxSize {
+		c.remove(c.recent.Back())
+	}
+}
+
+func (c *TokenCache) remove(element *list.Element) {
+	c.recent.Remove(element)
+	delete(c.tokens, element.Value.(*cachedToken).key)
+}
+
+// Invalidate removes the access token of a key from the cache, if it is still the cached token.
+// A token which was already replaced, e.g. by a concurrent request, is not removed.
+func (c *TokenCache) Invalidate(key string, token *Token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, found := c.tokens[key]; found && element.Value.(*cachedToken).token == token {
+		c.remove(element)
+	}
+}
+
+// Token returns the cached access token of a key, or requests and caches a new access token with the
+// TokenFetcher if none is cached or the cached token is about to expire.
+//
+// Concurrent calls for the same key wait for a single request, and share its access token or error.
+// If the request is canceled by its caller's context, waiting calls whose context is not done request
+// the access token again.
+func (c *TokenCache) Token(ctx context.Context, key string, fetch TokenFetcher) (*Token, error) {
+	for {
+		c.mu.Lock()
+
+		if token, found := c.get(key); found {
+			c.mu.Unlock()
+
+			return token, nil
+		}
+
+		if inFlight, found := c.fetches[key]; found {
+			c.mu.Unlock()
+
+			select {
+			case <-inFlight.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+
+			if inFlight.canceled && ctx.Err() == nil {
+				continue
+			}
+
+			return inFlight.token, inFlight.err
+		}
+
+		if c.fetches == nil {
+			c.fetches = make(map[string]*tokenFetch)
+		}
+
+		current := &tokenFetch{done: make(chan struct{})}
+		c.fetches[key] = current
+
+		c.mu.Unlock()
+
+		current.token, current.err = fetch(ctx)
+		current.canceled = current.err != nil && ctx.Err() != nil
+
+		c.mu.Lock()
+
+		delete(c.fetches, key)
+
+		if current.err == nil {
+			c.set(key, current.token)
+		}
+
+		c.mu.Unlock()
+
+		close(current.done)
+
+		return current.token, current.err
+	}
+}
+
+// TokenFetcherCache caches the TokenFetchers returned by NewTokenFetcher, keyed like a TokenCache, so that the
+// private key of the JWT assertions of an OAuth2Config is only parsed once. The zero value is ready to use.
+type TokenFetcherCache struct {
+	// MaxSize is the maximum number of cached TokenFetchers. Once reached, the least recently used TokenFetcher
+	// is evicted from the cache when another TokenFetcher is cached.
+	// Optional. Defaults to DefaultTokenCacheSize if not set.
+	MaxSize int
+
+	mu sync.Mutex
+
+	// fetchers are the elements of recent, keyed by key.
+	fetchers map[string]*list.Element
+
+	// recent is the list of cached TokenFetchers, from the most recently used to the least recently used.
+	recent list.List
+}
+
+// cachedFetcher is a cached TokenFetcher, and the HTTP client it requests access tokens with.
+type cachedFetcher struct {
+	key    string
+	client *http.Client
+	fetch  TokenFetcher
+}
+
+// Fetcher returns the cached TokenFetcher of a key, or a TokenFetcher built with NewTokenFetcher for the HTTP
+// client and OAuth2Config if none is cached or if the cached TokenFetcher uses another client, e.g. after the
+// TLS config of the token endpoint changed. The key must identify the OAuth2Config, e.g. as returned by
+// TokenCacheKey. Errors are not cached, and are returned as *ConfigError.
+func (c *TokenFetcherCache) Fetcher(key string, client *http.Client, config *OAuth2Config) (TokenFetcher, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, found := c.fetchers[key]
+	if found && element.Value.(*cachedFetcher).client == client {
+		c.recent.MoveToFront(element)
+
+		return element.Value.(*cachedFetcher).fetch, nil
+	}
+
+	fetch, err := NewTokenFetcher(client, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if found {
+		cached := element.Value.(*cachedFetcher)
+		cached.client, cached.fetch = client, fetch
+		c.recent.MoveToFront(element)
+
+		return fetch, nil
+	}
+
+	if c.fetchers == nil {
+		c.fetchers = make(map[string]*list.Element)
+	}
+
+	c.fetchers[key] = c.recent.PushFront(&cachedFetcher{key: key, client: client, fetch: fetch})
+
+	maxSize := c.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultTokenCacheSize
+	}
+
+	for c.recent.Len() > maxSize {
+		evicted := c.recent.Remove(c.recent.Back()).(*cachedFetcher)
+		delete(c.fetchers, evicted.key)
+	}
+
+	return fetch, nil
+}
+
+// TokenSource returns the access tokens of a client, requested with a TokenFetcher and cached in a TokenCache.
+type TokenSource struct {
+	// Key identifies the client in the cache, e.g. as returned by TokenCacheKey.
+	Key string
+
+	// Cache caches the access tokens.
+	Cache *TokenCache
+
+	// Fetch requests a new access token.
+	Fetch TokenFetcher
+}
+
+// Token returns the cached access token, or requests a new access token if none is cached or the cached
+// token is about to expire. Concurrent calls for the same key only request one access token.
+func (s *TokenSource) Token(ctx context.Context) (*Token, error) {
+	return s.Cache.Token(ctx, s.Key, s.Fetch)
+}
+
+// Invalidate removes an access token from the cache, e.g. if it was rejected by the datasource, so that a new
+// access token is requested.
+func (s *TokenSource) Invalidate(token *Token) {
+	s.Cache.Invalidate(s.Key, token)
+}
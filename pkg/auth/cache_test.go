@@ -0,0 +1,305 @@
+// Copyright 2025 SGNL.ai, Inc.
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sgnl-ai/sample-adapter/pkg/auth"
+)
+
+func TestTokenSource(t *testing.T) {
+	var fetches int
+
+	expiry := time.Now().Add(time.Hour)
+
+	source := &auth.TokenSource{
+		Key:   "https://scim.example.com#client",
+		Cache: &auth.TokenCache{},
+		Fetch: func(context.Context) (*auth.Token, error) {
+			fetches++
+
+			return &auth.Token{AccessToken: "token", Expiry: expiry}, nil
+		},
+	}
+
+	// The token is requested once, then cached.
+	first, err := source.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if second, _ := source.Token(context.Background()); second != first || fetches != 1 {
+		t.Errorf("gotFetches: %d, wantFetches: 1", fetches)
+	}
+
+	// An invalidated token is requested again.
+	source.Invalidate(first)
+
+	second, _ := source.Token(context.Background())
+	if second == first || fetches != 2 {
+		t.Errorf("gotFetches: %d, wantFetches: 2", fetches)
+	}
+
+	// Invalidating a token which was already replaced keeps the cached token.
+	source.Invalidate(first)
+
+	if third, _ := source.Token(context.Background()); third != second || fetches != 2 {
+		t.Errorf("gotFetches: %d, wantFetches: 2", fetches)
+	}
+
+	// Tokens whose lifetime is shorter than the margin are cached for half their lifetime.
+	expiry = time.Now().Add(auth.TokenExpiryMargin / 2)
+
+	source.Invalidate(second)
+
+	fourth, _ := source.Token(context.Background())
+	if fetches != 3 {
+		t.Errorf("gotFetches: %d, wantFetches: 3", fetches)
+	}
+
+	if fifth, _ := source.Token(context.Background()); fifth != fourth || fetches != 3 {
+		t.Errorf("gotFetches: %d, wantFetches: 3", fetches)
+	}
+
+	// Tokens past half their lifetime are requested again.
+	expiry = time.Now().Add(200 * time.Millisecond)
+
+	source.Invalidate(fourth)
+	source.Token(context.Background())
+
+	time.Sleep(150 * time.Millisecond)
+
+	if source.Token(context.Background()); fetches != 5 {
+		t.Errorf("gotFetches: %d, wantFetches: 5", fetches)
+	}
+}
+
+func TestTokenSourceError(t *testing.T) {
+	wantErr := &auth.TokenError{StatusCode: 401, ErrorCode: "invalid_client"}
+
+	source := &auth.TokenSource{
+		Key:   "https://scim.example.com#client",
+		Cache: &auth.TokenCache{},
+		Fetch: func(context.Context) (*auth.Token, error) {
+			return nil, wantErr
+		},
+	}
+
+	if _, gotErr := source.Token(context.Background()); !errors.Is(gotErr, wantErr) {
+		t.Errorf("gotErr: %v, wantErr: %v", gotErr, wantErr)
+	}
+
+	if _, found := source.Cache.Get(source.Key); found {
+		t.Errorf("Unexpected cached token")
+	}
+}
+
+func TestTokenCacheKey(t *testing.T) {
+	config := &auth.OAuth2Config{TokenURL: "https://login.example.com/token", ClientID: "client", ClientSecret: "secret"}
+
+	key := auth.TokenCacheKey("https://scim.example.com", config)
+
+	if strings.Contains(key, "secret") || strings.Contains(key, "client") {
+		t.Errorf("Key contains the credentials: %s", key)
+	}
+
+	if key != auth.TokenCacheKey("https://scim.example.com", &auth.OAuth2Config{TokenURL: "https://login.example.com/token", ClientID: "client", ClientSecret: "secret"}) {
+		t.Error("Key is not deterministic")
+	}
+
+	if key == auth.TokenCacheKey("https://scim.example.com", &auth.OAuth2Config{TokenURL: "https://login.example.com/token", ClientID: "client", ClientSecret: "rotated"}) {
+		t.Error("Keys of different credentials are equal")
+	}
+
+	if key == auth.TokenCacheKey("https://other.example.com", config) {
+		t.Error("Keys of different addresses are equal")
+	}
+}
+
+func TestTokenCacheSingleFlight(t *testing.T) {
+	var (
+		cache   auth.TokenCache
+		fetches atomic.Int32
+		wg      sync.WaitGroup
+	)
+
+	release := make(chan struct{})
+
+	fetch := func(context.Context) (*auth.Token, error) {
+		fetches.Add(1)
+
+		<-release
+
+		return &auth.Token{AccessToken: "token"}, nil
+	}
+
+	tokens := make([]*auth.Token, 10)
+
+	for i := range tokens {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			tokens[i], _ = cache.Token(context.Background(), "key", fetch)
+		}()
+	}
+
+	// Wait for the first request to start, and give the other calls time to wait for it.
+	for fetches.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if gotFetches := fetches.Load(); gotFetches != 1 {
+		t.Errorf("gotFetches: %d, wantFetches: 1", gotFetches)
+	}
+
+	for _, token := range tokens {
+		if token != tokens[0] {
+			t.Errorf("gotToken: %v, wantToken: %v", token, tokens[0])
+		}
+	}
+}
+
+func TestTokenCacheCanceledFetch(t *testing.T) {
+	var (
+		cache   auth.TokenCache
+		fetches atomic.Int32
+	)
+
+	started := make(chan struct{})
+
+	fetch := func(ctx context.Context) (*auth.Token, error) {
+		if fetches.Add(1) == 1 {
+			close(started)
+
+			<-ctx.Done()
+
+			return nil, ctx.Err()
+		}
+
+		return &auth.Token{AccessToken: "token"}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go cache.Token(ctx, "key", fetch)
+
+	<-started
+
+	done := make(chan struct{})
+
+	var (
+		gotToken *auth.Token
+		gotErr   error
+	)
+
+	go func() {
+		defer close(done)
+
+		gotToken, gotErr = cache.Token(context.Background(), "key", fetch)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	<-done
+
+	// The waiting call requests the token again, as the first request was canceled by its caller.
+	if gotErr != nil || gotToken == nil || gotToken.AccessToken != "token" {
+		t.Errorf("gotToken: %v, gotErr: %v, wantToken: token", gotToken, gotErr)
+	}
+
+	if gotFetches := fetches.Load(); gotFetches != 2 {
+		t.Errorf("gotFetches: %d, wantFetches: 2", gotFetches)
+	}
+}
+
+func TestTokenCacheEviction(t *testing.T) {
+	cache := &auth.TokenCache{MaxSize: 2}
+
+	a, b, c := &auth.Token{AccessToken: "a"}, &auth.Token{AccessToken: "b"}, &auth.Token{AccessToken: "c"}
+
+	cache.Set("a", a)
+	cache.Set("b", b)
+
+	// Getting a makes b the least recently used token.
+	cache.Get("a")
+	cache.Set("c", c)
+
+	if _, found := cache.Get("b"); found {
+		t.Error("The least recently used token was not evicted")
+	}
+
+	for key, want := range map[string]*auth.Token{"a": a, "c": c} {
+		if got, found := cache.Get(key); !found || got != want {
+			t.Errorf("gotToken: %v, wantToken: %v", got, want)
+		}
+	}
+
+	// Replacing a token does not evict another token.
+	cache.Set("a", &auth.Token{AccessToken: "a2"})
+
+	if _, found := cache.Get("c"); !found {
+		t.Error("A token was evicted when replacing another token")
+	}
+}
+
+func TestTokenFetcherCache(t *testing.T) {
+	cache := &auth.TokenFetcherCache{}
+	client := &http.Client{}
+
+	config := &auth.OAuth2Config{
+		TokenURL:         "https://login.example.com/oauth2/token",
+		ClientID:         "client",
+		ClientAuthMethod: auth.PrivateKeyJWT,
+		Assertion:        &auth.JWTAssertionConfig{PrivateKey: testECPrivateKey},
+	}
+
+	if _, err := cache.Fetcher("key", client, config); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The private key is only parsed once per key and client.
+	config.Assertion.PrivateKey = "not a key"
+
+	if _, err := cache.Fetcher("key", client, config); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// Another client builds another TokenFetcher, parsing the private key again.
+	var configErr *auth.ConfigError
+
+	if _, err := cache.Fetcher("key", &http.Client{}, config); !errors.As(err, &configErr) {
+		t.Errorf("gotErr: %v, wantErr: *auth.ConfigError", err)
+	}
+
+	// Errors are not cached.
+	config.Assertion.PrivateKey = testECPrivateKey
+
+	if _, err := cache.Fetcher("other", client, config); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	config.Assertion.PrivateKey = "not a key"
+
+	if _, err := cache.Fetcher("invalid", client, config); !errors.As(err, &configErr) {
+		t.Errorf("gotErr: %v, wantErr: *auth.ConfigError", err)
+	}
+
+	config.Assertion.PrivateKey = testECPrivateKey
+
+	if _, err := cache.Fetcher("invalid", client, config); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
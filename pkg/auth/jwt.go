@@ -0,0 +1,209 @@
+// Copyright 2025 SGNL.ai, Inc.
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultAssertionLifetime is the lifetime of JWT assertions if not configured.
+const DefaultAssertionLifetime = 5 * time.Minute
+
+// JWTAssertionConfig is the configuration of the JWT assertions signed by the adapter, sent to a token endpoint
+// as an authorization grant or to authenticate the client.
+// https://datatracker.ietf.org/doc/html/rfc7523#section-3
+type JWTAssertionConfig struct {
+	// PrivateKey is the PEM-encoded RSA or ECDSA private key signing the assertions, in PKCS #1, PKCS #8 or
+	// SEC 1 format. RSA keys sign with RS256, and ECDSA keys with ES256, ES384 or ES512 depending on their curve.
+	PrivateKey string `json:"privateKey"`
+
+	// KeyID is the "kid" header of the assertions, identifying the key to the token endpoint.
+	// Optional.
+	KeyID string `json:"keyId,omitempty"`
+
+	// Issuer is the "iss" claim of the assertions.
+	// Optional. Defaults to the client ID if not set.
+	Issuer string `json:"issuer,omitempty"`
+
+	// Subject is the "sub" claim of the assertions, i.e. the principal the access tokens are requested for.
+	// Optional. Defaults to the client ID if not set.
+	Subject string `json:"subject,omitempty"`
+
+	// Audience is the "aud" claim of the assertions.
+	// Optional. Defaults to the token URL if not set.
+	Audience string `json:"audience,omitempty"`
+
+	// LifetimeSeconds is the lifetime of the assertions.
+	// Optional. Defaults to DefaultAssertionLifetime if not set.
+	LifetimeSeconds int `json:"lifetimeSeconds,omitempty"`
+}
+
+// jwtSigner signs JWTs with a private key.
+type jwtSigner struct {
+	key       crypto.Signer
+	algorithm string
+	hash      crypto.Hash
+}
+
+// newJWTSigner parses a PEM-encoded private key, and returns a signer using the JWS algorithm of the key.
+func newJWTSigner(privateKeyPEM string) (*jwtSigner, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return nil, errors.New("the private key is not PEM-encoded")
+	}
+
+	var (
+		key any
+		err error
+	)
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported private key PEM block type %q", block.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < 2048 {
+			return nil, errors.New("RSA private keys must be at least 2048 bits long")
+		}
+
+		return &jwtSigner{key: key, algorithm: "RS256", hash: crypto.SHA256}, nil
+	case *ecdsa.PrivateKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return &jwtSigner{key: key, algorithm: "ES256", hash: crypto.SHA256}, nil
+		case elliptic.P384():
+			return &jwtSigner{key: key, algorithm: "ES384", hash: crypto.SHA384}, nil
+		case elliptic.P521():
+			return &jwtSigner{key: key, algorithm: "ES512", hash: crypto.SHA512}, nil
+		default:
+			return nil, errors.New("unsupported ECDSA private key curve")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T, only RSA and ECDSA keys are supported", key)
+	}
+}
+
+// sign returns a JWT with the provided key ID header and claims, signed with the signer's key.
+// https://datatracker.ietf.org/doc/html/rfc7515#section-3.1
+func (s *jwtSigner) sign(keyID string, claims map[string]any) (string, error) {
+	header := map[string]string{
+		"alg": s.algorithm,
+		"typ": "JWT",
+	}
+
+	if keyID != "" {
+		header["kid"] = keyID
+	}
+
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	encodedClaims, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(encodedHeader) + "." +
+		base64.RawURLEncoding.EncodeToString(encodedClaims)
+
+	var digest []byte
+
+	switch s.hash {
+	case crypto.SHA384:
+		sum := sha512.Sum384([]byte(signingInput))
+		digest = sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512([]byte(signingInput))
+		digest = sum[:]
+	default:
+		sum := sha256.Sum256([]byte(signingInput))
+		digest = sum[:]
+	}
+
+	var signature []byte
+
+	switch key := s.key.(type) {
+	case *ecdsa.PrivateKey:
+		r, sValue, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil {
+			return "", err
+		}
+
+		// ECDSA signatures are the concatenation of R and S, each padded to the size of the curve.
+		// https://datatracker.ietf.org/doc/html/rfc7518#section-3.4
+		size := (key.Curve.Params().BitSize + 7) / 8
+		signature = make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		sValue.FillBytes(signature[size:])
+	default:
+		if signature, err = s.key.Sign(rand.Reader, digest, s.hash); err != nil {
+			return "", err
+		}
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// signAssertion returns a JWT assertion signed by the signer of the configured private key.
+// The issuer and subject default to the client ID, and the audience to the token URL.
+func signAssertion(
+	signer *jwtSigner, config *JWTAssertionConfig, clientID string, tokenURL string, now time.Time,
+) (string, error) {
+	lifetime := DefaultAssertionLifetime
+	if config.LifetimeSeconds > 0 {
+		lifetime = time.Duration(config.LifetimeSeconds) * time.Second
+	}
+
+	// The JWT ID allows the token endpoint to reject replayed assertions.
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
+	claims := map[string]any{
+		"iss": valueOrDefault(config.Issuer, clientID),
+		"sub": valueOrDefault(config.Subject, clientID),
+		"aud": valueOrDefault(config.Audience, tokenURL),
+		"iat": now.Unix(),
+		"exp": now.Add(lifetime).Unix(),
+		"jti": hex.EncodeToString(jti),
+	}
+
+	return signer.sign(config.KeyID, claims)
+}
+
+// valueOrDefault returns the value, or the default value if the value is empty.
+func valueOrDefault[T ~string](value T, defaultValue T) T {
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
@@ -0,0 +1,320 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// nolint: lll
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sgnl-ai/sample-adapter/pkg/auth"
+)
+
+var (
+	testRSAKey = mustGenerateKey(rsa.GenerateKey(rand.Reader, 2048))
+	testECKey  = mustGenerateKey(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	testEC384  = mustGenerateKey(ecdsa.GenerateKey(elliptic.P384(), rand.Reader))
+
+	testRSAPrivateKey   = encodePrivateKey("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(testRSAKey))
+	testECPrivateKey    = encodePrivateKey("EC PRIVATE KEY", mustMarshal(x509.MarshalECPrivateKey(testECKey)))
+	testEC384PrivateKey = encodePrivateKey("PRIVATE KEY", mustMarshal(x509.MarshalPKCS8PrivateKey(testEC384)))
+)
+
+func mustGenerateKey[T any](key T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+}
+
+func mustMarshal(der []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+
+	return der
+}
+
+func encodePrivateKey(blockType string, der []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+}
+
+// verifyJWT verifies the signature of a JWT with a public key, and returns its header and claims.
+func verifyJWT(jwt string, publicKey crypto.PublicKey) (header map[string]any, claims map[string]any, err error) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return nil, nil, fmt.Errorf("invalid JWT %q", jwt)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signingInput := []byte(parts[0] + "." + parts[1])
+
+	switch publicKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(signingInput)
+
+		if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, nil, err
+		}
+	case *ecdsa.PublicKey:
+		var digest []byte
+
+		switch publicKey.Curve {
+		case elliptic.P384():
+			sum := sha512.Sum384(signingInput)
+			digest = sum[:]
+		default:
+			sum := sha256.Sum256(signingInput)
+			digest = sum[:]
+		}
+
+		size := len(signature) / 2
+		r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+
+		if !ecdsa.Verify(publicKey, digest, r, s) {
+			return nil, nil, errors.New("invalid ECDSA signature")
+		}
+	}
+
+	for i, v := range []*map[string]any{&header, &claims} {
+		decoded, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := json.Unmarshal(decoded, v); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return header, claims, nil
+}
+
+func TestNewTokenFetcherJWTAssertion(t *testing.T) {
+	tests := map[string]struct {
+		config       *auth.OAuth2Config
+		publicKey    crypto.PublicKey
+		wantGrant    string
+		wantClientID string
+		wantHeader   map[string]any
+		wantClaims   map[string]any
+		wantLifetime float64
+		wantErr      string
+	}{
+		"jwt_bearer_grant_rsa": {
+			config: &auth.OAuth2Config{
+				GrantType: auth.GrantTypeJWTBearer,
+				Assertion: &auth.JWTAssertionConfig{
+					PrivateKey:      testRSAPrivateKey,
+					KeyID:           "key-1",
+					Issuer:          "adapter@example.com",
+					Subject:         "scim-reader@example.com",
+					Audience:        "https://login.example.com",
+					LifetimeSeconds: 60,
+				},
+			},
+			publicKey:  &testRSAKey.PublicKey,
+			wantGrant:  "urn:ietf:params:oauth:grant-type:jwt-bearer",
+			wantHeader: map[string]any{"alg": "RS256", "typ": "JWT", "kid": "key-1"},
+			wantClaims: map[string]any{
+				"iss": "adapter@example.com",
+				"sub": "scim-reader@example.com",
+				"aud": "https://login.example.com",
+			},
+			wantLifetime: 60,
+		},
+		"jwt_bearer_grant_defaults": {
+			config: &auth.OAuth2Config{
+				GrantType: auth.GrantTypeJWTBearer,
+				ClientID:  "client",
+				Assertion: &auth.JWTAssertionConfig{
+					PrivateKey: testECPrivateKey,
+				},
+			},
+			publicKey:    &testECKey.PublicKey,
+			wantGrant:    "urn:ietf:params:oauth:grant-type:jwt-bearer",
+			wantClientID: "client",
+			wantHeader:   map[string]any{"alg": "ES256", "typ": "JWT"},
+			wantClaims: map[string]any{
+				"iss": "client",
+				"sub": "client",
+				"aud": "{{tokenURL}}",
+			},
+			wantLifetime: auth.DefaultAssertionLifetime.Seconds(),
+		},
+		"private_key_jwt": {
+			config: &auth.OAuth2Config{
+				ClientID:         "client",
+				ClientAuthMethod: auth.PrivateKeyJWT,
+				Assertion: &auth.JWTAssertionConfig{
+					PrivateKey: testEC384PrivateKey,
+					KeyID:      "key-2",
+				},
+			},
+			publicKey:    &testEC384.PublicKey,
+			wantGrant:    "client_credentials",
+			wantClientID: "client",
+			wantHeader:   map[string]any{"alg": "ES384", "typ": "JWT", "kid": "key-2"},
+			wantClaims: map[string]any{
+				"iss": "client",
+				"sub": "client",
+				"aud": "{{tokenURL}}",
+			},
+			wantLifetime: auth.DefaultAssertionLifetime.Seconds(),
+		},
+		"invalid_private_key": {
+			config: &auth.OAuth2Config{
+				GrantType: auth.GrantTypeJWTBearer,
+				ClientID:  "client",
+				Assertion: &auth.JWTAssertionConfig{
+					PrivateKey: encodePrivateKey("CERTIFICATE", []byte("certificate")),
+				},
+			},
+			wantErr: `invalid OAuth2 config: invalid assertion private key: unsupported private key PEM block type "CERTIFICATE"`,
+		},
+		"rsa_private_key_too_short": {
+			config: &auth.OAuth2Config{
+				ClientID:         "client",
+				ClientAuthMethod: auth.PrivateKeyJWT,
+				Assertion: &auth.JWTAssertionConfig{
+					PrivateKey: encodePrivateKey("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(mustGenerateKey(rsa.GenerateKey(rand.Reader, 1024)))),
+				},
+			},
+			wantErr: "invalid OAuth2 config: invalid assertion private key: RSA private keys must be at least 2048 bits long",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var requests int
+
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+
+				if err := r.ParseForm(); err != nil {
+					t.Errorf("Failed to parse form: %v", err)
+				}
+
+				if got := r.PostForm.Get("grant_type"); got != tt.wantGrant {
+					t.Errorf("gotGrant: %v, wantGrant: %v", got, tt.wantGrant)
+				}
+
+				if got := r.PostForm.Get("client_id"); got != tt.wantClientID {
+					t.Errorf("gotClientID: %v, wantClientID: %v", got, tt.wantClientID)
+				}
+
+				if got := r.Header.Get("Authorization"); got != "" {
+					t.Errorf("Unexpected Authorization header: %v", got)
+				}
+
+				assertion := r.PostForm.Get("assertion")
+
+				if tt.config.ClientAuthMethod == auth.PrivateKeyJWT {
+					if got := r.PostForm.Get("client_assertion_type"); got != "urn:ietf:params:oauth:client-assertion-type:jwt-bearer" {
+						t.Errorf("gotClientAssertionType: %v", got)
+					}
+
+					assertion = r.PostForm.Get("client_assertion")
+				}
+
+				header, claims, err := verifyJWT(assertion, tt.publicKey)
+				if err != nil {
+					t.Errorf("Failed to verify the assertion: %v", err)
+					w.WriteHeader(http.StatusBadRequest)
+
+					return
+				}
+
+				if !reflect.DeepEqual(header, tt.wantHeader) {
+					t.Errorf("gotHeader: %v, wantHeader: %v", header, tt.wantHeader)
+				}
+
+				if gotLifetime := claims["exp"].(float64) - claims["iat"].(float64); gotLifetime != tt.wantLifetime {
+					t.Errorf("gotLifetime: %v, wantLifetime: %v", gotLifetime, tt.wantLifetime)
+				}
+
+				if jti, _ := claims["jti"].(string); len(jti) != 32 {
+					t.Errorf("Invalid jti claim: %v", claims["jti"])
+				}
+
+				delete(claims, "exp")
+				delete(claims, "iat")
+				delete(claims, "jti")
+
+				if tt.wantClaims["aud"] == "{{tokenURL}}" {
+					tt.wantClaims["aud"] = tt.config.TokenURL
+				}
+
+				if !reflect.DeepEqual(claims, tt.wantClaims) {
+					t.Errorf("gotClaims: %v, wantClaims: %v", claims, tt.wantClaims)
+				}
+
+				w.Write([]byte(`{"access_token": "token", "token_type": "Bearer", "expires_in": 3600}`))
+			}))
+			defer server.Close()
+
+			tt.config.TokenURL = server.URL + "/token"
+
+			// Invalid private keys are returned when the TokenFetcher is built, before any token request.
+			fetch, gotErr := auth.NewTokenFetcher(server.Client(), tt.config)
+
+			if tt.wantErr != "" {
+				var configErr *auth.ConfigError
+				if !errors.As(gotErr, &configErr) || gotErr.Error() != tt.wantErr {
+					t.Fatalf("gotErr: %v, wantErr: %s", gotErr, tt.wantErr)
+				}
+
+				return
+			}
+
+			if gotErr != nil {
+				t.Fatalf("Unexpected error: %v", gotErr)
+			}
+
+			source := &auth.TokenSource{
+				Key:   name,
+				Cache: &auth.TokenCache{},
+				Fetch: fetch,
+			}
+
+			// The second token is returned from the cache.
+			for range 2 {
+				gotToken, gotErr := source.Token(context.Background())
+				if gotErr != nil {
+					t.Fatalf("Unexpected error: %v", gotErr)
+				}
+
+				if gotToken.AccessToken != "token" {
+					t.Errorf("gotAccessToken: %v, wantAccessToken: token", gotToken.AccessToken)
+				}
+			}
+
+			if requests != 1 {
+				t.Errorf("gotRequests: %d, wantRequests: 1", requests)
+			}
+		})
+	}
+}
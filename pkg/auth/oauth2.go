@@ -0,0 +1,409 @@
+// Copyright 2025 SGNL.ai, Inc.
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ClientAuthMethod is the method used by a client to authenticate with an OAuth2 token endpoint.
+// https://datatracker.ietf.org/doc/html/rfc6749#section-2.3.1
+type ClientAuthMethod string
+
+const (
+	// ClientSecretBasic sends the client ID and secret in an HTTP Basic Authorization header.
+	ClientSecretBasic ClientAuthMethod = "client_secret_basic"
+
+	// ClientSecretPost sends the client ID and secret in the request body.
+	ClientSecretPost ClientAuthMethod = "client_secret_post"
+
+	// PrivateKeyJWT sends a JWT assertion signed with the client's private key in the request body.
+	// https://datatracker.ietf.org/doc/html/rfc7523#section-2.2
+	PrivateKeyJWT ClientAuthMethod = "private_key_jwt"
+
+	// ClientAuthNone does not authenticate the client, e.g. if the JWT bearer grant authenticates the client.
+	ClientAuthNone ClientAuthMethod = "none"
+)
+
+// GrantType is the OAuth2 authorization grant used to request access tokens.
+type GrantType string
+
+const (
+	// GrantTypeClientCredentials requests access tokens for the client itself.
+	// https://datatracker.ietf.org/doc/html/rfc6749#section-4.4
+	GrantTypeClientCredentials GrantType = "client_credentials"
+
+	// GrantTypeJWTBearer requests access tokens with a JWT assertion signed with the client's private key.
+	// https://datatracker.ietf.org/doc/html/rfc7523#section-2.1
+	GrantTypeJWTBearer GrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+)
+
+// clientAssertionType is the type of the client assertions sent with PrivateKeyJWT.
+const clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// maxTokenResponseBytes is the maximum size of the body of a token endpoint response.
+const maxTokenResponseBytes = 1 << 20
+
+// OAuth2Config is the configuration of the OAuth2 grant used to request the access tokens sent to a datasource.
+type OAuth2Config struct {
+	// GrantType is the authorization grant used to request access tokens.
+	// Optional. Defaults to GrantTypeClientCredentials if not set.
+	GrantType GrantType `json:"grantType,omitempty"`
+
+	// TokenURL is the URL of the token endpoint, e.g. "https://login.example.com/oauth2/token".
+	TokenURL string `json:"tokenUrl"`
+
+	// ClientID is the client identifier.
+	// Optional. If not set, the username of the datasource's basic auth credentials is used.
+	ClientID string `json:"clientId,omitempty"`
+
+	// ClientSecret is the client secret.
+	// Optional. If not set, the password of the datasource's basic auth credentials is used, which is
+	// recommended so that the secret is not stored in the datasource config.
+	ClientSecret string `json:"clientSecret,omitempty"`
+
+	// Scopes are the scopes of the requested access tokens.
+	// Optional. If not set, the token endpoint's default scopes are granted.
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Audience is the audience of the requested access tokens, required by some token endpoints.
+	// Optional.
+	Audience string `json:"audience,omitempty"`
+
+	// ClientAuthMethod is the method used to authenticate with the token endpoint.
+	// Optional. Defaults to ClientSecretBasic for GrantTypeClientCredentials, and ClientAuthNone for
+	// GrantTypeJWTBearer if not set.
+	ClientAuthMethod ClientAuthMethod `json:"clientAuthMethod,omitempty"`
+
+	// Assertion is the configuration of the JWT assertions sent with GrantTypeJWTBearer or PrivateKeyJWT.
+	// Required for GrantTypeJWTBearer and PrivateKeyJWT.
+	Assertion *JWTAssertionConfig `json:"assertion,omitempty"`
+}
+
+// ResolvedGrantType returns the grant type, or its default if not set.
+func (c *OAuth2Config) ResolvedGrantType() GrantType {
+	return valueOrDefault(c.GrantType, GrantTypeClientCredentials)
+}
+
+// ResolvedClientAuthMethod returns the client auth method, or its default for the grant type if not set.
+func (c *OAuth2Config) ResolvedClientAuthMethod() ClientAuthMethod {
+	if c.ClientAuthMethod == "" && c.ResolvedGrantType() == GrantTypeJWTBearer {
+		return ClientAuthNone
+	}
+
+	return valueOrDefault(c.ClientAuthMethod, ClientSecretBasic)
+}
+
+// Validate returns an error if the config is invalid, including the private key of the JWT assertions.
+// The client secret is not validated, as it may be provided separately.
+func (c *OAuth2Config) Validate() error {
+	tokenURL, err := url.Parse(c.TokenURL)
+	if err != nil || tokenURL.Host == "" {
+		return errors.New("the token URL must be an absolute URL")
+	}
+
+	if tokenURL.Scheme != "https" {
+		return errors.New("the token URL must use HTTPS")
+	}
+
+	switch c.GrantType {
+	case "", GrantTypeClientCredentials, GrantTypeJWTBearer:
+	default:
+		return fmt.Errorf(
+			"unsupported grant type %q, supported grant types are %q and %q",
+			c.GrantType, GrantTypeClientCredentials, GrantTypeJWTBearer,
+		)
+	}
+
+	switch c.ClientAuthMethod {
+	case "", ClientSecretBasic, ClientSecretPost, PrivateKeyJWT:
+	case ClientAuthNone:
+		if c.ResolvedGrantType() != GrantTypeJWTBearer {
+			return fmt.Errorf("the client must be authenticated with the %q grant type", c.ResolvedGrantType())
+		}
+	default:
+		return fmt.Errorf(
+			"unsupported client auth method %q, supported client auth methods are %q, %q, %q and %q",
+			c.ClientAuthMethod, ClientSecretBasic, ClientSecretPost, PrivateKeyJWT, ClientAuthNone,
+		)
+	}
+
+	if !c.signsAssertions() {
+		return nil
+	}
+
+	if c.Assertion == nil {
+		return errors.New("an assertion config is required to sign JWT assertions")
+	}
+
+	if c.Assertion.LifetimeSeconds < 0 {
+		return errors.New("the assertion lifetime must not be negative")
+	}
+
+	if valueOrDefault(c.Assertion.Issuer, c.ClientID) == "" || valueOrDefault(c.Assertion.Subject, c.ClientID) == "" {
+		return errors.New("the assertion issuer and subject are required if the client ID is not set")
+	}
+
+	return nil
+}
+
+// signsAssertions returns true if JWT assertions are signed to request access tokens, either as the
+// authorization grant or to authenticate the client.
+func (c *OAuth2Config) signsAssertions() bool {
+	return c.ResolvedGrantType() == GrantTypeJWTBearer || c.ResolvedClientAuthMethod() == PrivateKeyJWT
+}
+
+// ConfigError is returned if an OAuth2Config is invalid, e.g. if the private key of its JWT assertions cannot be
+// parsed.
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid OAuth2 config: " + e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Token is an OAuth2 access token.
+type Token struct {
+	// AccessToken is the access token.
+	AccessToken string
+
+	// TokenType is the type of the access token, e.g. "Bearer".
+	TokenType string
+
+	// Expiry is the time when the access token expires.
+	// Zero if the token endpoint did not return the lifetime of the token.
+	Expiry time.Time
+}
+
+// AuthorizationHeader returns the Authorization header to send with the access token.
+func (t *Token) AuthorizationHeader() string {
+	// Token types are case-insensitive, but some servers only accept "Bearer".
+	if t.TokenType == "" || strings.EqualFold(t.TokenType, "bearer") {
+		return "Bearer " + t.AccessToken
+	}
+
+	return t.TokenType + " " + t.AccessToken
+}
+
+// ValidAt returns true if the token has not expired at the provided time.
+func (t *Token) ValidAt(now time.Time) bool {
+	return t.Expiry.IsZero() || now.Before(t.Expiry)
+}
+
+// TokenError is returned if an access token cannot be requested, e.g. if the token endpoint rejects the
+// client's credentials.
+type TokenError struct {
+	// StatusCode is the status code of the token endpoint's unsuccessful response.
+	// 0 if no response was received, or if the response was successful but invalid.
+	StatusCode int
+
+	// ErrorCode is the OAuth2 error code returned by the token endpoint, e.g. "invalid_client".
+	// https://datatracker.ietf.org/doc/html/rfc6749#section-5.2
+	ErrorCode string
+
+	// Description is the error description returned by the token endpoint, if any.
+	Description string
+
+	// Err is the cause of the error, if no error was returned by the token endpoint.
+	Err error
+}
+
+func (e *TokenError) Error() string {
+	var b strings.Builder
+
+	b.WriteString("failed to request an access token")
+
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, ": token endpoint returned status code %d", e.StatusCode)
+	}
+
+	if e.ErrorCode != "" {
+		fmt.Fprintf(&b, ": %s", e.ErrorCode)
+	}
+
+	if e.Description != "" {
+		fmt.Fprintf(&b, " (%s)", e.Description)
+	}
+
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+
+	return b.String()
+}
+
+func (e *TokenError) Unwrap() error {
+	return e.Err
+}
+
+// TokenFetcher requests a new access token.
+type TokenFetcher func(ctx context.Context) (*Token, error)
+
+// NewTokenFetcher returns a TokenFetcher requesting access tokens with the configured grant type and client
+// authentication method. The private key of the JWT assertions, if any, is parsed once, and a *ConfigError is
+// returned if it is invalid. Errors of the TokenFetcher are returned as *TokenError.
+func NewTokenFetcher(client *http.Client, config *OAuth2Config) (TokenFetcher, error) {
+	var signer *jwtSigner
+
+	if config.signsAssertions() {
+		if config.Assertion == nil {
+			return nil, &ConfigError{Err: errors.New("an assertion config is required to sign JWT assertions")}
+		}
+
+		var err error
+		if signer, err = newJWTSigner(config.Assertion.PrivateKey); err != nil {
+			return nil, &ConfigError{Err: fmt.Errorf("invalid assertion private key: %w", err)}
+		}
+	}
+
+	return func(ctx context.Context) (*Token, error) {
+		form := url.Values{
+			"grant_type": {string(config.ResolvedGrantType())},
+		}
+
+		if config.ResolvedGrantType() == GrantTypeJWTBearer {
+			assertion, err := signAssertion(signer, config.Assertion, config.ClientID, config.TokenURL, time.Now())
+			if err != nil {
+				return nil, &TokenError{Err: fmt.Errorf("failed to sign the JWT assertion: %w", err)}
+			}
+
+			form.Set("assertion", assertion)
+		}
+
+		if len(config.Scopes) > 0 {
+			form.Set("scope", strings.Join(config.Scopes, " "))
+		}
+
+		if config.Audience != "" {
+			form.Set("audience", config.Audience)
+		}
+
+		header := http.Header{}
+
+		switch config.ResolvedClientAuthMethod() {
+		case ClientSecretBasic:
+			// The client ID and secret are form-encoded before being sent as basic auth credentials.
+			// https://datatracker.ietf.org/doc/html/rfc6749#section-2.3.1
+			header.Set("Authorization", BasicAuthHeader(
+				url.QueryEscape(config.ClientID), url.QueryEscape(config.ClientSecret),
+			))
+		case ClientSecretPost:
+			form.Set("client_id", config.ClientID)
+			form.Set("client_secret", config.ClientSecret)
+		case PrivateKeyJWT:
+			// The audience of client assertions defaults to the token endpoint.
+			// https://datatracker.ietf.org/doc/html/rfc7523#section-3
+			assertion, err := signAssertion(signer, config.Assertion, config.ClientID, config.TokenURL, time.Now())
+			if err != nil {
+				return nil, &TokenError{Err: fmt.Errorf("failed to sign the client assertion: %w", err)}
+			}
+
+			if config.ClientID != "" {
+				form.Set("client_id", config.ClientID)
+			}
+
+			form.Set("client_assertion_type", clientAssertionType)
+			form.Set("client_assertion", assertion)
+		case ClientAuthNone:
+			if config.ClientID != "" {
+				form.Set("client_id", config.ClientID)
+			}
+		}
+
+		return RequestToken(ctx, client, config.TokenURL, form, header)
+	}, nil
+}
+
+// RequestToken sends a token request with the provided form parameters and headers to a token endpoint,
+// and returns the access token. Errors are returned as *TokenError.
+// https://datatracker.ietf.org/doc/html/rfc6749#section-5
+func RequestToken(
+	ctx context.Context,
+	client *http.Client,
+	tokenURL string,
+	form url.Values,
+	header http.Header,
+) (*Token, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, &TokenError{Err: err}
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	requestedAt := time.Now()
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, &TokenError{Err: err}
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxTokenResponseBytes))
+	if err != nil {
+		return nil, &TokenError{Err: err}
+	}
+
+	var response struct {
+		AccessToken      string      `json:"access_token"`
+		TokenType        string      `json:"token_type"`
+		ExpiresIn        json.Number `json:"expires_in"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+
+	// Unsuccessful responses may not contain an OAuth2 error, in which case only the status code is reported.
+	decodeErr := json.Unmarshal(body, &response)
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return nil, &TokenError{
+			StatusCode:  res.StatusCode,
+			ErrorCode:   response.Error,
+			Description: response.ErrorDescription,
+		}
+	}
+
+	if decodeErr != nil {
+		return nil, &TokenError{Err: fmt.Errorf("invalid token response: %w", decodeErr)}
+	}
+
+	if response.AccessToken == "" {
+		return nil, &TokenError{Err: errors.New("the token response has no access token")}
+	}
+
+	token := &Token{
+		AccessToken: response.AccessToken,
+		TokenType:   response.TokenType,
+	}
+
+	// The lifetime is counted from the time of the request, as the token may have been issued any time after.
+	if response.ExpiresIn != "" {
+		expiresIn, err := strconv.ParseFloat(response.ExpiresIn.String(), 64)
+		if err != nil || expiresIn <= 0 {
+			return nil, &TokenError{Err: errors.New("the token response has an invalid lifetime")}
+		}
+
+		token.Expiry = requestedAt.Add(time.Duration(expiresIn * float64(time.Second)))
+	}
+
+	return token, nil
+}
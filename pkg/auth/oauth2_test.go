@@ -0,0 +1,277 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// nolint: lll
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sgnl-ai/sample-adapter/pkg/auth"
+)
+
+func TestNewTokenFetcherClientCredentials(t *testing.T) {
+	tests := map[string]struct {
+		config         *auth.OAuth2Config
+		statusCode     int
+		body           string
+		wantForm       url.Values
+		wantAuthHeader string
+		wantToken      *auth.Token
+		wantExpiresIn  time.Duration
+		wantErr        *auth.TokenError
+		wantErrMessage string
+	}{
+		"client_secret_basic": {
+			config: &auth.OAuth2Config{
+				ClientID:     "client:1",
+				ClientSecret: "secret/1",
+				Scopes:       []string{"scim.read", "scim.groups"},
+				Audience:     "https://scim.example.com",
+			},
+			statusCode: http.StatusOK,
+			body:       `{"access_token": "token", "token_type": "bearer", "expires_in": 3600}`,
+			wantForm: url.Values{
+				"grant_type": {"client_credentials"},
+				"scope":      {"scim.read scim.groups"},
+				"audience":   {"https://scim.example.com"},
+			},
+			wantAuthHeader: auth.BasicAuthHeader("client%3A1", "secret%2F1"),
+			wantToken:      &auth.Token{AccessToken: "token", TokenType: "bearer"},
+			wantExpiresIn:  time.Hour,
+		},
+		"client_secret_post": {
+			config: &auth.OAuth2Config{
+				ClientID:         "client",
+				ClientSecret:     "secret",
+				ClientAuthMethod: auth.ClientSecretPost,
+			},
+			statusCode: http.StatusOK,
+			body:       `{"access_token": "token", "token_type": "Bearer", "expires_in": "60"}`,
+			wantForm: url.Values{
+				"grant_type":    {"client_credentials"},
+				"client_id":     {"client"},
+				"client_secret": {"secret"},
+			},
+			wantToken:     &auth.Token{AccessToken: "token", TokenType: "Bearer"},
+			wantExpiresIn: time.Minute,
+		},
+		"no_expiry": {
+			config: &auth.OAuth2Config{
+				ClientID:     "client",
+				ClientSecret: "secret",
+			},
+			statusCode: http.StatusOK,
+			body:       `{"access_token": "token"}`,
+			wantForm: url.Values{
+				"grant_type": {"client_credentials"},
+			},
+			wantAuthHeader: auth.BasicAuthHeader("client", "secret"),
+			wantToken:      &auth.Token{AccessToken: "token"},
+		},
+		"invalid_client": {
+			config: &auth.OAuth2Config{
+				ClientID:     "client",
+				ClientSecret: "wrong",
+			},
+			statusCode: http.StatusUnauthorized,
+			body:       `{"error": "invalid_client", "error_description": "Client authentication failed."}`,
+			wantForm: url.Values{
+				"grant_type": {"client_credentials"},
+			},
+			wantAuthHeader: auth.BasicAuthHeader("client", "wrong"),
+			wantErr: &auth.TokenError{
+				StatusCode:  http.StatusUnauthorized,
+				ErrorCode:   "invalid_client",
+				Description: "Client authentication failed.",
+			},
+			wantErrMessage: "failed to request an access token: token endpoint returned status code 401: invalid_client (Client authentication failed.)",
+		},
+		"not_json_error": {
+			config: &auth.OAuth2Config{
+				ClientID:     "client",
+				ClientSecret: "secret",
+			},
+			statusCode: http.StatusBadGateway,
+			body:       `Bad Gateway`,
+			wantForm: url.Values{
+				"grant_type": {"client_credentials"},
+			},
+			wantAuthHeader: auth.BasicAuthHeader("client", "secret"),
+			wantErr: &auth.TokenError{
+				StatusCode: http.StatusBadGateway,
+			},
+			wantErrMessage: "failed to request an access token: token endpoint returned status code 502",
+		},
+		"missing_access_token": {
+			config: &auth.OAuth2Config{
+				ClientID:     "client",
+				ClientSecret: "secret",
+			},
+			statusCode: http.StatusOK,
+			body:       `{"token_type": "Bearer"}`,
+			wantForm: url.Values{
+				"grant_type": {"client_credentials"},
+			},
+			wantAuthHeader: auth.BasicAuthHeader("client", "secret"),
+			wantErrMessage: "failed to request an access token: the token response has no access token",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Errorf("Failed to parse form: %v", err)
+				}
+
+				if !reflect.DeepEqual(r.PostForm, tt.wantForm) {
+					t.Errorf("gotForm: %v, wantForm: %v", r.PostForm, tt.wantForm)
+				}
+
+				if gotAuthHeader := r.Header.Get("Authorization"); gotAuthHeader != tt.wantAuthHeader {
+					t.Errorf("gotAuthHeader: %v, wantAuthHeader: %v", gotAuthHeader, tt.wantAuthHeader)
+				}
+
+				w.WriteHeader(tt.statusCode)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			tt.config.TokenURL = server.URL + "/token"
+
+			fetch, err := auth.NewTokenFetcher(server.Client(), tt.config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			requestedAt := time.Now()
+
+			gotToken, gotErr := fetch(context.Background())
+
+			if tt.wantErrMessage != "" {
+				if gotErr == nil || gotErr.Error() != tt.wantErrMessage {
+					t.Fatalf("gotErr: %v, wantErr: %s", gotErr, tt.wantErrMessage)
+				}
+
+				if tt.wantErr != nil && !reflect.DeepEqual(gotErr, tt.wantErr) {
+					t.Errorf("gotErr: %#v, wantErr: %#v", gotErr, tt.wantErr)
+				}
+
+				return
+			}
+
+			if gotErr != nil {
+				t.Fatalf("Unexpected error: %v", gotErr)
+			}
+
+			// The expiry is counted from the time of the request.
+			if tt.wantExpiresIn > 0 {
+				if gotExpiresIn := gotToken.Expiry.Sub(requestedAt); gotExpiresIn < tt.wantExpiresIn || gotExpiresIn > tt.wantExpiresIn+time.Second {
+					t.Errorf("gotExpiresIn: %v, wantExpiresIn: %v", gotExpiresIn, tt.wantExpiresIn)
+				}
+
+				gotToken.Expiry = time.Time{}
+			}
+
+			if !reflect.DeepEqual(gotToken, tt.wantToken) {
+				t.Errorf("gotToken: %+v, wantToken: %+v", gotToken, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestOAuth2ConfigValidate(t *testing.T) {
+	tests := map[string]struct {
+		config  *auth.OAuth2Config
+		wantErr string
+	}{
+		"valid": {
+			config: &auth.OAuth2Config{TokenURL: "https://login.example.com/oauth2/token", ClientAuthMethod: auth.ClientSecretPost},
+		},
+		"relative_token_url": {
+			config:  &auth.OAuth2Config{TokenURL: "/oauth2/token"},
+			wantErr: "the token URL must be an absolute URL",
+		},
+		"http_token_url": {
+			config:  &auth.OAuth2Config{TokenURL: "http://login.example.com/oauth2/token"},
+			wantErr: "the token URL must use HTTPS",
+		},
+		"unsupported_client_auth_method": {
+			config:  &auth.OAuth2Config{TokenURL: "https://login.example.com/oauth2/token", ClientAuthMethod: "tls_client_auth"},
+			wantErr: `unsupported client auth method "tls_client_auth", supported client auth methods are "client_secret_basic", "client_secret_post", "private_key_jwt" and "none"`,
+		},
+		"unsupported_grant_type": {
+			config:  &auth.OAuth2Config{TokenURL: "https://login.example.com/oauth2/token", GrantType: "password"},
+			wantErr: `unsupported grant type "password", supported grant types are "client_credentials" and "urn:ietf:params:oauth:grant-type:jwt-bearer"`,
+		},
+		"client_credentials_without_client_auth": {
+			config:  &auth.OAuth2Config{TokenURL: "https://login.example.com/oauth2/token", ClientAuthMethod: auth.ClientAuthNone},
+			wantErr: `the client must be authenticated with the "client_credentials" grant type`,
+		},
+		"private_key_jwt_without_assertion": {
+			config:  &auth.OAuth2Config{TokenURL: "https://login.example.com/oauth2/token", ClientID: "client", ClientAuthMethod: auth.PrivateKeyJWT},
+			wantErr: "an assertion config is required to sign JWT assertions",
+		},
+		"jwt_bearer_without_issuer": {
+			config: &auth.OAuth2Config{
+				TokenURL:  "https://login.example.com/oauth2/token",
+				GrantType: auth.GrantTypeJWTBearer,
+				Assertion: &auth.JWTAssertionConfig{PrivateKey: testECPrivateKey, Subject: "user@example.com"},
+			},
+			wantErr: "the assertion issuer and subject are required if the client ID is not set",
+		},
+		"jwt_bearer_private_key_parsed_by_token_fetcher": {
+			// The private key is only parsed once by NewTokenFetcher, rather than by every validation.
+			config: &auth.OAuth2Config{
+				TokenURL:  "https://login.example.com/oauth2/token",
+				GrantType: auth.GrantTypeJWTBearer,
+				ClientID:  "client",
+				Assertion: &auth.JWTAssertionConfig{PrivateKey: "not a key"},
+			},
+		},
+		"jwt_bearer_valid": {
+			config: &auth.OAuth2Config{
+				TokenURL:  "https://login.example.com/oauth2/token",
+				GrantType: auth.GrantTypeJWTBearer,
+				ClientID:  "client",
+				Assertion: &auth.JWTAssertionConfig{PrivateKey: testECPrivateKey},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotErr := tt.config.Validate()
+
+			if (gotErr == nil && tt.wantErr != "") || (gotErr != nil && gotErr.Error() != tt.wantErr) {
+				t.Errorf("gotErr: %v, wantErr: %s", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTokenAuthorizationHeader(t *testing.T) {
+	tests := map[string]struct {
+		token *auth.Token
+		want  string
+	}{
+		"bearer":       {token: &auth.Token{AccessToken: "abc", TokenType: "bearer"}, want: "Bearer abc"},
+		"no_type":      {token: &auth.Token{AccessToken: "abc"}, want: "Bearer abc"},
+		"other_type":   {token: &auth.Token{AccessToken: "abc", TokenType: "DPoP"}, want: "DPoP abc"},
+		"mixed_bearer": {token: &auth.Token{AccessToken: "abc", TokenType: "BEARER"}, want: "Bearer abc"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tt.token.AuthorizationHeader(); got != tt.want {
+				t.Errorf("got: %v, want: %v", got, tt.want)
+			}
+		})
+	}
+}
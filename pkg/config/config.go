@@ -1,6 +1,11 @@
 // Copyright 2025 SGNL.ai, Inc.
 package config
 
+import (
+	"math"
+	"slices"
+)
+
 var (
 	DefaultRequestTimeout = 10 // 10 seconds
 )
@@ -16,8 +21,138 @@ type CommonConfig struct {
 	// number of seconds east of UTC. If this is set to 0 or not set, this will default to UTC.
 	// Allowed offset is -12 hours to 14 hours, in seconds.
 	LocalTimeZoneOffset int `json:"localTimeZoneOffset,omitempty" validate:"omitempty,gte=-43200,lte=50400"`
+
+	// Retry configures retries of failed requests made to datasources.
+	// All the attempts of a request, and the backoffs between them, must complete within RequestTimeoutSeconds.
+	// Optional. If not set, requests are not retried.
+	Retry *RetryConfig `json:"retry,omitempty"`
+
+	// RateLimit limits the rate and concurrency of requests made to a datasource with the same credentials,
+	// across all the entities of the datasource. Requests wait for the rate limit within RequestTimeoutSeconds.
+	// Optional. If not set, requests are not limited.
+	RateLimit *RateLimitConfig `json:"rateLimit,omitempty"`
+
+	// TLS configures the TLS connections to a datasource, e.g. to present a client certificate to datasources
+	// requiring mutual TLS. Requests to the datasource's token endpoint, if any, present the same client
+	// certificate, and trust the same additional CA certificates.
+	// Optional. If not set, the adapter's default TLS config is used.
+	TLS *TLSConfig `json:"tls,omitempty"`
+}
+
+// TLSConfig is the configuration of the TLS connections to a datasource.
+type TLSConfig struct {
+	// ClientCertificate is the PEM-encoded client certificate presented to the datasource, followed by any
+	// intermediate certificates. Must be set together with ClientKey.
+	// Optional. Mutually exclusive with ClientPKCS12.
+	ClientCertificate string `json:"clientCertificate,omitempty"`
+
+	// ClientKey is the PEM-encoded private key of ClientCertificate.
+	ClientKey string `json:"clientKey,omitempty"`
+
+	// ClientPKCS12 is the base64-encoded PKCS #12 archive containing the client certificate presented to the
+	// datasource, its private key, and any intermediate certificates.
+	// Optional. Mutually exclusive with ClientCertificate.
+	ClientPKCS12 string `json:"clientPkcs12,omitempty"`
+
+	// ClientPKCS12Password is the password of ClientPKCS12, if any.
+	ClientPKCS12Password string `json:"clientPkcs12Password,omitempty"`
+
+	// CACertificates is the PEM-encoded bundle of the CA certificates trusted to verify the datasource's
+	// certificate, instead of the adapter's default trust store. The datasource's token endpoint, if any, is
+	// still verified against the adapter's default trust store.
+	// Optional. If not set, the adapter's default trust store is used.
+	CACertificates string `json:"caCertificates,omitempty"`
+
+	// AdditionalCACertificates is the PEM-encoded bundle of the root CA certificates trusted to verify the
+	// datasource's certificate in addition to CACertificates, or to the adapter's default trust store,
+	// e.g. the root CAs of an internal PKI.
+	// Optional.
+	AdditionalCACertificates string `json:"additionalCaCertificates,omitempty"`
+
+	// PinnedPublicKeys are the base64-encoded SHA-256 hashes of the DER-encoded SubjectPublicKeyInfo of the
+	// public keys trusted in the datasource's certificate chain. If set, the verified certificate chain of the
+	// datasource must contain a certificate with one of the pinned public keys. The datasource's token endpoint,
+	// if any, is not pinned.
+	// Optional. If not set, public keys are not pinned.
+	PinnedPublicKeys []string `json:"pinnedPublicKeys,omitempty"`
+}
+
+// RateLimitConfig is the configuration of the token-bucket rate limiter of requests made to a datasource.
+type RateLimitConfig struct {
+	// RequestsPerSecond is the maximum sustained rate of requests. The rate is not limited if not set.
+	RequestsPerSecond float64 `json:"requestsPerSecond,omitempty" validate:"omitempty,gt=0"`
+
+	// Burst is the maximum number of requests made at once, above the sustained rate.
+	// Defaults to RequestsPerSecond rounded up if not set.
+	Burst int `json:"burst,omitempty" validate:"omitempty,gt=0"`
+
+	// MaxConcurrentRequests is the maximum number of requests in flight at the same time.
+	// The number of concurrent requests is not limited if not set.
+	MaxConcurrentRequests int `json:"maxConcurrentRequests,omitempty" validate:"omitempty,gt=0"`
+}
+
+// RetryConfig is the configuration of retries of failed requests made to datasources.
+// Requests are retried with an exponential backoff, or after the duration requested by the datasource
+// in the Retry-After response header, if any.
+type RetryConfig struct {
+	// MaxAttempts is the maximum number of attempts of a request, including the first attempt.
+	// Defaults to DefaultRetryMaxAttempts if not set.
+	MaxAttempts int `json:"maxAttempts,omitempty" validate:"omitempty,gte=1,lte=10"`
+
+	// BaseBackoffMilliseconds is the backoff before the first retry. The backoff doubles after each retry.
+	// Defaults to DefaultRetryBaseBackoffMilliseconds if not set.
+	BaseBackoffMilliseconds int `json:"baseBackoffMilliseconds,omitempty" validate:"omitempty,gt=0"`
+
+	// MaxBackoffMilliseconds is the maximum backoff between two attempts, unless the datasource requests a
+	// longer backoff in the Retry-After response header.
+	// Defaults to DefaultRetryMaxBackoffMilliseconds if not set.
+	MaxBackoffMilliseconds int `json:"maxBackoffMilliseconds,omitempty" validate:"omitempty,gt=0"`
+
+	// Jitter is the fraction of each backoff which is randomized, between 0 and 1, to spread the retries
+	// of concurrent requests. For example, with a jitter of 0.2, a backoff of 1 second is randomized
+	// between 0.8 and 1 second.
+	// Defaults to DefaultRetryJitter if not set.
+	Jitter *float64 `json:"jitter,omitempty" validate:"omitempty,gte=0,lte=1"`
+
+	// RetryableStatusCodes is the list of response status codes for which a request is retried.
+	// Defaults to DefaultRetryableStatusCodes if not set.
+	RetryableStatusCodes []int `json:"retryableStatusCodes,omitempty"`
+
+	// RetryableNetworkErrors is the list of network errors for which a request is retried,
+	// among NetworkErrorTimeout, NetworkErrorConnectionReset, NetworkErrorConnectionRefused and NetworkErrorEOF.
+	// Defaults to DefaultRetryableNetworkErrors if not set.
+	RetryableNetworkErrors []string `json:"retryableNetworkErrors,omitempty"`
 }
 
+const (
+	// NetworkErrorTimeout is a network timeout of a single attempt, e.g. a TLS handshake timeout.
+	// Requests exceeding the RequestTimeoutSeconds budget are never retried.
+	NetworkErrorTimeout = "timeout"
+
+	// NetworkErrorConnectionReset is a connection reset by the datasource.
+	NetworkErrorConnectionReset = "connectionReset"
+
+	// NetworkErrorConnectionRefused is a connection refused by the datasource.
+	NetworkErrorConnectionRefused = "connectionRefused"
+
+	// NetworkErrorEOF is a connection closed by the datasource before a response is received.
+	NetworkErrorEOF = "eof"
+)
+
+var (
+	DefaultRetryMaxAttempts             = 3
+	DefaultRetryBaseBackoffMilliseconds = 500
+	DefaultRetryMaxBackoffMilliseconds  = 10000 // 10 seconds
+	DefaultRetryJitter                  = 0.2
+	DefaultRetryableStatusCodes         = []int{429, 502, 503, 504}
+	DefaultRetryableNetworkErrors       = []string{
+		NetworkErrorTimeout,
+		NetworkErrorConnectionReset,
+		NetworkErrorConnectionRefused,
+		NetworkErrorEOF,
+	}
+)
+
 // SetMissingCommonConfigDefaults sets default values for any missing common configuration values.
 // If the provided CommonConfig is nil, a new CommonConfig will be created.
 func SetMissingCommonConfigDefaults(c *CommonConfig) *CommonConfig {
@@ -30,5 +165,37 @@ func SetMissingCommonConfigDefaults(c *CommonConfig) *CommonConfig {
 		c.RequestTimeoutSeconds = &DefaultRequestTimeout
 	}
 
+	// Set default retry values, if retries are enabled.
+	if c.Retry != nil {
+		if c.Retry.MaxAttempts == 0 {
+			c.Retry.MaxAttempts = DefaultRetryMaxAttempts
+		}
+
+		if c.Retry.BaseBackoffMilliseconds == 0 {
+			c.Retry.BaseBackoffMilliseconds = DefaultRetryBaseBackoffMilliseconds
+		}
+
+		if c.Retry.MaxBackoffMilliseconds == 0 {
+			c.Retry.MaxBackoffMilliseconds = DefaultRetryMaxBackoffMilliseconds
+		}
+
+		if c.Retry.Jitter == nil {
+			c.Retry.Jitter = &DefaultRetryJitter
+		}
+
+		if c.Retry.RetryableStatusCodes == nil {
+			c.Retry.RetryableStatusCodes = slices.Clone(DefaultRetryableStatusCodes)
+		}
+
+		if c.Retry.RetryableNetworkErrors == nil {
+			c.Retry.RetryableNetworkErrors = slices.Clone(DefaultRetryableNetworkErrors)
+		}
+	}
+
+	// Set the default burst, if the rate is limited.
+	if c.RateLimit != nil && c.RateLimit.RequestsPerSecond > 0 && c.RateLimit.Burst == 0 {
+		c.RateLimit.Burst = int(math.Ceil(c.RateLimit.RequestsPerSecond))
+	}
+
 	return c
 }
@@ -0,0 +1,151 @@
+// Copyright 2025 SGNL.ai, Inc.
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/sgnl-ai/sample-adapter/pkg/config"
+	"golang.org/x/time/rate"
+)
+
+// DefaultRegistrySize is the maximum number of rate limiters held by a Registry if MaxSize is not set.
+const DefaultRegistrySize = 1000
+
+// Registry holds the rate limiters of datasources, keyed by datasource address and credential.
+// The zero value is ready to use.
+type Registry struct {
+	// MaxSize is the maximum number of rate limiters. Once reached, the least recently used rate limiter is
+	// evicted when another rate limiter is created, e.g. after credentials were rotated. Requests waiting
+	// for an evicted rate limiter are not affected.
+	// Optional. Defaults to DefaultRegistrySize if not set.
+	MaxSize int
+
+	mu sync.Mutex
+
+	// limiters are the elements of recent, keyed by key.
+	limiters map[string]*list.Element
+
+	// recent is the list of rate limiters, from the most recently used to the least recently used.
+	recent list.List
+}
+
+// limiter limits the rate and concurrency of the requests made with the same key.
+type limiter struct {
+	key    string
+	config config.RateLimitConfig
+
+	// rate is nil if the rate is not limited.
+	rate *rate.Limiter
+
+	// slots is nil if the number of concurrent requests is not limited.
+	slots chan struct{}
+}
+
+// Key returns the key of the rate limiter of requests made to a datasource address with a credential,
+// e.g. an Authorization header. The credential is hashed, so it is not retained by the Registry.
+func Key(address string, credential string) string {
+	hash := sha256.Sum256([]byte(credential))
+
+	return address + "#" + hex.EncodeToString(hash[:])
+}
+
+// Wait blocks until a request can be made with the provided key, according to the provided RateLimitConfig.
+// The returned release function must be called once the request has completed, to release its concurrency slot.
+//
+// If the RateLimitConfig is nil, Wait returns immediately. If the wait would exceed the deadline of ctx,
+// Wait returns an error wrapping context.DeadlineExceeded without waiting.
+// If the RateLimitConfig of a key changes, the rate limiter of the key is replaced.
+// The rate limiter of a key is evicted once MaxSize rate limiters of more recently used keys are held.
+func (r *Registry) Wait(ctx context.Context, key string, cfg *config.RateLimitConfig) (release func(), err error) {
+	if cfg == nil {
+		return func() {}, nil
+	}
+
+	l := r.limiter(key, cfg)
+
+	release = func() {}
+
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for a concurrent request slot: %w", ctx.Err())
+		}
+
+		var once sync.Once
+
+		release = func() {
+			once.Do(func() { <-l.slots })
+		}
+	}
+
+	if l.rate != nil {
+		if err := l.rate.Wait(ctx); err != nil {
+			release()
+
+			// rate.Limiter returns an unwrapped error if the wait would exceed the deadline.
+			if ctx.Err() == nil {
+				return nil, fmt.Errorf("waiting for the rate limit: %w", context.DeadlineExceeded)
+			}
+
+			return nil, fmt.Errorf("waiting for the rate limit: %w", ctx.Err())
+		}
+	}
+
+	return release, nil
+}
+
+func (r *Registry) limiter(key string, cfg *config.RateLimitConfig) *limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if element, found := r.limiters[key]; found {
+		if l := element.Value.(*limiter); l.config == *cfg {
+			r.recent.MoveToFront(element)
+
+			return l
+		}
+
+		r.remove(element)
+	}
+
+	if r.limiters == nil {
+		r.limiters = make(map[string]*list.Element)
+	}
+
+	l := &limiter{
+		key:    key,
+		config: *cfg,
+	}
+
+	if cfg.RequestsPerSecond > 0 {
+		l.rate = rate.NewLimiter(rate.Limit(cfg.RequestsPerSecond), max(cfg.Burst, 1))
+	}
+
+	if cfg.MaxConcurrentRequests > 0 {
+		l.slots = make(chan struct{}, cfg.MaxConcurrentRequests)
+	}
+
+	r.limiters[key] = r.recent.PushFront(l)
+
+	maxSize := r.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultRegistrySize
+	}
+
+	for r.recent.Len() > maxSize {
+		r.remove(r.recent.Back())
+	}
+
+	return l
+}
+
+func (r *Registry) remove(element *list.Element) {
+	r.recent.Remove(element)
+	delete(r.limiters, element.Value.(*limiter).key)
+}
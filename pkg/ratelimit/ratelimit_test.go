@@ -0,0 +1,191 @@
+// Copyright 2025 SGNL.ai, Inc.
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sgnl-ai/sample-adapter/pkg/config"
+	"github.com/sgnl-ai/sample-adapter/pkg/ratelimit"
+)
+
+func TestKey(t *testing.T) {
+	key := ratelimit.Key("https://scim.example.com", "Bearer secret")
+
+	if strings.Contains(key, "secret") {
+		t.Errorf("Key contains the credential: %s", key)
+	}
+
+	if key != ratelimit.Key("https://scim.example.com", "Bearer secret") {
+		t.Error("Key is not deterministic")
+	}
+
+	if key == ratelimit.Key("https://scim.example.com", "Bearer other") {
+		t.Error("Keys of different credentials are equal")
+	}
+
+	if key == ratelimit.Key("https://other.example.com", "Bearer secret") {
+		t.Error("Keys of different addresses are equal")
+	}
+}
+
+func TestWaitWithoutConfig(t *testing.T) {
+	var registry ratelimit.Registry
+
+	for range 100 {
+		release, err := registry.Wait(context.Background(), "key", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		release()
+	}
+}
+
+func TestWaitRate(t *testing.T) {
+	var registry ratelimit.Registry
+
+	cfg := &config.RateLimitConfig{
+		RequestsPerSecond: 1,
+		Burst:             2,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The burst is available immediately.
+	for range 2 {
+		release, err := registry.Wait(ctx, "key", cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		release()
+	}
+
+	// The next request would be allowed in 1 second, after the deadline.
+	start := time.Now()
+
+	if _, err := registry.Wait(ctx, "key", cfg); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("gotErr: %v, wantErr: %v", err, context.DeadlineExceeded)
+	}
+
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Wait took %v, want an immediate error", elapsed)
+	}
+
+	// Other keys are limited separately.
+	release, err := registry.Wait(ctx, "other", cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	release()
+
+	// A different config replaces the rate limiter of the key.
+	release, err = registry.Wait(ctx, "key", &config.RateLimitConfig{RequestsPerSecond: 10, Burst: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	release()
+}
+
+func TestWaitConcurrency(t *testing.T) {
+	var registry ratelimit.Registry
+
+	cfg := &config.RateLimitConfig{
+		MaxConcurrentRequests: 1,
+	}
+
+	release, err := registry.Wait(context.Background(), "key", cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := registry.Wait(ctx, "key", cfg); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("gotErr: %v, wantErr: %v", err, context.DeadlineExceeded)
+	}
+
+	acquired := make(chan struct{})
+
+	go func() {
+		secondRelease, err := registry.Wait(context.Background(), "key", cfg)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		secondRelease()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Concurrent request acquired a slot before the first request was released")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Releasing more than once has no effect.
+	release()
+	release()
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Concurrent request did not acquire a slot after the first request was released")
+	}
+}
+
+func TestRegistryEviction(t *testing.T) {
+	registry := &ratelimit.Registry{MaxSize: 2}
+
+	cfg := &config.RateLimitConfig{
+		MaxConcurrentRequests: 1,
+	}
+
+	// acquired returns true if a request with the key can be made without waiting.
+	acquired := func(key string) bool {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		release, err := registry.Wait(ctx, key, cfg)
+		if err != nil {
+			return false
+		}
+
+		release()
+
+		return true
+	}
+
+	// The slot of a is held, so further requests with a must wait while its rate limiter is held.
+	if _, err := registry.Wait(context.Background(), "a", cfg); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	acquired("b")
+
+	// Using a makes b the least recently used rate limiter.
+	if acquired("a") {
+		t.Fatal("Request acquired the held slot of a")
+	}
+
+	acquired("c")
+
+	if acquired("a") {
+		t.Error("The rate limiter of a was evicted instead of the least recently used rate limiter")
+	}
+
+	acquired("d")
+	acquired("e")
+
+	if !acquired("a") {
+		t.Error("The least recently used rate limiter of a was not evicted")
+	}
+}
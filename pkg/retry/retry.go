@@ -0,0 +1,233 @@
+// Copyright 2025 SGNL.ai, Inc.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/sgnl-ai/sample-adapter/pkg/config"
+)
+
+// maxDrainBytes is the maximum number of bytes read from the body of a response which is retried,
+// so that the connection can be reused.
+const maxDrainBytes = 4 << 10
+
+// Policy decides whether and when a failed request is retried.
+// A nil Policy never retries requests.
+type Policy struct {
+	maxAttempts            int
+	baseBackoff            time.Duration
+	maxBackoff             time.Duration
+	jitter                 float64
+	retryableStatusCodes   []int
+	retryableNetworkErrors []string
+}
+
+// NewPolicy returns the Policy configured by the provided RetryConfig, whose missing values must have been
+// set to their defaults by config.SetMissingCommonConfigDefaults.
+// Returns nil if the RetryConfig is nil, or an error if the RetryConfig is invalid.
+func NewPolicy(cfg *config.RetryConfig) (*Policy, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	if cfg.MaxAttempts < 1 {
+		return nil, fmt.Errorf("the maximum number of attempts must be at least 1, got %d", cfg.MaxAttempts)
+	}
+
+	if cfg.BaseBackoffMilliseconds < 1 || cfg.MaxBackoffMilliseconds < 1 {
+		return nil, errors.New("the base and maximum backoffs must be positive")
+	}
+
+	if cfg.BaseBackoffMilliseconds > cfg.MaxBackoffMilliseconds {
+		return nil, fmt.Errorf(
+			"the base backoff of %dms must not exceed the maximum backoff of %dms",
+			cfg.BaseBackoffMilliseconds, cfg.MaxBackoffMilliseconds,
+		)
+	}
+
+	jitter := config.DefaultRetryJitter
+	if cfg.Jitter != nil {
+		jitter = *cfg.Jitter
+	}
+
+	if jitter < 0 || jitter > 1 {
+		return nil, fmt.Errorf("the jitter must be between 0 and 1, got %v", jitter)
+	}
+
+	for _, statusCode := range cfg.RetryableStatusCodes {
+		if statusCode < 100 || statusCode > 599 {
+			return nil, fmt.Errorf("invalid retryable status code %d", statusCode)
+		}
+	}
+
+	for _, networkError := range cfg.RetryableNetworkErrors {
+		switch networkError {
+		case config.NetworkErrorTimeout, config.NetworkErrorConnectionReset,
+			config.NetworkErrorConnectionRefused, config.NetworkErrorEOF:
+		default:
+			return nil, fmt.Errorf(
+				"unsupported retryable network error %q, supported network errors are %q, %q, %q and %q",
+				networkError, config.NetworkErrorTimeout, config.NetworkErrorConnectionReset,
+				config.NetworkErrorConnectionRefused, config.NetworkErrorEOF,
+			)
+		}
+	}
+
+	return &Policy{
+		maxAttempts:            cfg.MaxAttempts,
+		baseBackoff:            time.Duration(cfg.BaseBackoffMilliseconds) * time.Millisecond,
+		maxBackoff:             time.Duration(cfg.MaxBackoffMilliseconds) * time.Millisecond,
+		jitter:                 jitter,
+		retryableStatusCodes:   cfg.RetryableStatusCodes,
+		retryableNetworkErrors: cfg.RetryableNetworkErrors,
+	}, nil
+}
+
+// Do calls attempt until it returns a response or an error which is not retryable, the maximum number of
+// attempts is reached, or the next attempt cannot start before the deadline of ctx.
+// The response or error of the last attempt is returned.
+//
+// Before each retry, Do waits for the duration requested in the Retry-After header of the response, if any,
+// or for an exponential backoff. The bodies of responses which are retried are closed.
+func (p *Policy) Do(ctx context.Context, attempt func() (*http.Response, error)) (*http.Response, error) {
+	if p == nil {
+		return attempt()
+	}
+
+	for attemptNumber := 1; ; attemptNumber++ {
+		res, err := attempt()
+
+		if attemptNumber >= p.maxAttempts {
+			return res, err
+		}
+
+		var wait time.Duration
+
+		switch {
+		case err != nil:
+			if !p.retryableError(ctx, err) {
+				return res, err
+			}
+
+			wait = p.Backoff(attemptNumber)
+		case slices.Contains(p.retryableStatusCodes, res.StatusCode):
+			retryAfter, found := ParseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+			if found {
+				wait = retryAfter
+			} else {
+				wait = p.Backoff(attemptNumber)
+			}
+		default:
+			return res, err
+		}
+
+		// Do not wait for a retry which would exceed the deadline anyway.
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return res, err
+		}
+
+		if res != nil {
+			io.Copy(io.Discard, io.LimitReader(res.Body, maxDrainBytes))
+			res.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Backoff returns the exponential backoff before the retry following the provided attempt number,
+// starting at 1, reduced by a random jitter.
+func (p *Policy) Backoff(attemptNumber int) time.Duration {
+	backoff := p.maxBackoff
+
+	// Avoid overflowing when shifting the base backoff.
+	if shift := attemptNumber - 1; shift < 32 {
+		if exponential := p.baseBackoff << shift; exponential > 0 && exponential < p.maxBackoff {
+			backoff = exponential
+		}
+	}
+
+	return backoff - time.Duration(p.jitter*rand.Float64()*float64(backoff))
+}
+
+// retryableError returns true if the error of an attempt is a retryable network error.
+// Errors caused by the cancellation or the deadline of ctx are never retryable.
+func (p *Policy) retryableError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var netErr net.Error
+
+	for _, networkError := range p.retryableNetworkErrors {
+		switch networkError {
+		case config.NetworkErrorTimeout:
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return true
+			}
+		case config.NetworkErrorConnectionReset:
+			if errors.Is(err, syscall.ECONNRESET) {
+				return true
+			}
+		case config.NetworkErrorConnectionRefused:
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				return true
+			}
+		case config.NetworkErrorEOF:
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ParseRetryAfter parses the value of a Retry-After response header, which is either a number of seconds
+// or an HTTP-date, into the duration to wait from now.
+// https://datatracker.ietf.org/doc/html/rfc9110#section-10.2.3
+// Returns false if the header is not set or invalid.
+func ParseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(header, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		// Avoid overflowing time.Duration, any such duration exceeds all request timeouts.
+		if seconds > int64(math.MaxInt64/time.Second) {
+			return time.Duration(math.MaxInt64), true
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(date.Sub(now), 0), true
+}
@@ -0,0 +1,412 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// nolint: lll
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sgnl-ai/sample-adapter/pkg/config"
+	"github.com/sgnl-ai/sample-adapter/pkg/retry"
+	"github.com/sgnl-ai/sample-adapter/pkg/testutil"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		header    string
+		wantWait  time.Duration
+		wantFound bool
+	}{
+		"seconds": {
+			header:    "120",
+			wantWait:  2 * time.Minute,
+			wantFound: true,
+		},
+		"zero_seconds": {
+			header:    "0",
+			wantWait:  0,
+			wantFound: true,
+		},
+		"negative_seconds": {
+			header:    "-1",
+			wantFound: false,
+		},
+		"http_date": {
+			header:    "Sun, 01 Jun 2025 12:00:30 GMT",
+			wantWait:  30 * time.Second,
+			wantFound: true,
+		},
+		"http_date_rfc850": {
+			header:    "Sunday, 01-Jun-25 12:01:00 GMT",
+			wantWait:  time.Minute,
+			wantFound: true,
+		},
+		"http_date_in_the_past": {
+			header:    "Sun, 01 Jun 2025 11:00:00 GMT",
+			wantWait:  0,
+			wantFound: true,
+		},
+		"invalid": {
+			header:    "soon",
+			wantFound: false,
+		},
+		"empty": {
+			header:    "",
+			wantFound: false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotWait, gotFound := retry.ParseRetryAfter(tt.header, now)
+
+			if gotWait != tt.wantWait || gotFound != tt.wantFound {
+				t.Errorf("got (%v, %v), want (%v, %v)", gotWait, gotFound, tt.wantWait, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	validConfig := func(modify func(*config.RetryConfig)) *config.RetryConfig {
+		cfg := config.SetMissingCommonConfigDefaults(&config.CommonConfig{Retry: &config.RetryConfig{}}).Retry
+		modify(cfg)
+
+		return cfg
+	}
+
+	tests := map[string]struct {
+		config     *config.RetryConfig
+		wantPolicy bool
+		wantErr    string
+	}{
+		"nil_config": {
+			config:     nil,
+			wantPolicy: false,
+		},
+		"defaults": {
+			config:     validConfig(func(*config.RetryConfig) {}),
+			wantPolicy: true,
+		},
+		"negative_max_attempts": {
+			config:  validConfig(func(c *config.RetryConfig) { c.MaxAttempts = -1 }),
+			wantErr: "the maximum number of attempts must be at least 1, got -1",
+		},
+		"negative_backoff": {
+			config:  validConfig(func(c *config.RetryConfig) { c.BaseBackoffMilliseconds = -1 }),
+			wantErr: "the base and maximum backoffs must be positive",
+		},
+		"base_backoff_exceeds_max_backoff": {
+			config: validConfig(func(c *config.RetryConfig) {
+				c.BaseBackoffMilliseconds = 2000
+				c.MaxBackoffMilliseconds = 1000
+			}),
+			wantErr: "the base backoff of 2000ms must not exceed the maximum backoff of 1000ms",
+		},
+		"invalid_jitter": {
+			config:  validConfig(func(c *config.RetryConfig) { c.Jitter = testutil.GenPtr(1.5) }),
+			wantErr: "the jitter must be between 0 and 1, got 1.5",
+		},
+		"invalid_status_code": {
+			config:  validConfig(func(c *config.RetryConfig) { c.RetryableStatusCodes = []int{503, 1000} }),
+			wantErr: "invalid retryable status code 1000",
+		},
+		"unsupported_network_error": {
+			config:  validConfig(func(c *config.RetryConfig) { c.RetryableNetworkErrors = []string{"dns"} }),
+			wantErr: `unsupported retryable network error "dns", supported network errors are "timeout", "connectionReset", "connectionRefused" and "eof"`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotPolicy, gotErr := retry.NewPolicy(tt.config)
+
+			if (gotPolicy != nil) != tt.wantPolicy {
+				t.Errorf("gotPolicy: %v, wantPolicy: %v", gotPolicy, tt.wantPolicy)
+			}
+
+			var gotErrMessage string
+			if gotErr != nil {
+				gotErrMessage = gotErr.Error()
+			}
+
+			if gotErrMessage != tt.wantErr {
+				t.Errorf("gotErr: %v, wantErr: %v", gotErrMessage, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyBackoff(t *testing.T) {
+	policy, err := retry.NewPolicy(&config.RetryConfig{
+		MaxAttempts:             10,
+		BaseBackoffMilliseconds: 100,
+		MaxBackoffMilliseconds:  1000,
+		Jitter:                  testutil.GenPtr(0.0),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	wantBackoffs := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		1000 * time.Millisecond,
+		1000 * time.Millisecond,
+	}
+
+	for i, wantBackoff := range wantBackoffs {
+		if gotBackoff := policy.Backoff(i + 1); gotBackoff != wantBackoff {
+			t.Errorf("attempt %d: gotBackoff: %v, wantBackoff: %v", i+1, gotBackoff, wantBackoff)
+		}
+	}
+
+	if gotBackoff := policy.Backoff(100); gotBackoff != time.Second {
+		t.Errorf("gotBackoff: %v, wantBackoff: %v", gotBackoff, time.Second)
+	}
+
+	jitteredPolicy, err := retry.NewPolicy(&config.RetryConfig{
+		MaxAttempts:             10,
+		BaseBackoffMilliseconds: 100,
+		MaxBackoffMilliseconds:  1000,
+		Jitter:                  testutil.GenPtr(0.5),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for range 100 {
+		if gotBackoff := jitteredPolicy.Backoff(2); gotBackoff < 100*time.Millisecond || gotBackoff > 200*time.Millisecond {
+			t.Fatalf("gotBackoff: %v, want between 100ms and 200ms", gotBackoff)
+		}
+	}
+}
+
+func TestPolicyDo(t *testing.T) {
+	tests := map[string]struct {
+		// responses is the sequence of responses of the server. The last response is repeated.
+		responses    []func(w http.ResponseWriter)
+		retryConfig  *config.RetryConfig
+		timeout      time.Duration
+		wantStatus   int
+		wantErr      bool
+		wantAttempts int32
+	}{
+		"success_without_retry": {
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusOK, ""),
+			},
+			retryConfig:  fastRetryConfig(3),
+			wantStatus:   http.StatusOK,
+			wantAttempts: 1,
+		},
+		"retry_until_success": {
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusBadGateway, ""),
+				status(http.StatusServiceUnavailable, ""),
+				status(http.StatusOK, ""),
+			},
+			retryConfig:  fastRetryConfig(3),
+			wantStatus:   http.StatusOK,
+			wantAttempts: 3,
+		},
+		"max_attempts_reached": {
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusServiceUnavailable, ""),
+			},
+			retryConfig:  fastRetryConfig(3),
+			wantStatus:   http.StatusServiceUnavailable,
+			wantAttempts: 3,
+		},
+		"non_retryable_status_code": {
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusInternalServerError, ""),
+				status(http.StatusOK, ""),
+			},
+			retryConfig:  fastRetryConfig(3),
+			wantStatus:   http.StatusInternalServerError,
+			wantAttempts: 1,
+		},
+		"honors_retry_after_seconds": {
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusTooManyRequests, "0"),
+				status(http.StatusOK, ""),
+			},
+			retryConfig:  fastRetryConfig(2),
+			wantStatus:   http.StatusOK,
+			wantAttempts: 2,
+		},
+		// The Retry-After exceeds the timeout, so the 429 is returned without waiting.
+		"retry_after_exceeds_timeout": {
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusTooManyRequests, "60"),
+				status(http.StatusOK, ""),
+			},
+			retryConfig:  fastRetryConfig(3),
+			timeout:      time.Second,
+			wantStatus:   http.StatusTooManyRequests,
+			wantAttempts: 1,
+		},
+		"retry_after_http_date_exceeds_timeout": {
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusServiceUnavailable, time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)),
+				status(http.StatusOK, ""),
+			},
+			retryConfig:  fastRetryConfig(3),
+			timeout:      time.Second,
+			wantStatus:   http.StatusServiceUnavailable,
+			wantAttempts: 1,
+		},
+		"retry_closed_connection": {
+			responses: []func(w http.ResponseWriter){
+				closeConnection,
+				status(http.StatusOK, ""),
+			},
+			retryConfig:  fastRetryConfig(2),
+			wantStatus:   http.StatusOK,
+			wantAttempts: 2,
+		},
+		"closed_connection_not_retryable": {
+			responses: []func(w http.ResponseWriter){
+				closeConnection,
+				status(http.StatusOK, ""),
+			},
+			retryConfig: func() *config.RetryConfig {
+				cfg := fastRetryConfig(2)
+				cfg.RetryableNetworkErrors = []string{config.NetworkErrorTimeout}
+
+				return cfg
+			}(),
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+		"retries_disabled": {
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusServiceUnavailable, ""),
+				status(http.StatusOK, ""),
+			},
+			retryConfig:  nil,
+			wantStatus:   http.StatusServiceUnavailable,
+			wantAttempts: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var attempts atomic.Int32
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := int(attempts.Add(1))
+
+				tt.responses[min(attempt, len(tt.responses))-1](w)
+			}))
+			defer server.Close()
+
+			policy, err := retry.NewPolicy(tt.retryConfig)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			ctx := context.Background()
+
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			res, gotErr := policy.Do(ctx, func() (*http.Response, error) {
+				req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+				if err != nil {
+					return nil, err
+				}
+
+				return server.Client().Do(req)
+			})
+
+			if (gotErr != nil) != tt.wantErr {
+				t.Fatalf("gotErr: %v, wantErr: %v", gotErr, tt.wantErr)
+			}
+
+			if gotErr == nil {
+				defer res.Body.Close()
+
+				if res.StatusCode != tt.wantStatus {
+					t.Errorf("gotStatus: %d, wantStatus: %d", res.StatusCode, tt.wantStatus)
+				}
+			}
+
+			if gotAttempts := attempts.Load(); gotAttempts != tt.wantAttempts {
+				t.Errorf("gotAttempts: %d, wantAttempts: %d", gotAttempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestPolicyDoDeadlineExceeded(t *testing.T) {
+	policy, err := retry.NewPolicy(fastRetryConfig(3))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	<-ctx.Done()
+
+	var attempts int
+
+	_, gotErr := policy.Do(ctx, func() (*http.Response, error) {
+		attempts++
+
+		return nil, ctx.Err()
+	})
+
+	if !errors.Is(gotErr, context.DeadlineExceeded) {
+		t.Errorf("gotErr: %v, wantErr: %v", gotErr, context.DeadlineExceeded)
+	}
+
+	if attempts != 1 {
+		t.Errorf("gotAttempts: %d, wantAttempts: 1", attempts)
+	}
+}
+
+// fastRetryConfig returns a RetryConfig with the default retryable errors and short backoffs.
+func fastRetryConfig(maxAttempts int) *config.RetryConfig {
+	return config.SetMissingCommonConfigDefaults(&config.CommonConfig{Retry: &config.RetryConfig{
+		MaxAttempts:             maxAttempts,
+		BaseBackoffMilliseconds: 1,
+		MaxBackoffMilliseconds:  5,
+	}}).Retry
+}
+
+func status(statusCode int, retryAfter string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+
+		w.WriteHeader(statusCode)
+	}
+}
+
+// closeConnection closes the connection without writing a response.
+func closeConnection(w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		panic(err)
+	}
+
+	conn.Close()
+}
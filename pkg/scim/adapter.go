@@ -3,7 +3,11 @@ package scim
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 
 	framework "github.com/sgnl-ai/adapter-framework"
@@ -11,6 +15,8 @@ import (
 	"github.com/sgnl-ai/adapter-framework/web"
 	"github.com/sgnl-ai/sample-adapter/pkg/auth"
 	"github.com/sgnl-ai/sample-adapter/pkg/config"
+	"github.com/sgnl-ai/sample-adapter/pkg/retry"
+	"github.com/sgnl-ai/sample-adapter/pkg/scim/filter"
 )
 
 // Adapter implements the framework.Adapter interface to query pages of objects
@@ -18,13 +24,78 @@ import (
 type Adapter struct {
 	// Client provides access to the datasource.
 	Client Client
+
+	// WatermarkHandler is called with the IncrementalSyncConfig of the next sync when an incremental sync
+	// completes, which must be saved and set in the datasource config of the next sync.
+	// Optional.
+	WatermarkHandler WatermarkHandler
+
+	// CursorSigningKeys are the keys used to sign and verify the cursors returned by the adapter.
+	// Cursors are signed with the first key, and verified with any of the keys.
+	// Optional. Cursors are not signed if empty.
+	CursorSigningKeys [][]byte
+
+	// CoercionFailureHandler is called for each attribute whose values could not be coerced into their types.
+	// Optional.
+	CoercionFailureHandler CoercionFailureHandler
+
+	// TruncatedGroupMembersHandler is called for each group whose members were truncated to the maximum
+	// number of members per group.
+	// Optional.
+	TruncatedGroupMembersHandler TruncatedGroupMembersHandler
+}
+
+// normalizedAttributePaths are the paths of the attributes read by the adapter, whose names are normalized in
+// addition to the names of the entity's attributes.
+var normalizedAttributePaths = []string{"id", "schemas", LastModifiedAttribute, UserGroupsAttribute + ".value"}
+
+// AdapterOption configures optional behavior of an Adapter.
+type AdapterOption func(*Adapter)
+
+// WithWatermarkHandler sets the handler called with the IncrementalSyncConfig of the next sync, i.e. with the
+// new high-water mark, when an incremental sync completes.
+func WithWatermarkHandler(handler WatermarkHandler) AdapterOption {
+	return func(a *Adapter) {
+		a.WatermarkHandler = handler
+	}
+}
+
+// WithCursorSigningKeys sets the keys used to sign and verify the cursors returned by the adapter.
+// Cursors are signed with the first key, and verified with any of the keys, so that a new signing key can be
+// added first while the previous keys still verify the cursors of syncs in progress.
+func WithCursorSigningKeys(keys ...[]byte) AdapterOption {
+	return func(a *Adapter) {
+		a.CursorSigningKeys = keys
+	}
+}
+
+// WithCoercionFailureHandler sets the handler called for each attribute whose values could not be coerced
+// into their types.
+func WithCoercionFailureHandler(handler CoercionFailureHandler) AdapterOption {
+	return func(a *Adapter) {
+		a.CoercionFailureHandler = handler
+	}
+}
+
+// WithTruncatedGroupMembersHandler sets the handler called for each group whose members were truncated to
+// the maximum number of members per group.
+func WithTruncatedGroupMembersHandler(handler TruncatedGroupMembersHandler) AdapterOption {
+	return func(a *Adapter) {
+		a.TruncatedGroupMembersHandler = handler
+	}
 }
 
 // NewAdapter instantiates a new Adapter.
-func NewAdapter(client Client) framework.Adapter[Config] {
-	return &Adapter{
+func NewAdapter(client Client, opts ...AdapterOption) framework.Adapter[Config] {
+	adapter := &Adapter{
 		Client: client,
 	}
+
+	for _, opt := range opts {
+		opt(adapter)
+	}
+
+	return adapter
 }
 
 // GetPage is called by SGNL's ingestion service to query a page of objects
@@ -56,9 +127,24 @@ func (a *Adapter) RequestPageFromDatasource(
 		request.Address = "https://" + request.Address
 	}
 
-	var authorizationHeader string
+	var (
+		authorizationHeader string
+		oauth2Config        *auth.OAuth2Config
+	)
 
 	switch {
+	case request.Config != nil && request.Config.OAuth2 != nil:
+		// The access tokens are requested when sending the requests, so that they are refreshed if rejected.
+		oauth2Config = new(auth.OAuth2Config)
+		*oauth2Config = *request.Config.OAuth2
+		oauth2Config.ClientID, oauth2Config.ClientSecret = oauth2ClientCredentials(request)
+	case request.Auth == nil:
+		return framework.NewGetPageResponseError(
+			&framework.Error{
+				Message: "No valid credentials provided.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_AUTHENTICATION_FAILED,
+			},
+		)
 	case request.Auth.Basic != nil:
 		authorizationHeader = auth.BasicAuthHeader(request.Auth.Basic.Username, request.Auth.Basic.Password)
 	case request.Auth.HTTPAuthorization != "":
@@ -72,13 +158,29 @@ func (a *Adapter) RequestPageFromDatasource(
 		)
 	}
 
+	// The retry config is validated in ValidateGetPageRequest.
+	retryPolicy, _ := retry.NewPolicy(commonConfig.Retry)
+
 	req := &Request{
 		BaseURL:               request.Address,
 		AuthorizationHeader:   authorizationHeader,
+		OAuth2:                oauth2Config,
 		PageSize:              request.PageSize,
 		EntityExternalID:      request.Entity.ExternalId,
-		Cursor:                request.Cursor,
 		RequestTimeoutSeconds: *commonConfig.RequestTimeoutSeconds,
+		MaxURLLength:          DefaultMaxURLLength,
+		MaxResponseBodyBytes:  DefaultMaxResponseBodyBytes,
+		RetryPolicy:           retryPolicy,
+		RateLimit:             commonConfig.RateLimit,
+		TLS:                   commonConfig.TLS,
+	}
+
+	if request.Config != nil && request.Config.MaxURLLength > 0 {
+		req.MaxURLLength = request.Config.MaxURLLength
+	}
+
+	if request.Config != nil && request.Config.MaxResponseBodyBytes > 0 {
+		req.MaxResponseBodyBytes = request.Config.MaxResponseBodyBytes
 	}
 
 	if request.Config != nil && request.Config.QueryParams != nil {
@@ -87,17 +189,185 @@ func (a *Adapter) RequestPageFromDatasource(
 		}
 	}
 
+	// The cursor records the position of the next page and the state of the sync. It is rejected if
+	// the query changed since the sync started, as the position would not be valid.
+	queryHash := QueryHash(request)
+	cursor := &Cursor{}
+
+	if request.Cursor != "" {
+		var decodeErr error
+		cursor, decodeErr = DecodeCursor(request.Cursor, a.CursorSigningKeys)
+
+		if errors.Is(decodeErr, ErrInvalidCursorSignature) {
+			return framework.NewGetPageResponseError(
+				&framework.Error{
+					Message: "Invalid cursor signature. The cursor was not issued by this adapter, " +
+						"or its signing key is no longer accepted. Restart the sync.",
+					Code: api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_PAGE_REQUEST_CONFIG,
+				},
+			)
+		}
+
+		if decodeErr != nil {
+			return framework.NewGetPageResponseError(
+				&framework.Error{
+					Message: fmt.Sprintf("Invalid cursor: %v.", decodeErr),
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_PAGE_REQUEST_CONFIG,
+				},
+			)
+		}
+
+		if !cursorMatchesQuery(cursor, queryHash, req.QueryParams.PagingMode) {
+			return framework.NewGetPageResponseError(
+				&framework.Error{
+					Message: fmt.Sprintf(
+						"The cursor does not match the current query of entity %s. Restart the sync.",
+						request.Entity.ExternalId,
+					),
+					Code: api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_PAGE_REQUEST_CONFIG,
+				},
+			)
+		}
+	}
+
+	// During an incremental sync, the cursor carries the watermark and the latest "meta.lastModified"
+	// seen so far. Legacy cursors do not, so the sync continues from the config.
+	var incrementalSync *IncrementalSyncState
+
+	if request.Config != nil {
+		if incrementalSyncConfig, found := request.Config.IncrementalSync[request.Entity.ExternalId]; found {
+			incrementalSync = cursor.IncrementalSync
+			if incrementalSync == nil {
+				incrementalSync = newIncrementalSyncState(incrementalSyncConfig)
+			}
+
+			var filterErr error
+			if req.QueryParams.Filter, filterErr = incrementalSync.filter(req.QueryParams.Filter); filterErr != nil {
+				return framework.NewGetPageResponseError(
+					&framework.Error{
+						Message: fmt.Sprintf("Invalid filter for entity %s: %v.", request.Entity.ExternalId, filterErr),
+						Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+					},
+				)
+			}
+		}
+	}
+
+	// The SCIM server's capabilities are used to validate the query parameters, if known.
+	// Errors are ignored as the ServiceProviderConfig is not required to request pages.
+	filterSupported := true
+
+	if serviceProviderConfig, _ := a.Client.GetServiceProviderConfig(ctx, req); serviceProviderConfig != nil {
+		if err := ValidateServiceProviderCapabilities(
+			serviceProviderConfig, req.EntityExternalID, req.QueryParams,
+		); err != nil {
+			return framework.NewGetPageResponseError(err)
+		}
+
+		if maxResults := serviceProviderConfig.Filter.MaxResults; maxResults > 0 && req.PageSize > maxResults {
+			req.PageSize = maxResults
+		}
+
+		filterSupported = serviceProviderConfig.Filter.Supported
+	}
+
+	// The filter is evaluated in the adapter if the SCIM server does not support filtering.
+	var localFilter filter.Expression
+
+	if req.QueryParams.LocalFilter || cursor.LocalFilter || !filterSupported {
+		var err *framework.Error
+		if localFilter, err = takeLocalFilter(req); err != nil {
+			return framework.NewGetPageResponseError(err)
+		}
+	}
+
+	req.PagingMode = req.QueryParams.PagingMode
+
+	// When the paging mode is detected automatically, the cursor records the paging mode detected
+	// on the first page.
+	if request.Cursor != "" {
+		req.PagingMode = cursor.PagingMode
+
+		if cursor.PagingMode == PagingModeCursor {
+			req.Cursor = cursor.ServerCursor
+		} else {
+			req.Cursor = strconv.FormatInt(cursor.StartIndex, 10)
+		}
+	}
+
+	// Group members are requested separately for each group, so exclude them from the listing.
+	groupMembers := request.Config != nil && request.Config.GroupMembers != nil &&
+		hasChildEntity(&request.Entity, GroupMembersAttribute)
+	if groupMembers {
+		req.ExcludedAttributes = []string{GroupMembersAttribute}
+	}
+
+	req.Attributes = requestedAttributes(request, incrementalSync != nil, groupMembers, localFilter)
+
 	resp, err := a.Client.GetPage(ctx, req)
 	if err != nil {
 		return framework.NewGetPageResponseError(err)
 	}
 
+	// Some SCIM servers respond with 501 Not Implemented to filtered requests, in which case the page is
+	// requested again without the filter, which is evaluated in the adapter for the rest of the sync.
+	if resp.StatusCode == http.StatusNotImplemented && req.QueryParams.Filter != "" {
+		if localFilter, err = takeLocalFilter(req); err != nil {
+			return framework.NewGetPageResponseError(err)
+		}
+
+		req.Attributes = requestedAttributes(request, incrementalSync != nil, groupMembers, localFilter)
+
+		if resp, err = a.Client.GetPage(ctx, req); err != nil {
+			return framework.NewGetPageResponseError(err)
+		}
+	}
+
 	// An adapter error message is generated if the response status code is not
-	// successful (i.e. if not statusCode >= 200 && statusCode < 300).
-	if adapterErr := web.HTTPError(resp.StatusCode, resp.RetryAfterHeader); adapterErr != nil {
+	// successful (i.e. if not statusCode >= 200 && statusCode < 300), including the SCIM error
+	// returned by the datasource, if any.
+	if adapterErr := HTTPError(resp.StatusCode, resp.RetryAfterHeader, resp.ErrorResponse); adapterErr != nil {
 		return framework.NewGetPageResponseError(adapterErr)
 	}
 
+	// SCIM attribute names are case-insensitive, so the members of the resources are renamed to the names of
+	// the entity's attributes, and of the attributes read by the adapter.
+	NormalizeAttributeNames(&request.Entity, resp.Objects, normalizedAttributePaths...)
+
+	// The resources are filtered before the next cursor is computed from the response, so that paging
+	// advances by the number of resources returned by the SCIM server.
+	if localFilter != nil {
+		resp.Objects = slices.DeleteFunc(resp.Objects, func(object map[string]any) bool {
+			return !filter.Match(localFilter, object)
+		})
+	}
+
+	if incrementalSync != nil {
+		incrementalSync.observe(resp.Objects)
+	}
+
+	// Group memberships are ingested as child objects of the User objects, or of the Group objects
+	// if the SCIM server does not return the groups of users.
+	ExpandUserGroupMemberships(&request.Entity, resp.Objects)
+
+	if groupMembers {
+		if err := a.ExpandGroupMembers(ctx, req, request, resp.Objects); err != nil {
+			return framework.NewGetPageResponseError(err)
+		}
+
+		// The members are returned by separate requests, so their names are normalized once expanded.
+		NormalizeAttributeNames(&request.Entity, resp.Objects)
+	}
+
+	// Attribute values which do not match their types are coerced, if enabled, so that a single value
+	// does not fail the page.
+	if request.Config != nil {
+		switch request.Config.TypeCoercion {
+		case TypeCoercionEntity, TypeCoercionSchema:
+			a.coerceObjects(ctx, req, request, resp.Objects, commonConfig.LocalTimeZoneOffset)
+		}
+	}
+
 	// The raw JSON objects from the response must be parsed and converted into framework.Objects.
 	// Nested attributes are flattened and delimited by the delimiter specified.
 	// DateTime values are parsed using the specified DateTimeFormatWithTimeZone.
@@ -116,8 +386,123 @@ func (a *Adapter) RequestPageFromDatasource(
 		)
 	}
 
+	var nextCursor string
+
+	switch {
+	case resp.NextCursor != "":
+		next := &Cursor{
+			PagingMode:      resp.PagingMode,
+			TotalResults:    resp.TotalResults,
+			QueryHash:       queryHash,
+			LocalFilter:     localFilter != nil,
+			IncrementalSync: incrementalSync,
+		}
+
+		if resp.PagingMode == PagingModeCursor {
+			next.ServerCursor = resp.NextCursor
+		} else {
+			nextStartIndex, _ := strconv.ParseInt(resp.NextCursor, 10, 64)
+			next.StartIndex = adjustNextStartIndex(cursor, nextStartIndex, resp.TotalResults)
+		}
+
+		nextCursor = EncodeCursor(next, a.signingKey())
+	case incrementalSync != nil && a.WatermarkHandler != nil && incrementalSync.MaxLastModified != "":
+		a.WatermarkHandler(request.Address, request.Entity.ExternalId, IncrementalSyncConfig{
+			Since:          incrementalSync.MaxLastModified,
+			OverlapSeconds: request.Config.IncrementalSync[request.Entity.ExternalId].OverlapSeconds,
+		})
+	}
+
 	return framework.NewGetPageResponseSuccess(&framework.Page{
 		Objects:    parsedObjects,
-		NextCursor: resp.NextCursor,
+		NextCursor: nextCursor,
 	})
 }
+
+// takeLocalFilter removes the filter from a request, and returns it to be evaluated in the adapter.
+// Returns nil if the request has no filter.
+func takeLocalFilter(req *Request) (filter.Expression, *framework.Error) {
+	if req.QueryParams.Filter == "" {
+		return nil, nil
+	}
+
+	expression, err := filter.Parse(req.QueryParams.Filter)
+	if err != nil {
+		return nil, &framework.Error{
+			Message: fmt.Sprintf("Invalid filter for entity %s: %v.", req.EntityExternalID, err),
+			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+		}
+	}
+
+	req.QueryParams.Filter = ""
+
+	return expression, nil
+}
+
+// requestedAttributes returns the attributes to request for an entity, including the attributes required
+// by the incremental sync, the local filter and the schema type coercion, if any.
+// Returns nil to request all attributes.
+func requestedAttributes(
+	request *framework.Request[Config],
+	incrementalSync bool,
+	groupMembers bool,
+	localFilter filter.Expression,
+) []string {
+	// Only request the attributes required for the entity, unless disabled.
+	if request.Config != nil && request.Config.RequestAllAttributes {
+		return nil
+	}
+
+	var additionalPaths []string
+	if incrementalSync {
+		additionalPaths = append(additionalPaths, LastModifiedAttribute)
+	}
+
+	if localFilter != nil {
+		additionalPaths = append(additionalPaths, filter.Paths(localFilter)...)
+	}
+
+	// The schemas of each resource select the Schemas used to coerce its attribute values, and servers may
+	// only return the requested attributes.
+	if request.Config != nil && request.Config.TypeCoercion == TypeCoercionSchema {
+		additionalPaths = append(additionalPaths, "schemas")
+	}
+
+	attributes := AttributePaths(&request.Entity, additionalPaths...)
+
+	// Group members are requested separately for each group, so they are excluded from the listing.
+	if groupMembers && attributes != nil {
+		attributes = slices.DeleteFunc(attributes, func(path string) bool {
+			return strings.EqualFold(path, GroupMembersAttribute)
+		})
+	}
+
+	return attributes
+}
+
+// oauth2ClientCredentials returns the OAuth2 client ID and secret of a request, from its OAuth2 config, or else
+// from its basic auth credentials.
+func oauth2ClientCredentials(request *framework.Request[Config]) (clientID string, clientSecret string) {
+	clientID, clientSecret = request.Config.OAuth2.ClientID, request.Config.OAuth2.ClientSecret
+
+	if request.Auth != nil && request.Auth.Basic != nil {
+		if clientID == "" {
+			clientID = request.Auth.Basic.Username
+		}
+
+		if clientSecret == "" {
+			clientSecret = request.Auth.Basic.Password
+		}
+	}
+
+	return clientID, clientSecret
+}
+
+// signingKey returns the key used to sign the cursors returned by the adapter, if any.
+func (a *Adapter) signingKey() []byte {
+	if len(a.CursorSigningKeys) == 0 {
+		return nil
+	}
+
+	return a.CursorSigningKeys[0]
+}
@@ -5,13 +5,24 @@ package scim_test
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	framework "github.com/sgnl-ai/adapter-framework"
 	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
+	"github.com/sgnl-ai/sample-adapter/pkg/auth"
 	"github.com/sgnl-ai/sample-adapter/pkg/config"
 	"github.com/sgnl-ai/sample-adapter/pkg/scim"
 	"github.com/sgnl-ai/sample-adapter/pkg/testutil"
@@ -25,14 +36,45 @@ var (
 func TestAdapterGetPage(t *testing.T) {
 	server := httptest.NewTLSServer(TestServerHandler)
 	baseURL := server.URL
+
+	// Requests to example.com are sent to a server whose self-signed certificate is not trusted, so that the
+	// invalid certificate is not served by the real host.
+	untrustedCert := newTestCertificate(t, "example.com", nil)
+
+	untrustedServer := httptest.NewUnstartedServer(TestServerHandler)
+	untrustedServer.TLS = &tls.Config{
+		Certificates: []tls.Certificate{
+			{
+				Certificate: [][]byte{untrustedCert.cert.Raw},
+				PrivateKey:  untrustedCert.key,
+			},
+		},
+	}
+
+	untrustedServer.StartTLS()
+	defer untrustedServer.Close()
+
+	client := server.Client()
+	client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr == "example.com:443" {
+			addr = untrustedServer.Listener.Addr().String()
+		}
+
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}
+
 	adapter := scim.NewAdapter(&scim.Datasource{
-		Client: server.Client(),
+		Client: client,
 	})
 
 	tests := map[string]struct {
-		ctx          context.Context
-		request      *framework.Request[scim.Config]
+		ctx     context.Context
+		request *framework.Request[scim.Config]
+		// cursor, if set, is encoded for the query of the request and replaces the request's cursor.
+		cursor       *scim.Cursor
 		wantResponse framework.Response
+		// wantNextCursor, if set, is encoded for the query of the request as the expected next cursor.
+		wantNextCursor *scim.Cursor
 	}{
 		"valid_user_request_without_common_config": {
 			ctx: context.Background(),
@@ -247,31 +289,101 @@ func TestAdapterGetPage(t *testing.T) {
 				},
 			},
 		},
-		"invalid_request_missing_auth": {
+		"group_membership_child_objects_from_valid_user_request": {
+			ctx: context.Background(),
 			request: &framework.Request[scim.Config]{
-				Address: "example.com",
+				Address: baseURL,
+				Auth: &framework.DatasourceAuthCredentials{
+					Basic: &framework.BasicAuthCredentials{
+						Username: testUsername,
+						Password: testPassword,
+					},
+				},
 				Entity: framework.EntityConfig{
 					ExternalId: scimUser,
 					Attributes: []*framework.AttributeConfig{
 						{
 							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+							List:       false,
+						},
+					},
+					ChildEntities: []*framework.EntityConfig{
+						{
+							ExternalId: "groups",
+							Attributes: []*framework.AttributeConfig{
+								{
+									ExternalId: "id",
+									Type:       framework.AttributeTypeString,
+									UniqueId:   true,
+								},
+								{
+									ExternalId: "userId",
+									Type:       framework.AttributeTypeString,
+								},
+								{
+									ExternalId: "value",
+									Type:       framework.AttributeTypeString,
+								},
+								{
+									ExternalId: `$["$ref"]`,
+									Type:       framework.AttributeTypeString,
+								},
+								{
+									ExternalId: "display",
+									Type:       framework.AttributeTypeString,
+								},
+								{
+									ExternalId: "type",
+									Type:       framework.AttributeTypeString,
+								},
+							},
 						},
 					},
 				},
+
+				PageSize: 2,
+				Cursor:   "5", // startIndex=5 contains Full Enterprise User Extension Representation
 			},
 			wantResponse: framework.Response{
-				Error: &framework.Error{
-					Message: "SCIM auth is missing required credentials.",
-					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{
+							"id": "2819c223-7f76-453a-919d-413861904000",
+							"groups": []framework.Object{
+								{
+									"id":        "2819c223-7f76-453a-919d-413861904000:e9e30dba-f08f-4109-8486-d5c6a331660a",
+									"userId":    "2819c223-7f76-453a-919d-413861904000",
+									"value":     "e9e30dba-f08f-4109-8486-d5c6a331660a",
+									`$["$ref"]`: "../Groups/e9e30dba-f08f-4109-8486-d5c6a331660a",
+									"display":   "Tour Guides",
+									"type":      "direct",
+								},
+								{
+									"id":        "2819c223-7f76-453a-919d-413861904000:fc348aa8-3835-40eb-a20b-c726e15c55b5",
+									"userId":    "2819c223-7f76-453a-919d-413861904000",
+									"value":     "fc348aa8-3835-40eb-a20b-c726e15c55b5",
+									`$["$ref"]`: "../Groups/fc348aa8-3835-40eb-a20b-c726e15c55b5",
+									"display":   "Employees",
+								},
+								{
+									"id":        "2819c223-7f76-453a-919d-413861904000:71ddacd2-a8e7-49b8-a5db-ae50d0a5bfd7",
+									"userId":    "2819c223-7f76-453a-919d-413861904000",
+									"value":     "71ddacd2-a8e7-49b8-a5db-ae50d0a5bfd7",
+									`$["$ref"]`: "../Groups/71ddacd2-a8e7-49b8-a5db-ae50d0a5bfd7",
+									"display":   "US Employees",
+								},
+							},
+						},
+					},
+					NextCursor: "",
 				},
 			},
 		},
-		// This test ensures that if the SCIM SoR returns a non successful status code, we return an
-		// appropriate error.
-		"scim_request_returns_400": {
+		"valid_user_request_auto_paging_detects_cursor": {
 			ctx: context.Background(),
 			request: &framework.Request[scim.Config]{
-				Address: server.URL,
+				Address: baseURL,
 				Auth: &framework.DatasourceAuthCredentials{
 					Basic: &framework.BasicAuthCredentials{
 						Username: testUsername,
@@ -288,24 +400,33 @@ func TestAdapterGetPage(t *testing.T) {
 						},
 					},
 				},
-
-				PageSize: 1,
-				Cursor:   "400",
+				Config: &scim.Config{
+					QueryParams: map[string]scim.QueryParams{
+						scimUser: {
+							PagingMode: scim.PagingModeAuto,
+						},
+					},
+				},
+				PageSize: 2,
 			},
 			wantResponse: framework.Response{
-				Error: &framework.Error{
-					Message: "Datasource rejected request, returned status code: 400.",
-					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{"id": "2819c223-7f76-453a-919d-413861904646"},
+						{"id": "c75ad752-64ae-4823-840d-ffa80929976c"},
+					},
 				},
 			},
+			wantNextCursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeCursor,
+				ServerCursor: "VZUTiyhEQJ94IR",
+				TotalResults: 5,
+			},
 		},
-		// This test case uses a random URL instead of the test server's URL, so we should expect an invalid cert.
-		// This test case also verifies that the "https://" prefix is added to the address if it's not present
-		// which is evident by the error message.
-		"failed_to_make_get_page_request_invalid_certs": {
+		"valid_user_request_auto_paging_with_cursor": {
 			ctx: context.Background(),
 			request: &framework.Request[scim.Config]{
-				Address: "example.com",
+				Address: baseURL,
 				Auth: &framework.DatasourceAuthCredentials{
 					Basic: &framework.BasicAuthCredentials{
 						Username: testUsername,
@@ -322,25 +443,38 @@ func TestAdapterGetPage(t *testing.T) {
 						},
 					},
 				},
-
-				PageSize: 1,
+				Config: &scim.Config{
+					QueryParams: map[string]scim.QueryParams{
+						scimUser: {
+							PagingMode: scim.PagingModeAuto,
+						},
+					},
+				},
+				PageSize: 2,
+			},
+			cursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeCursor,
+				ServerCursor: "VZUTiyhEQJ94IR",
+				TotalResults: 5,
 			},
 			wantResponse: framework.Response{
-				Error: &framework.Error{
-					Message: `Failed to execute SCIM request: ` +
-						`Get "https://example.com/Users?startIndex=1&count=1": tls: failed to verify certificate: x509: ` +
-						`certificate signed by unknown authority.`,
-					Code: api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{"id": "e2be737c-61f5-4abe-8797-1e816b15cec8"},
+						{"id": "89fa657e-3ef5-49e3-bb34-b3255e04a8bb"},
+					},
 				},
 			},
+			wantNextCursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeCursor,
+				ServerCursor: "YkU3OF86Pz0rGv",
+				TotalResults: 5,
+			},
 		},
-		"failed_to_make_get_page_request_invalid_host": {
+		"valid_group_request_auto_paging_falls_back_to_index": {
 			ctx: context.Background(),
 			request: &framework.Request[scim.Config]{
-				// Deliberately add the extra "/".
-				// This test case also indirectly verifies that the "https://" prefix is NOT added
-				// if the prefix already exists.
-				Address: "https:///example.com",
+				Address: baseURL,
 				Auth: &framework.DatasourceAuthCredentials{
 					Basic: &framework.BasicAuthCredentials{
 						Username: testUsername,
@@ -348,7 +482,7 @@ func TestAdapterGetPage(t *testing.T) {
 					},
 				},
 				Entity: framework.EntityConfig{
-					ExternalId: scimUser,
+					ExternalId: scimGroup,
 					Attributes: []*framework.AttributeConfig{
 						{
 							ExternalId: "id",
@@ -357,150 +491,1385 @@ func TestAdapterGetPage(t *testing.T) {
 						},
 					},
 				},
-
-				PageSize: 1,
+				Config: &scim.Config{
+					QueryParams: map[string]scim.QueryParams{
+						scimGroup: {
+							PagingMode: scim.PagingModeAuto,
+						},
+					},
+				},
+				PageSize: 2,
+				Cursor:   "3",
 			},
 			wantResponse: framework.Response{
-				Error: &framework.Error{
-					Message: `Failed to execute SCIM request: ` +
-						`Get "https:///example.com/Users?startIndex=1&count=1": http: no Host in request URL.`,
-					Code: api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{"id": "e2be737c-61f5-4abe-8797-1e816b15cec8"},
+						{"id": "89fa657e-3ef5-49e3-bb34-b3255e04a8bb"},
+					},
 				},
 			},
+			wantNextCursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   5,
+				TotalResults: 5,
+			},
 		},
-		// The endpoint is setup to sleep for 5 seconds.
-		// The request timeout is configured for 1 seconds.
-		"user_request_timeout_with_common_config": {
-			ctx: context.Background(),
+		"invalid_request_unsupported_paging_mode": {
 			request: &framework.Request[scim.Config]{
-				Address: baseURL,
+				Address: "example.com",
 				Auth: &framework.DatasourceAuthCredentials{
-					Basic: &framework.BasicAuthCredentials{
-						Username: testUsername,
-						Password: testPassword,
-					},
+					HTTPAuthorization: "Bearer token",
 				},
 				Entity: framework.EntityConfig{
 					ExternalId: scimUser,
 					Attributes: []*framework.AttributeConfig{
 						{
 							ExternalId: "id",
-							Type:       framework.AttributeTypeString,
-							List:       false,
 						},
-						{
-							ExternalId: "userName",
-							Type:       framework.AttributeTypeString,
-							List:       false,
+					},
+				},
+				Config: &scim.Config{
+					QueryParams: map[string]scim.QueryParams{
+						scimUser: {
+							PagingMode: "offset",
 						},
 					},
-					ChildEntities: []*framework.EntityConfig{
+				},
+			},
+			wantResponse: framework.Response{
+				Error: &framework.Error{
+					Message: `Unsupported paging mode "offset" for entity Users. Supported paging modes are "index", "cursor" and "auto".`,
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+				},
+			},
+		},
+		"invalid_request_unsupported_type_coercion_mode": {
+			request: &framework.Request[scim.Config]{
+				Address: "example.com",
+				Auth: &framework.DatasourceAuthCredentials{
+					HTTPAuthorization: "Bearer token",
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
 						{
-							ExternalId: "emails",
-							Attributes: []*framework.AttributeConfig{
-								{
-									ExternalId: "value",
-									Type:       framework.AttributeTypeString,
-								},
-								{
-									ExternalId: "type",
-									Type:       framework.AttributeTypeString,
-								},
-								{
-									ExternalId: "primary",
-									Type:       framework.AttributeTypeBool,
-								},
-							},
+							ExternalId: "id",
 						},
 					},
 				},
 				Config: &scim.Config{
-					CommonConfig: &config.CommonConfig{
-						RequestTimeoutSeconds: testutil.GenPtr(1),
-					},
+					TypeCoercion: "strict",
 				},
-				PageSize: 1,
-				Cursor:   "408",
 			},
 			wantResponse: framework.Response{
 				Error: &framework.Error{
-					Message: "Failed to execute SCIM request: Get \"" + baseURL + "/Users?startIndex=408&count=1\": context deadline exceeded. Request exceeded configured timeout of 1 seconds. Please increase the request timeout.",
-					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+					Message: `Unsupported type coercion mode "strict". Supported type coercion modes are "none", "entity" and "schema".`,
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
 				},
 			},
 		},
-
-		// The endpoint is setup to sleep for 5 seconds.
-		// The request timeout is configured for 1 seconds.
-		"group_request_timeout_with_common_config": {
-			ctx: context.Background(),
+		"invalid_request_oauth2_http_token_url": {
 			request: &framework.Request[scim.Config]{
-				Address: baseURL,
+				Address: "example.com",
 				Auth: &framework.DatasourceAuthCredentials{
 					Basic: &framework.BasicAuthCredentials{
-						Username: testUsername,
-						Password: testPassword,
+						Username: "client",
+						Password: "secret",
 					},
 				},
 				Entity: framework.EntityConfig{
-					ExternalId: scimGroup,
+					ExternalId: scimUser,
 					Attributes: []*framework.AttributeConfig{
 						{
 							ExternalId: "id",
-							Type:       framework.AttributeTypeString,
-							List:       false,
-						},
-						{
-							ExternalId: "displayName",
-							Type:       framework.AttributeTypeString,
-							List:       false,
-						},
-						{
-							ExternalId: "$.meta.created",
-							Type:       framework.AttributeTypeDateTime,
-							List:       false,
 						},
 					},
-					ChildEntities: []*framework.EntityConfig{
+				},
+				Config: &scim.Config{
+					OAuth2: &auth.OAuth2Config{
+						TokenURL: "http://login.example.com/oauth2/token",
+					},
+				},
+			},
+			wantResponse: framework.Response{
+				Error: &framework.Error{
+					Message: "Invalid OAuth2 config: the token URL must use HTTPS.",
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+				},
+			},
+		},
+		"invalid_request_oauth2_missing_client_secret": {
+			request: &framework.Request[scim.Config]{
+				Address: "example.com",
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
 						{
-							ExternalId: "members",
-							Attributes: []*framework.AttributeConfig{
-								{
-									ExternalId: "value",
-									Type:       framework.AttributeTypeString,
-								},
-								{
-									ExternalId: "type",
-									Type:       framework.AttributeTypeString,
-								},
-							},
+							ExternalId: "id",
 						},
 					},
 				},
 				Config: &scim.Config{
-					CommonConfig: &config.CommonConfig{
-						RequestTimeoutSeconds: testutil.GenPtr(1),
+					OAuth2: &auth.OAuth2Config{
+						TokenURL: "https://login.example.com/oauth2/token",
+						ClientID: "client",
 					},
 				},
-				PageSize: 1,
-				Cursor:   "408",
 			},
 			wantResponse: framework.Response{
 				Error: &framework.Error{
-					Message: "Failed to execute SCIM request: Get \"" + baseURL + "/Groups?startIndex=408&count=1\": context deadline exceeded. Request exceeded configured timeout of 1 seconds. Please increase the request timeout.",
-					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+					Message: "OAuth2 requires a client ID and secret, in the OAuth2 config or as basic auth credentials.",
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
 				},
 			},
 		},
-	}
-
-	for name, tt := range tests {
-		t.Run(name, func(t *testing.T) {
-			gotResponse := adapter.GetPage(tt.ctx, tt.request)
-
+		"invalid_request_oauth2_invalid_assertion_private_key": {
+			ctx: context.Background(),
+			request: &framework.Request[scim.Config]{
+				Address: "example.com",
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+						},
+					},
+				},
+				Config: &scim.Config{
+					OAuth2: &auth.OAuth2Config{
+						TokenURL:         "https://login.example.com/oauth2/token",
+						ClientID:         "client",
+						ClientAuthMethod: auth.PrivateKeyJWT,
+						Assertion: &auth.JWTAssertionConfig{
+							PrivateKey: "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n",
+						},
+					},
+				},
+			},
+			wantResponse: framework.Response{
+				Error: &framework.Error{
+					Message: `Failed to execute SCIM request: invalid OAuth2 config: invalid assertion private key: unsupported private key PEM block type "CERTIFICATE".`,
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+				},
+			},
+		},
+		"invalid_request_malformed_filter": {
+			request: &framework.Request[scim.Config]{
+				Address: "example.com",
+				Auth: &framework.DatasourceAuthCredentials{
+					HTTPAuthorization: "Bearer token",
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+						},
+					},
+				},
+				Config: &scim.Config{
+					QueryParams: map[string]scim.QueryParams{
+						scimUser: {
+							Filter: `userType eq "Employee" and (emails co "sgnl.com"`,
+						},
+					},
+				},
+			},
+			wantResponse: framework.Response{
+				Error: &framework.Error{
+					Message: `Invalid filter for entity Users: expected ")", got end of filter at position 49.`,
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+				},
+			},
+		},
+		"invalid_request_missing_auth": {
+			request: &framework.Request[scim.Config]{
+				Address: "example.com",
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+						},
+					},
+				},
+			},
+			wantResponse: framework.Response{
+				Error: &framework.Error{
+					Message: "SCIM auth is missing required credentials.",
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+				},
+			},
+		},
+		// This test ensures that if the SCIM SoR returns a non successful status code, we return an
+		// appropriate error.
+		"scim_request_returns_400": {
+			ctx: context.Background(),
+			request: &framework.Request[scim.Config]{
+				Address: server.URL,
+				Auth: &framework.DatasourceAuthCredentials{
+					Basic: &framework.BasicAuthCredentials{
+						Username: testUsername,
+						Password: testPassword,
+					},
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+							List:       false,
+						},
+					},
+				},
+
+				PageSize: 1,
+				Cursor:   "400",
+			},
+			wantResponse: framework.Response{
+				Error: &framework.Error{
+					Message: "Datasource rejected request, returned status code: 400.",
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+				},
+			},
+		},
+		// This test case uses a random URL instead of the test server's URL, so we should expect an invalid cert.
+		// This test case also verifies that the "https://" prefix is added to the address if it's not present
+		// which is evident by the error message.
+		"failed_to_make_get_page_request_invalid_certs": {
+			ctx: context.Background(),
+			request: &framework.Request[scim.Config]{
+				Address: "example.com",
+				Auth: &framework.DatasourceAuthCredentials{
+					Basic: &framework.BasicAuthCredentials{
+						Username: testUsername,
+						Password: testPassword,
+					},
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+							List:       false,
+						},
+					},
+				},
+
+				PageSize: 1,
+			},
+			wantResponse: framework.Response{
+				Error: &framework.Error{
+					Message: `Failed to execute SCIM request: ` +
+						`Get "https://example.com/Users?startIndex=1&count=1&attributes=id": tls: failed to verify certificate: x509: ` +
+						fmt.Sprintf(
+							`certificate signed by unknown authority (presented certificate chain: "CN=example.com" expiring %s).`,
+							untrustedCert.cert.NotAfter.UTC().Format(time.RFC3339),
+						),
+					Code: api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+				},
+			},
+		},
+		"failed_to_make_get_page_request_invalid_host": {
+			ctx: context.Background(),
+			request: &framework.Request[scim.Config]{
+				// Deliberately add the extra "/".
+				// This test case also indirectly verifies that the "https://" prefix is NOT added
+				// if the prefix already exists.
+				Address: "https:///example.com",
+				Auth: &framework.DatasourceAuthCredentials{
+					Basic: &framework.BasicAuthCredentials{
+						Username: testUsername,
+						Password: testPassword,
+					},
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+							List:       false,
+						},
+					},
+				},
+
+				PageSize: 1,
+			},
+			wantResponse: framework.Response{
+				Error: &framework.Error{
+					Message: `Failed to execute SCIM request: ` +
+						`Get "https:///example.com/Users?startIndex=1&count=1&attributes=id": http: no Host in request URL.`,
+					Code: api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+				},
+			},
+		},
+		// The endpoint is setup to sleep for 5 seconds.
+		// The request timeout is configured for 1 seconds.
+		"user_request_timeout_with_common_config": {
+			ctx: context.Background(),
+			request: &framework.Request[scim.Config]{
+				Address: baseURL,
+				Auth: &framework.DatasourceAuthCredentials{
+					Basic: &framework.BasicAuthCredentials{
+						Username: testUsername,
+						Password: testPassword,
+					},
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+							List:       false,
+						},
+						{
+							ExternalId: "userName",
+							Type:       framework.AttributeTypeString,
+							List:       false,
+						},
+					},
+					ChildEntities: []*framework.EntityConfig{
+						{
+							ExternalId: "emails",
+							Attributes: []*framework.AttributeConfig{
+								{
+									ExternalId: "value",
+									Type:       framework.AttributeTypeString,
+								},
+								{
+									ExternalId: "type",
+									Type:       framework.AttributeTypeString,
+								},
+								{
+									ExternalId: "primary",
+									Type:       framework.AttributeTypeBool,
+								},
+							},
+						},
+					},
+				},
+				Config: &scim.Config{
+					CommonConfig: &config.CommonConfig{
+						RequestTimeoutSeconds: testutil.GenPtr(1),
+					},
+				},
+				PageSize: 1,
+				Cursor:   "408",
+			},
+			wantResponse: framework.Response{
+				Error: &framework.Error{
+					Message: "Failed to execute SCIM request: Get \"" + baseURL + "/Users?startIndex=408&count=1&attributes=id%2CuserName%2Cemails\": context deadline exceeded. Request exceeded configured timeout of 1 seconds. Please increase the request timeout.",
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+				},
+			},
+		},
+
+		// The endpoint is setup to sleep for 5 seconds.
+		// The request timeout is configured for 1 seconds.
+		"group_request_timeout_with_common_config": {
+			ctx: context.Background(),
+			request: &framework.Request[scim.Config]{
+				Address: baseURL,
+				Auth: &framework.DatasourceAuthCredentials{
+					Basic: &framework.BasicAuthCredentials{
+						Username: testUsername,
+						Password: testPassword,
+					},
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimGroup,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+							List:       false,
+						},
+						{
+							ExternalId: "displayName",
+							Type:       framework.AttributeTypeString,
+							List:       false,
+						},
+						{
+							ExternalId: "$.meta.created",
+							Type:       framework.AttributeTypeDateTime,
+							List:       false,
+						},
+					},
+					ChildEntities: []*framework.EntityConfig{
+						{
+							ExternalId: "members",
+							Attributes: []*framework.AttributeConfig{
+								{
+									ExternalId: "value",
+									Type:       framework.AttributeTypeString,
+								},
+								{
+									ExternalId: "type",
+									Type:       framework.AttributeTypeString,
+								},
+							},
+						},
+					},
+				},
+				Config: &scim.Config{
+					CommonConfig: &config.CommonConfig{
+						RequestTimeoutSeconds: testutil.GenPtr(1),
+					},
+				},
+				PageSize: 1,
+				Cursor:   "408",
+			},
+			wantResponse: framework.Response{
+				Error: &framework.Error{
+					Message: "Failed to execute SCIM request: Get \"" + baseURL + "/Groups?startIndex=408&count=1&attributes=id%2CdisplayName%2Cmeta.created%2Cmembers\": context deadline exceeded. Request exceeded configured timeout of 1 seconds. Please increase the request timeout.",
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+				},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tt.cursor != nil {
+				tt.request.Cursor = encodeTestCursor(tt.request, tt.cursor)
+			}
+
+			if tt.wantNextCursor != nil {
+				tt.wantResponse.Success.NextCursor = encodeTestCursor(tt.request, tt.wantNextCursor)
+			}
+
+			gotResponse := adapter.GetPage(tt.ctx, tt.request)
+
 			if !reflect.DeepEqual(gotResponse, tt.wantResponse) {
 				t.Errorf("gotResponse: %v, wantResponse: %v", gotResponse, tt.wantResponse)
 			}
 		})
 	}
 }
+
+func TestAdapterGetPageRetries(t *testing.T) {
+	tests := map[string]struct {
+		retry          *config.RetryConfig
+		wantResponse   framework.Response
+		wantNextCursor *scim.Cursor
+		wantAttempts   int32
+	}{
+		"retried_until_success": {
+			retry: &config.RetryConfig{
+				MaxAttempts:             3,
+				BaseBackoffMilliseconds: 1,
+				MaxBackoffMilliseconds:  5,
+			},
+			wantResponse: framework.Response{
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{"id": "2819c223-7f76-453a-919d-413861904646"},
+						{"id": "c75ad752-64ae-4823-840d-ffa80929976c"},
+					},
+				},
+			},
+			wantNextCursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   3,
+				TotalResults: 5,
+			},
+			wantAttempts: 3,
+		},
+		"retries_disabled": {
+			wantResponse: framework.Response{
+				Error: &framework.Error{
+					Message:    "Datasource is temporarily unavailable; try again later, returned status code: 503.",
+					Code:       api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_TEMPORARILY_UNAVAILABLE,
+					RetryAfter: testutil.GenPtr(time.Duration(0)),
+				},
+			},
+			wantAttempts: 1,
+		},
+		"invalid_retry_config": {
+			retry: &config.RetryConfig{
+				MaxAttempts: -1,
+			},
+			wantResponse: framework.Response{
+				Error: &framework.Error{
+					Message: "Invalid retry config: the maximum number of attempts must be at least 1, got -1.",
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+				},
+			},
+			wantAttempts: 0,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var attempts atomic.Int32
+
+			// The first 2 attempts to request the page fail.
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/"+scimUser && attempts.Add(1) <= 2 {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusServiceUnavailable)
+
+					return
+				}
+
+				TestServerHandler(w, r)
+			}))
+			defer server.Close()
+
+			adapter := scim.NewAdapter(&scim.Datasource{
+				Client: server.Client(),
+			})
+
+			request := &framework.Request[scim.Config]{
+				Address: server.URL,
+				Auth: &framework.DatasourceAuthCredentials{
+					Basic: &framework.BasicAuthCredentials{
+						Username: testUsername,
+						Password: testPassword,
+					},
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+						},
+					},
+				},
+				Config: &scim.Config{
+					CommonConfig: &config.CommonConfig{
+						Retry: tt.retry,
+					},
+				},
+				PageSize: 2,
+			}
+
+			if tt.wantNextCursor != nil {
+				tt.wantResponse.Success.NextCursor = encodeTestCursor(request, tt.wantNextCursor)
+			}
+
+			gotResponse := adapter.GetPage(context.Background(), request)
+
+			if !reflect.DeepEqual(gotResponse, tt.wantResponse) {
+				t.Errorf("gotResponse: %v, wantResponse: %v", gotResponse, tt.wantResponse)
+			}
+
+			if gotAttempts := attempts.Load(); gotAttempts != tt.wantAttempts {
+				t.Errorf("gotAttempts: %d, wantAttempts: %d", gotAttempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestAdapterGetPageLocalFilter(t *testing.T) {
+	tests := map[string]struct {
+		queryParams          scim.QueryParams
+		cursor               *scim.Cursor
+		wantResponse         framework.Response
+		wantNextCursor       *scim.Cursor
+		wantFilteredRequests int32
+		wantAttributes       string
+	}{
+		"local_filter_configured": {
+			queryParams: scim.QueryParams{
+				Filter:      `userName eq "bacong"`,
+				LocalFilter: true,
+			},
+			wantResponse: framework.Response{
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{"id": "c75ad752-64ae-4823-840d-ffa80929976c"},
+					},
+				},
+			},
+			wantNextCursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   3,
+				TotalResults: 5,
+				LocalFilter:  true,
+			},
+			wantFilteredRequests: 0,
+			wantAttributes:       "id,userName",
+		},
+		"filter_not_implemented": {
+			queryParams: scim.QueryParams{
+				Filter: `userName eq "bacong" or userName sw "AL"`,
+			},
+			wantResponse: framework.Response{
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{"id": "2819c223-7f76-453a-919d-413861904646"},
+						{"id": "c75ad752-64ae-4823-840d-ffa80929976c"},
+					},
+				},
+			},
+			wantNextCursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   3,
+				TotalResults: 5,
+				LocalFilter:  true,
+			},
+			wantFilteredRequests: 1,
+			wantAttributes:       "id,userName",
+		},
+		"local_filter_recorded_in_cursor": {
+			queryParams: scim.QueryParams{
+				Filter: `userName ew "ID"`,
+			},
+			cursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   3,
+				TotalResults: 5,
+				LocalFilter:  true,
+			},
+			wantResponse: framework.Response{
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{"id": "89fa657e-3ef5-49e3-bb34-b3255e04a8bb"},
+					},
+				},
+			},
+			wantNextCursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   5,
+				TotalResults: 5,
+				LocalFilter:  true,
+			},
+			wantFilteredRequests: 0,
+			wantAttributes:       "id,userName",
+		},
+		"no_matching_resources": {
+			queryParams: scim.QueryParams{
+				Filter:      `userName eq "Zoe"`,
+				LocalFilter: true,
+			},
+			wantResponse: framework.Response{
+				Success: &framework.Page{},
+			},
+			wantNextCursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   3,
+				TotalResults: 5,
+				LocalFilter:  true,
+			},
+			wantFilteredRequests: 0,
+			wantAttributes:       "id,userName",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var filteredRequests atomic.Int32
+
+			var gotAttributes atomic.Value
+
+			// The SCIM server does not support filtering.
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/"+scimUser {
+					if r.URL.Query().Has("filter") {
+						filteredRequests.Add(1)
+						w.WriteHeader(http.StatusNotImplemented)
+						w.Write([]byte(`{"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"], "status": "501"}`))
+
+						return
+					}
+
+					gotAttributes.Store(r.URL.Query().Get("attributes"))
+				}
+
+				TestServerHandler(w, r)
+			}))
+			defer server.Close()
+
+			adapter := scim.NewAdapter(&scim.Datasource{
+				Client: server.Client(),
+			})
+
+			request := &framework.Request[scim.Config]{
+				Address: server.URL,
+				Auth: &framework.DatasourceAuthCredentials{
+					Basic: &framework.BasicAuthCredentials{
+						Username: testUsername,
+						Password: testPassword,
+					},
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+						},
+					},
+				},
+				Config: &scim.Config{
+					QueryParams: map[string]scim.QueryParams{
+						scimUser: tt.queryParams,
+					},
+				},
+				PageSize: 2,
+			}
+
+			if tt.cursor != nil {
+				request.Cursor = encodeTestCursor(request, tt.cursor)
+			}
+
+			if tt.wantNextCursor != nil {
+				tt.wantResponse.Success.NextCursor = encodeTestCursor(request, tt.wantNextCursor)
+			}
+
+			gotResponse := adapter.GetPage(context.Background(), request)
+
+			if !reflect.DeepEqual(gotResponse, tt.wantResponse) {
+				t.Errorf("gotResponse: %v, wantResponse: %v", gotResponse, tt.wantResponse)
+			}
+
+			if gotFilteredRequests := filteredRequests.Load(); gotFilteredRequests != tt.wantFilteredRequests {
+				t.Errorf("gotFilteredRequests: %d, wantFilteredRequests: %d", gotFilteredRequests, tt.wantFilteredRequests)
+			}
+
+			if gotAttributes.Load() != tt.wantAttributes {
+				t.Errorf("gotAttributes: %v, wantAttributes: %s", gotAttributes.Load(), tt.wantAttributes)
+			}
+		})
+	}
+}
+
+func TestAdapterGetPageRateLimit(t *testing.T) {
+	server := httptest.NewTLSServer(TestServerHandler)
+	defer server.Close()
+
+	adapter := scim.NewAdapter(&scim.Datasource{
+		Client: server.Client(),
+	})
+
+	newRequest := func(password string) *framework.Request[scim.Config] {
+		return &framework.Request[scim.Config]{
+			Address: server.URL,
+			Auth: &framework.DatasourceAuthCredentials{
+				Basic: &framework.BasicAuthCredentials{
+					Username: testUsername,
+					Password: password,
+				},
+			},
+			Entity: framework.EntityConfig{
+				ExternalId: scimUser,
+				Attributes: []*framework.AttributeConfig{
+					{
+						ExternalId: "id",
+						Type:       framework.AttributeTypeString,
+					},
+				},
+			},
+			Config: &scim.Config{
+				CommonConfig: &config.CommonConfig{
+					RequestTimeoutSeconds: testutil.GenPtr(1),
+					// The burst allows the ServiceProviderConfig request and the first page request.
+					RateLimit: &config.RateLimitConfig{
+						RequestsPerSecond: 0.01,
+						Burst:             2,
+					},
+				},
+			},
+			PageSize: 2,
+		}
+	}
+
+	if gotResponse := adapter.GetPage(context.Background(), newRequest(testPassword)); gotResponse.Error != nil {
+		t.Fatalf("Unexpected error: %v", gotResponse.Error)
+	}
+
+	// The next request would only be allowed after 100 seconds, which exceeds the request timeout.
+	wantErr := &framework.Error{
+		Message: "Failed to execute SCIM request: waiting for the rate limit: context deadline exceeded. Request exceeded configured timeout of 1 seconds. Please increase the request timeout.",
+		Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+	}
+
+	if gotResponse := adapter.GetPage(context.Background(), newRequest(testPassword)); !reflect.DeepEqual(gotResponse.Error, wantErr) {
+		t.Errorf("gotErr: %v, wantErr: %v", gotResponse.Error, wantErr)
+	}
+
+	// Requests with other credentials are limited separately.
+	if gotResponse := adapter.GetPage(context.Background(), newRequest("otherpassword")); gotResponse.Error != nil {
+		t.Errorf("Unexpected error: %v", gotResponse.Error)
+	}
+}
+
+func TestAdapterGetPageMutualTLS(t *testing.T) {
+	ca := newTestCertificate(t, "Test CA", nil)
+	clientCert := newTestCertificate(t, "adapter", ca)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	server := httptest.NewUnstartedServer(TestServerHandler)
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+
+	server.StartTLS()
+	defer server.Close()
+
+	adapter := scim.NewAdapter(&scim.Datasource{
+		Client: server.Client(),
+	})
+
+	tests := map[string]struct {
+		tls      *config.TLSConfig
+		wantErr  *framework.Error
+		wantCode api_adapter_v1.ErrorCode
+	}{
+		"client_certificate": {
+			tls: &config.TLSConfig{
+				ClientCertificate: clientCert.certificatePEM(),
+				ClientKey:         clientCert.keyPEM(t),
+			},
+		},
+		"no_client_certificate": {
+			wantCode: api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+		},
+		"invalid_client_certificate": {
+			tls: &config.TLSConfig{
+				ClientCertificate: clientCert.certificatePEM(),
+				ClientKey:         ca.keyPEM(t),
+			},
+			wantErr: &framework.Error{
+				Message: "Invalid TLS config: invalid client certificate: tls: private key does not match public key.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			request := &framework.Request[scim.Config]{
+				Address: server.URL,
+				Auth: &framework.DatasourceAuthCredentials{
+					Basic: &framework.BasicAuthCredentials{
+						Username: testUsername,
+						Password: testPassword,
+					},
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+						},
+					},
+				},
+				Config: &scim.Config{
+					CommonConfig: &config.CommonConfig{
+						TLS: tt.tls,
+					},
+				},
+				PageSize: 2,
+			}
+
+			gotResponse := adapter.GetPage(context.Background(), request)
+
+			switch {
+			case tt.wantErr != nil:
+				if !reflect.DeepEqual(gotResponse.Error, tt.wantErr) {
+					t.Errorf("gotErr: %v, wantErr: %v", gotResponse.Error, tt.wantErr)
+				}
+			case tt.wantCode != api_adapter_v1.ErrorCode_ERROR_CODE_UNSPECIFIED:
+				// The handshake error depends on the TLS version.
+				if gotResponse.Error == nil || gotResponse.Error.Code != tt.wantCode {
+					t.Errorf("gotErr: %v, wantCode: %v", gotResponse.Error, tt.wantCode)
+				}
+			default:
+				if gotResponse.Error != nil {
+					t.Fatalf("Unexpected error: %v", gotResponse.Error)
+				}
+
+				if len(gotResponse.Success.Objects) != 2 {
+					t.Errorf("gotObjects: %v, wantObjects: 2", gotResponse.Success.Objects)
+				}
+			}
+		})
+	}
+}
+
+func TestAdapterGetPageTLSVerification(t *testing.T) {
+	ca := newTestCertificate(t, "Internal Root CA", nil)
+	serverCert := newTestCertificate(t, "scim.internal", ca)
+	otherCA := newTestCertificate(t, "Other CA", nil)
+
+	server := httptest.NewUnstartedServer(TestServerHandler)
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{
+			{
+				Certificate: [][]byte{serverCert.cert.Raw, ca.cert.Raw},
+				PrivateKey:  serverCert.key,
+			},
+		},
+	}
+
+	server.StartTLS()
+	defer server.Close()
+
+	// The datasource's client only trusts the default trust store.
+	adapter := scim.NewAdapter(&scim.Datasource{
+		Client: &http.Client{},
+	})
+
+	pin := func(cert *testCertificate) string {
+		hash := sha256.Sum256(cert.cert.RawSubjectPublicKeyInfo)
+
+		return base64.StdEncoding.EncodeToString(hash[:])
+	}
+
+	presentedChain := fmt.Sprintf(
+		`(presented certificate chain: "CN=scim.internal" expiring %s, "CN=Internal Root CA" expiring %s)`,
+		serverCert.cert.NotAfter.UTC().Format(time.RFC3339), ca.cert.NotAfter.UTC().Format(time.RFC3339),
+	)
+
+	tests := map[string]struct {
+		tls     *config.TLSConfig
+		wantErr *framework.Error
+	}{
+		"untrusted_ca": {
+			wantErr: &framework.Error{
+				Message: fmt.Sprintf(
+					`Failed to execute SCIM request: Get "%s/Users?startIndex=1&count=2&attributes=id": tls: failed to verify certificate: x509: certificate signed by unknown authority %s.`,
+					server.URL, presentedChain,
+				),
+				Code: api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+		},
+		"additional_ca": {
+			tls: &config.TLSConfig{
+				AdditionalCACertificates: ca.certificatePEM(),
+			},
+		},
+		"pinned_server_public_key": {
+			tls: &config.TLSConfig{
+				AdditionalCACertificates: ca.certificatePEM(),
+				PinnedPublicKeys:         []string{pin(otherCA), pin(serverCert)},
+			},
+		},
+		"pinned_ca_public_key": {
+			tls: &config.TLSConfig{
+				AdditionalCACertificates: ca.certificatePEM(),
+				PinnedPublicKeys:         []string{pin(ca)},
+			},
+		},
+		"pinned_public_key_not_presented": {
+			tls: &config.TLSConfig{
+				AdditionalCACertificates: ca.certificatePEM(),
+				PinnedPublicKeys:         []string{pin(otherCA)},
+			},
+			wantErr: &framework.Error{
+				Message: fmt.Sprintf(
+					`Failed to execute SCIM request: Get "%s/Users?startIndex=1&count=2&attributes=id": tls: the certificate chain does not contain any pinned public key %s.`,
+					server.URL, presentedChain,
+				),
+				Code: api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+		},
+		"invalid_pinned_public_key": {
+			tls: &config.TLSConfig{
+				AdditionalCACertificates: ca.certificatePEM(),
+				PinnedPublicKeys:         []string{"c2hhMjU2"},
+			},
+			wantErr: &framework.Error{
+				Message: `Invalid TLS config: the pinned public key "c2hhMjU2" is not a base64-encoded SHA-256 hash.`,
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			},
+		},
+		"invalid_additional_ca": {
+			tls: &config.TLSConfig{
+				AdditionalCACertificates: "ca",
+			},
+			wantErr: &framework.Error{
+				Message: "Invalid TLS config: the additional CA certificates do not contain any PEM-encoded certificate.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			request := &framework.Request[scim.Config]{
+				Address: server.URL,
+				Auth: &framework.DatasourceAuthCredentials{
+					Basic: &framework.BasicAuthCredentials{
+						Username: testUsername,
+						Password: testPassword,
+					},
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+						},
+					},
+				},
+				Config: &scim.Config{
+					CommonConfig: &config.CommonConfig{
+						TLS: tt.tls,
+					},
+				},
+				PageSize: 2,
+			}
+
+			gotResponse := adapter.GetPage(context.Background(), request)
+
+			if tt.wantErr != nil {
+				if !reflect.DeepEqual(gotResponse.Error, tt.wantErr) {
+					t.Errorf("gotErr: %v, wantErr: %v", gotResponse.Error, tt.wantErr)
+				}
+
+				return
+			}
+
+			if gotResponse.Error != nil {
+				t.Fatalf("Unexpected error: %v", gotResponse.Error)
+			}
+
+			if len(gotResponse.Success.Objects) != 2 {
+				t.Errorf("gotObjects: %v, wantObjects: 2", gotResponse.Success.Objects)
+			}
+		})
+	}
+}
+
+func TestAdapterGetPageTLSTokenEndpoint(t *testing.T) {
+	ca := newTestCertificate(t, "Internal Root CA", nil)
+	serverCert := newTestCertificate(t, "scim.internal", ca)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		TestServerHandler(w, r)
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{
+			{
+				Certificate: [][]byte{serverCert.cert.Raw, ca.cert.Raw},
+				PrivateKey:  serverCert.key,
+			},
+		},
+	}
+
+	server.StartTLS()
+	defer server.Close()
+
+	// The token endpoint is served by an identity provider with a certificate trusted by the default trust store.
+	tokenServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"access_token": "token", "token_type": "bearer", "expires_in": 3600}`))
+	}))
+	defer tokenServer.Close()
+
+	serverPin := sha256.Sum256(serverCert.cert.RawSubjectPublicKeyInfo)
+
+	tests := map[string]struct {
+		tls *config.TLSConfig
+	}{
+		"ca_certificates_only_apply_to_datasource": {
+			tls: &config.TLSConfig{
+				CACertificates: ca.certificatePEM(),
+			},
+		},
+		"pinned_public_keys_only_apply_to_datasource": {
+			tls: &config.TLSConfig{
+				AdditionalCACertificates: ca.certificatePEM(),
+				PinnedPublicKeys:         []string{base64.StdEncoding.EncodeToString(serverPin[:])},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Each case has its own adapter, so that access tokens are not cached across cases.
+			adapter := scim.NewAdapter(&scim.Datasource{
+				Client: tokenServer.Client(),
+			})
+
+			gotResponse := adapter.GetPage(context.Background(), &framework.Request[scim.Config]{
+				Address: server.URL,
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+						},
+					},
+				},
+				Config: &scim.Config{
+					CommonConfig: &config.CommonConfig{
+						TLS: tt.tls,
+					},
+					OAuth2: &auth.OAuth2Config{
+						TokenURL:     tokenServer.URL + "/token",
+						ClientID:     "client",
+						ClientSecret: "secret",
+					},
+				},
+				PageSize: 2,
+			})
+
+			if gotResponse.Error != nil {
+				t.Fatalf("Unexpected error: %v", gotResponse.Error)
+			}
+
+			if len(gotResponse.Success.Objects) != 2 {
+				t.Errorf("gotObjects: %v, wantObjects: 2", gotResponse.Success.Objects)
+			}
+		})
+	}
+}
+
+func TestAdapterGetPageOAuth2(t *testing.T) {
+	tests := map[string]struct {
+		auth             *framework.DatasourceAuthCredentials
+		oauth2           *auth.OAuth2Config
+		tokenStatusCode  int
+		revokedTokens    int
+		wantTokenForm    string
+		wantTokenHeader  string
+		wantTokenFetches int32
+		wantErr          *framework.Error
+	}{
+		"client_credentials_from_basic_auth": {
+			auth: &framework.DatasourceAuthCredentials{
+				Basic: &framework.BasicAuthCredentials{
+					Username: "client",
+					Password: "secret",
+				},
+			},
+			oauth2: &auth.OAuth2Config{
+				Scopes: []string{"scim.read"},
+			},
+			tokenStatusCode:  http.StatusOK,
+			wantTokenForm:    "grant_type=client_credentials&scope=scim.read",
+			wantTokenHeader:  auth.BasicAuthHeader("client", "secret"),
+			wantTokenFetches: 1,
+		},
+		"client_credentials_in_config": {
+			oauth2: &auth.OAuth2Config{
+				ClientID:         "client",
+				ClientSecret:     "secret",
+				ClientAuthMethod: auth.ClientSecretPost,
+			},
+			tokenStatusCode:  http.StatusOK,
+			wantTokenForm:    "client_id=client&client_secret=secret&grant_type=client_credentials",
+			wantTokenFetches: 1,
+		},
+		"revoked_token_refreshed": {
+			oauth2: &auth.OAuth2Config{
+				ClientID:     "client",
+				ClientSecret: "secret",
+			},
+			tokenStatusCode:  http.StatusOK,
+			revokedTokens:    1,
+			wantTokenForm:    "grant_type=client_credentials",
+			wantTokenHeader:  auth.BasicAuthHeader("client", "secret"),
+			wantTokenFetches: 2,
+		},
+		"refreshed_token_rejected": {
+			oauth2: &auth.OAuth2Config{
+				ClientID:     "client",
+				ClientSecret: "secret",
+			},
+			tokenStatusCode: http.StatusOK,
+			revokedTokens:   100,
+			wantTokenForm:   "grant_type=client_credentials",
+			wantTokenHeader: auth.BasicAuthHeader("client", "secret"),
+			// The ServiceProviderConfig and page requests are each sent again once with a new token.
+			wantTokenFetches: 3,
+			wantErr: &framework.Error{
+				Message: "Failed to authenticate with datasource. Check datasource configuration details and try again.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_AUTHENTICATION_FAILED,
+			},
+		},
+		"invalid_client": {
+			oauth2: &auth.OAuth2Config{
+				ClientID:     "client",
+				ClientSecret: "wrong",
+			},
+			tokenStatusCode: http.StatusUnauthorized,
+			wantTokenForm:   "grant_type=client_credentials",
+			wantTokenHeader: auth.BasicAuthHeader("client", "wrong"),
+			// The token is requested for the ServiceProviderConfig request, whose errors are ignored, then for
+			// the page request.
+			wantTokenFetches: 2,
+			wantErr: &framework.Error{
+				Message: "Failed to execute SCIM request: failed to request an access token: token endpoint returned status code 401: invalid_client.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_AUTHENTICATION_FAILED,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var tokenFetches atomic.Int32
+
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/token" {
+					body, _ := io.ReadAll(r.Body)
+
+					if string(body) != tt.wantTokenForm {
+						t.Errorf("gotTokenForm: %s, wantTokenForm: %s", body, tt.wantTokenForm)
+					}
+
+					if gotTokenHeader := r.Header.Get("Authorization"); gotTokenHeader != tt.wantTokenHeader {
+						t.Errorf("gotTokenHeader: %s, wantTokenHeader: %s", gotTokenHeader, tt.wantTokenHeader)
+					}
+
+					fetch := tokenFetches.Add(1)
+
+					w.WriteHeader(tt.tokenStatusCode)
+
+					if tt.tokenStatusCode == http.StatusOK {
+						fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": 3600}`, fetch)
+					} else {
+						w.Write([]byte(`{"error": "invalid_client"}`))
+					}
+
+					return
+				}
+
+				// The first tokens are revoked before their expiry.
+				if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token-%d", tt.revokedTokens+1) {
+					w.WriteHeader(http.StatusUnauthorized)
+
+					return
+				}
+
+				TestServerHandler(w, r)
+			}))
+			defer server.Close()
+
+			adapter := scim.NewAdapter(&scim.Datasource{
+				Client: server.Client(),
+			})
+
+			tt.oauth2.TokenURL = server.URL + "/token"
+
+			request := &framework.Request[scim.Config]{
+				Address: server.URL,
+				Auth:    tt.auth,
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+						},
+					},
+				},
+				Config: &scim.Config{
+					OAuth2: tt.oauth2,
+				},
+				PageSize: 2,
+			}
+
+			// The access token is cached across pages.
+			for range 2 {
+				gotResponse := adapter.GetPage(context.Background(), request)
+
+				if !reflect.DeepEqual(gotResponse.Error, tt.wantErr) {
+					t.Fatalf("gotErr: %v, wantErr: %v", gotResponse.Error, tt.wantErr)
+				}
+
+				if tt.wantErr != nil {
+					break
+				}
+			}
+
+			if gotTokenFetches := tokenFetches.Load(); gotTokenFetches != tt.wantTokenFetches {
+				t.Errorf("gotTokenFetches: %d, wantTokenFetches: %d", gotTokenFetches, tt.wantTokenFetches)
+			}
+		})
+	}
+}
+
+func TestAdapterGetPageOAuth2ConcurrentRequests(t *testing.T) {
+	var tokenFetches atomic.Int32
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			fetch := tokenFetches.Add(1)
+
+			// Concurrent requests for the same datasource wait for the token being requested.
+			time.Sleep(50 * time.Millisecond)
+
+			fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": 3600}`, fetch)
+
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		TestServerHandler(w, r)
+	}))
+	defer server.Close()
+
+	adapter := scim.NewAdapter(&scim.Datasource{
+		Client: server.Client(),
+	})
+
+	newRequest := func() *framework.Request[scim.Config] {
+		return &framework.Request[scim.Config]{
+			Address: server.URL,
+			Entity: framework.EntityConfig{
+				ExternalId: scimUser,
+				Attributes: []*framework.AttributeConfig{
+					{
+						ExternalId: "id",
+						Type:       framework.AttributeTypeString,
+					},
+				},
+			},
+			Config: &scim.Config{
+				OAuth2: &auth.OAuth2Config{
+					TokenURL:     server.URL + "/token",
+					ClientID:     "client",
+					ClientSecret: "secret",
+				},
+			},
+			PageSize: 2,
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if gotResponse := adapter.GetPage(context.Background(), newRequest()); gotResponse.Error != nil {
+				t.Errorf("Unexpected error: %v", gotResponse.Error)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if gotTokenFetches := tokenFetches.Load(); gotTokenFetches != 1 {
+		t.Errorf("gotTokenFetches: %d, wantTokenFetches: 1", gotTokenFetches)
+	}
+}
@@ -0,0 +1,354 @@
+// Copyright 2025 SGNL.ai, Inc.
+package scim
+
+import (
+	"strings"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+)
+
+// AttributePaths returns the SCIM attribute paths to request in the "attributes" query parameter
+// to return all the attributes and child entities of the provided entity.
+// https://datatracker.ietf.org/doc/html/rfc7644#section-3.9
+//
+// Attribute external IDs are converted into SCIM attribute paths as follows:
+//   - "userName" is requested as "userName"
+//   - "$.name.givenName" is requested as "name.givenName"
+//   - "$.emails[?(@.primary==true)].value" is requested as "emails"
+//   - `$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].manager.value` is requested as
+//     "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value"
+//
+// Child entities are requested as a whole, e.g. a child entity with the external ID "emails" is requested as
+// "emails". The "id" attribute is always requested, as well as any additional attribute paths provided.
+//
+// Returns nil if any external ID cannot be converted into a SCIM attribute path, e.g. "$..value",
+// in which case all attributes must be requested.
+func AttributePaths(entity *framework.EntityConfig, additionalPaths ...string) []string {
+	paths := make([]string, 0, len(entity.Attributes)+len(entity.ChildEntities)+len(additionalPaths)+1)
+	seen := make(map[string]struct{}, cap(paths))
+
+	add := func(path string) {
+		key := strings.ToLower(path)
+		if _, found := seen[key]; found {
+			return
+		}
+
+		seen[key] = struct{}{}
+
+		paths = append(paths, path)
+	}
+
+	add("id")
+
+	for _, attribute := range entity.Attributes {
+		path, ok := attributePath(attribute.ExternalId)
+		if !ok {
+			return nil
+		}
+
+		add(path)
+	}
+
+	for _, childEntity := range entity.ChildEntities {
+		path, ok := attributePath(childEntity.ExternalId)
+		if !ok {
+			return nil
+		}
+
+		add(path)
+	}
+
+	for _, path := range additionalPaths {
+		add(path)
+	}
+
+	return paths
+}
+
+// attributePath converts an attribute external ID into a SCIM attribute path.
+// Returns false if the external ID cannot be converted.
+func attributePath(externalID string) (string, bool) {
+	if !strings.HasPrefix(externalID, "$") {
+		return externalID, externalID != ""
+	}
+
+	names := jsonPathNames(externalID)
+	if len(names) == 0 {
+		return "", false
+	}
+
+	// A schema URN is the prefix of the attribute path of an extension attribute.
+	var urn string
+	if strings.Contains(names[0], ":") {
+		urn = names[0]
+		names = names[1:]
+
+		if len(names) == 0 {
+			return urn, true
+		}
+	}
+
+	// SCIM attribute paths only support one level of sub-attributes.
+	path := names[0]
+	if len(names) > 1 {
+		path += "." + names[1]
+	}
+
+	if urn != "" {
+		path = urn + ":" + path
+	}
+
+	return path, true
+}
+
+// attributeNames returns the member names of an attribute external ID, i.e. the attribute name itself, or the
+// member names of a JSONPath, e.g. ["name", "givenName"] for "$.name.givenName".
+// Returns false if the JSONPath contains segments which are not member names, e.g. filters.
+func attributeNames(externalID string) ([]string, bool) {
+	if !strings.HasPrefix(externalID, "$") {
+		return []string{externalID}, externalID != ""
+	}
+
+	names, complete := parseJSONPathNames(externalID, false)
+
+	return names, complete && len(names) > 0
+}
+
+// jsonPathNames returns the leading member names of a JSONPath, up to the first segment which is not a
+// member name, e.g. a filter, an index, a wildcard or a recursive descent.
+// For example, "$.emails[?(@.primary==true)].value" returns ["emails"].
+func jsonPathNames(jsonPath string) []string {
+	names, _ := parseJSONPathNames(jsonPath, false)
+
+	return names
+}
+
+// parseJSONPathNames returns the leading member names of a JSONPath, and whether the JSONPath only contains
+// member names. If skipSelectors is true, bracketed segments which are not member names, e.g. filters, indexes
+// and wildcards selecting elements of multi-valued attributes, are skipped instead of ending the member names,
+// e.g. ["emails", "value"] is returned for "$.emails[?(@.primary==true)].value".
+func parseJSONPathNames(jsonPath string, skipSelectors bool) (names []string, complete bool) {
+	rest := strings.TrimPrefix(jsonPath, "$")
+
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			return names, false
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+
+			name := rest[:end]
+			if name == "" || name == "*" {
+				return names, false
+			}
+
+			names = append(names, name)
+			rest = rest[end:]
+		case strings.HasPrefix(rest, "["):
+			end := jsonPathBracketEnd(rest)
+			if end == -1 {
+				return names, false
+			}
+
+			// A member name is a single quoted string, e.g. ["name"] or ['name'].
+			segment := rest[1:end]
+			if len(segment) >= 2 && (segment[0] == '"' || segment[0] == '\'') &&
+				strings.IndexByte(segment[1:], segment[0]) == len(segment)-2 {
+				names = append(names, segment[1:len(segment)-1])
+			} else if !skipSelectors {
+				return names, false
+			}
+
+			rest = rest[end+1:]
+		default:
+			return names, false
+		}
+	}
+
+	return names, true
+}
+
+// jsonPathBracketEnd returns the index of the bracket closing the bracket at the start of a JSONPath segment,
+// ignoring brackets in quoted strings and nested brackets, or -1 if it is not closed.
+func jsonPathBracketEnd(segment string) int {
+	depth := 0
+
+	var quote byte
+
+	for i := 0; i < len(segment); i++ {
+		switch c := segment[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// NormalizeAttributeNames renames the members of the objects whose names only differ in case from the names
+// of the entity's attributes and child entities, and of any additional attribute paths provided, as SCIM
+// attribute names are case-insensitive while external IDs are matched case-sensitively.
+// https://datatracker.ietf.org/doc/html/rfc7643#section-2.1
+//
+// For example, for an entity with the `userName`, `$.name.givenName` and
+// `$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].employeeNumber` attributes,
+// the members `username`, `Name.GivenName` and `urn:ietf:params:scim:schemas:extension:enterprise:2.0:user`
+// are renamed. The members of multi-valued complex attributes, e.g. `emails`, are renamed in each element.
+// Members of the JSONPath filters of external IDs, e.g. `primary` in "$.emails[?(@.primary==true)].value",
+// are not renamed.
+//
+// A member is not renamed if the object also has a member with the name of the attribute. Additional paths
+// are SCIM attribute paths, e.g. "meta.lastModified". The objects are modified in place.
+func NormalizeAttributeNames(entity *framework.EntityConfig, objects []map[string]any, additionalPaths ...string) {
+	index := newAttributeNameIndex()
+	index.addEntity(entity)
+
+	for _, path := range additionalPaths {
+		index.add(strings.Split(path, "."))
+	}
+
+	for _, object := range objects {
+		index.normalize(object)
+	}
+}
+
+// attributeNameIndex is a tree of the names of the attributes of an entity, keyed by name and by lowercase
+// name, so that the members of large pages are matched with one lookup in most cases.
+type attributeNameIndex struct {
+	// children are the sub-attributes, keyed by name.
+	children map[string]*attributeNameIndex
+
+	// folded are the sub-attributes, keyed by lowercase name.
+	folded map[string]*attributeNameIndex
+
+	// name is the name of the attribute, as in the entity.
+	name string
+}
+
+func newAttributeNameIndex() *attributeNameIndex {
+	return &attributeNameIndex{
+		children: make(map[string]*attributeNameIndex),
+		folded:   make(map[string]*attributeNameIndex),
+	}
+}
+
+// addEntity adds the attributes and child entities of an entity to the index.
+func (i *attributeNameIndex) addEntity(entity *framework.EntityConfig) {
+	for _, attribute := range entity.Attributes {
+		i.add(externalIDNames(attribute.ExternalId))
+	}
+
+	for _, childEntity := range entity.ChildEntities {
+		if child := i.add(externalIDNames(childEntity.ExternalId)); child != nil {
+			child.addEntity(childEntity)
+		}
+	}
+}
+
+// add adds a path of attribute names to the index, and returns the index of the last attribute, or nil if the
+// path is empty. The first casing of an attribute name is kept.
+func (i *attributeNameIndex) add(names []string) *attributeNameIndex {
+	if len(names) == 0 {
+		return nil
+	}
+
+	key := strings.ToLower(names[0])
+
+	child, found := i.folded[key]
+	if !found {
+		child = newAttributeNameIndex()
+		child.name = names[0]
+
+		i.children[names[0]] = child
+		i.folded[key] = child
+	}
+
+	if len(names) == 1 {
+		return child
+	}
+
+	return child.add(names[1:])
+}
+
+// normalize renames the members of an object, and of its complex values, to the names of the index.
+func (i *attributeNameIndex) normalize(object map[string]any) {
+	if len(i.children) == 0 {
+		return
+	}
+
+	var renamed map[string]string
+
+	for name, value := range object {
+		child, found := i.children[name]
+		if !found {
+			if child, found = i.folded[strings.ToLower(name)]; !found {
+				continue
+			}
+
+			if _, exists := object[child.name]; !exists {
+				if renamed == nil {
+					renamed = make(map[string]string)
+				}
+
+				renamed[name] = child.name
+			}
+		}
+
+		child.normalizeValue(value)
+	}
+
+	// Members are renamed after iterating, as members added during iteration may or may not be visited.
+	for name, newName := range renamed {
+		object[newName] = object[name]
+		delete(object, name)
+	}
+}
+
+// normalizeValue renames the members of a complex value, or of each complex value of a multi-valued attribute.
+func (i *attributeNameIndex) normalizeValue(value any) {
+	switch value := value.(type) {
+	case map[string]any:
+		i.normalize(value)
+	case []any:
+		for _, element := range value {
+			if element, ok := element.(map[string]any); ok {
+				i.normalize(element)
+			}
+		}
+	}
+}
+
+// externalIDNames returns the attribute names of an attribute external ID, i.e. the attribute name itself,
+// or the member names of a JSONPath, skipping filters, indexes and wildcards which select elements of
+// multi-valued attributes. For example, "$.emails[?(@.primary==true)].value" returns ["emails", "value"].
+// The names following a recursive descent are not returned, as their depth is unknown.
+func externalIDNames(externalID string) []string {
+	if !strings.HasPrefix(externalID, "$") {
+		if externalID == "" {
+			return nil
+		}
+
+		return []string{externalID}
+	}
+
+	names, _ := parseJSONPathNames(externalID, true)
+
+	return names
+}
@@ -0,0 +1,368 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// nolint: lll
+package scim_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	"github.com/sgnl-ai/sample-adapter/pkg/scim"
+)
+
+func TestAttributePaths(t *testing.T) {
+	tests := map[string]struct {
+		entity          *framework.EntityConfig
+		additionalPaths []string
+		wantPaths       []string
+	}{
+		"simple_attributes": {
+			entity: &framework.EntityConfig{
+				Attributes: []*framework.AttributeConfig{
+					{ExternalId: "id"},
+					{ExternalId: "userName"},
+					{ExternalId: "active"},
+				},
+			},
+			wantPaths: []string{"id", "userName", "active"},
+		},
+		"json_path_attributes": {
+			entity: &framework.EntityConfig{
+				Attributes: []*framework.AttributeConfig{
+					{ExternalId: "$.name.givenName"},
+					{ExternalId: "$.meta.created"},
+					{ExternalId: "$.emails[?(@.primary==true)].value"},
+					{ExternalId: "$['name']['familyName']"},
+					{ExternalId: "$.addresses[0].locality"},
+				},
+			},
+			wantPaths: []string{"id", "name.givenName", "meta.created", "emails", "name.familyName", "addresses"},
+		},
+		"extension_attributes": {
+			entity: &framework.EntityConfig{
+				Attributes: []*framework.AttributeConfig{
+					{ExternalId: `$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].employeeNumber`},
+					{ExternalId: `$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].manager.value`},
+					{ExternalId: `$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"]`},
+				},
+			},
+			wantPaths: []string{
+				"id",
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber",
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value",
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+			},
+		},
+		"child_entities": {
+			entity: &framework.EntityConfig{
+				Attributes: []*framework.AttributeConfig{
+					{ExternalId: "id"},
+				},
+				ChildEntities: []*framework.EntityConfig{
+					{
+						ExternalId: "emails",
+						Attributes: []*framework.AttributeConfig{
+							{ExternalId: "value"},
+						},
+					},
+					{
+						ExternalId: `$.phoneNumbers[?(@.type=="work")]`,
+					},
+				},
+			},
+			wantPaths: []string{"id", "emails", "phoneNumbers"},
+		},
+		"duplicate_paths": {
+			entity: &framework.EntityConfig{
+				Attributes: []*framework.AttributeConfig{
+					{ExternalId: "userName"},
+					{ExternalId: "$.username"},
+				},
+			},
+			additionalPaths: []string{"meta.lastModified", "userName"},
+			wantPaths:       []string{"id", "userName", "meta.lastModified"},
+		},
+		"recursive_descent_requests_all_attributes": {
+			entity: &framework.EntityConfig{
+				Attributes: []*framework.AttributeConfig{
+					{ExternalId: "id"},
+					{ExternalId: "$..value"},
+				},
+			},
+			wantPaths: nil,
+		},
+		"wildcard_requests_all_attributes": {
+			entity: &framework.EntityConfig{
+				ChildEntities: []*framework.EntityConfig{
+					{ExternalId: "$[*]"},
+				},
+			},
+			wantPaths: nil,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotPaths := scim.AttributePaths(tt.entity, tt.additionalPaths...)
+
+			if !reflect.DeepEqual(gotPaths, tt.wantPaths) {
+				t.Errorf("gotPaths: %v, wantPaths: %v", gotPaths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestNormalizeAttributeNames(t *testing.T) {
+	entity := &framework.EntityConfig{
+		ExternalId: scimUser,
+		Attributes: []*framework.AttributeConfig{
+			{ExternalId: "userName"},
+			{ExternalId: "$.name.givenName"},
+			{ExternalId: `$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].manager.value`},
+			{ExternalId: "$.phoneNumbers[?(@.type==\"work\")].value"},
+		},
+		ChildEntities: []*framework.EntityConfig{
+			{
+				ExternalId: "emails",
+				Attributes: []*framework.AttributeConfig{
+					{ExternalId: "value"},
+					{ExternalId: "primary"},
+				},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		objects         []map[string]any
+		additionalPaths []string
+		wantObjects     []map[string]any
+	}{
+		"matching_names": {
+			objects: []map[string]any{
+				{"id": "1", "userName": "bjensen", "name": map[string]any{"givenName": "Barbara"}},
+			},
+			wantObjects: []map[string]any{
+				{"id": "1", "userName": "bjensen", "name": map[string]any{"givenName": "Barbara"}},
+			},
+		},
+		"top_level_and_nested_names": {
+			objects: []map[string]any{
+				{"USERNAME": "bjensen", "Name": map[string]any{"GivenName": "Barbara", "familyname": "Jensen"}},
+				{"username": "jsmith"},
+			},
+			wantObjects: []map[string]any{
+				{"userName": "bjensen", "name": map[string]any{"givenName": "Barbara", "familyname": "Jensen"}},
+				{"userName": "jsmith"},
+			},
+		},
+		"extension_names": {
+			objects: []map[string]any{
+				{"urn:ietf:params:scim:schemas:extension:enterprise:2.0:user": map[string]any{"Manager": map[string]any{"Value": "26118915-6090-4610-87e4-49d8ca9f808d"}}},
+			},
+			wantObjects: []map[string]any{
+				{"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": map[string]any{"manager": map[string]any{"value": "26118915-6090-4610-87e4-49d8ca9f808d"}}},
+			},
+		},
+		"multi_valued_names": {
+			objects: []map[string]any{
+				{
+					"Emails":       []any{map[string]any{"Value": "bjensen@example.com", "PRIMARY": true}, "invalid"},
+					"phonenumbers": []any{map[string]any{"Value": "555-555-5555", "Type": "work"}},
+				},
+			},
+			wantObjects: []map[string]any{
+				{
+					"emails":       []any{map[string]any{"value": "bjensen@example.com", "primary": true}, "invalid"},
+					"phoneNumbers": []any{map[string]any{"value": "555-555-5555", "Type": "work"}},
+				},
+			},
+		},
+		"existing_name_not_overwritten": {
+			objects: []map[string]any{
+				{"userName": "bjensen", "username": "babs"},
+			},
+			wantObjects: []map[string]any{
+				{"userName": "bjensen", "username": "babs"},
+			},
+		},
+		"additional_paths": {
+			objects: []map[string]any{
+				{"ID": "1", "Meta": map[string]any{"LastModified": "2025-06-01T00:00:00Z"}},
+			},
+			additionalPaths: []string{"id", "meta.lastModified"},
+			wantObjects: []map[string]any{
+				{"id": "1", "meta": map[string]any{"lastModified": "2025-06-01T00:00:00Z"}},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			scim.NormalizeAttributeNames(entity, tt.objects, tt.additionalPaths...)
+
+			if !reflect.DeepEqual(tt.objects, tt.wantObjects) {
+				t.Errorf("gotObjects: %v, wantObjects: %v", tt.objects, tt.wantObjects)
+			}
+		})
+	}
+}
+
+func TestAdapterGetPageAttributesQueryParameter(t *testing.T) {
+	tests := map[string]struct {
+		config         *scim.Config
+		wantRequestURI string
+	}{
+		"attributes_derived_from_entity": {
+			config:         nil,
+			wantRequestURI: "/Users?startIndex=1&count=2&attributes=id%2CuserName%2Curn%3Aietf%3Aparams%3Ascim%3Aschemas%3Aextension%3Aenterprise%3A2.0%3AUser%3AemployeeNumber%2Cemails",
+		},
+		"request_all_attributes": {
+			config: &scim.Config{
+				RequestAllAttributes: true,
+			},
+			wantRequestURI: "/Users?startIndex=1&count=2",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var gotRequestURI string
+
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/Users" {
+					gotRequestURI = r.URL.RequestURI()
+				}
+
+				TestServerHandler(w, r)
+			}))
+			defer server.Close()
+
+			adapter := scim.NewAdapter(&scim.Datasource{
+				Client: server.Client(),
+			})
+
+			gotResponse := adapter.GetPage(context.Background(), &framework.Request[scim.Config]{
+				Address: server.URL,
+				Auth: &framework.DatasourceAuthCredentials{
+					HTTPAuthorization: "Bearer token",
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "userName",
+							Type:       framework.AttributeTypeString,
+						},
+						{
+							ExternalId: `$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].employeeNumber`,
+							Type:       framework.AttributeTypeString,
+						},
+					},
+					ChildEntities: []*framework.EntityConfig{
+						{
+							ExternalId: "emails",
+							Attributes: []*framework.AttributeConfig{
+								{
+									ExternalId: "value",
+									Type:       framework.AttributeTypeString,
+								},
+							},
+						},
+					},
+				},
+				Config:   tt.config,
+				PageSize: 2,
+			})
+
+			if gotResponse.Error != nil {
+				t.Fatalf("Unexpected error: %v", gotResponse.Error)
+			}
+
+			if gotRequestURI != tt.wantRequestURI {
+				t.Errorf("gotRequestURI: %v, wantRequestURI: %v", gotRequestURI, tt.wantRequestURI)
+			}
+		})
+	}
+}
+
+func TestAdapterGetPageCaseInsensitiveAttributeNames(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"totalResults": 1,
+			"Resources": [
+				{
+					"ID": "2819c223-7f76-453a-919d-413861904646",
+					"username": "bjensen",
+					"Emails": [{"Value": "bjensen@example.com"}],
+					"urn:ietf:params:scim:schemas:extension:enterprise:2.0:user": {"EmployeeNumber": "701984"}
+				}
+			]
+		}`))
+	}))
+	defer server.Close()
+
+	adapter := scim.NewAdapter(&scim.Datasource{
+		Client: server.Client(),
+	})
+
+	gotResponse := adapter.GetPage(context.Background(), &framework.Request[scim.Config]{
+		Address: server.URL,
+		Auth: &framework.DatasourceAuthCredentials{
+			HTTPAuthorization: "Bearer token",
+		},
+		Entity: framework.EntityConfig{
+			ExternalId: scimUser,
+			Attributes: []*framework.AttributeConfig{
+				{
+					ExternalId: "id",
+					Type:       framework.AttributeTypeString,
+					UniqueId:   true,
+				},
+				{
+					ExternalId: "userName",
+					Type:       framework.AttributeTypeString,
+				},
+				{
+					ExternalId: `$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].employeeNumber`,
+					Type:       framework.AttributeTypeString,
+				},
+			},
+			ChildEntities: []*framework.EntityConfig{
+				{
+					ExternalId: "emails",
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "value",
+							Type:       framework.AttributeTypeString,
+						},
+					},
+				},
+			},
+		},
+		PageSize: 1,
+	})
+
+	wantResponse := framework.Response{
+		Success: &framework.Page{
+			Objects: []framework.Object{
+				{
+					"id":       "2819c223-7f76-453a-919d-413861904646",
+					"userName": "bjensen",
+					`$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].employeeNumber`: "701984",
+					"emails": []framework.Object{
+						{"value": "bjensen@example.com"},
+					},
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(gotResponse, wantResponse) {
+		t.Errorf("gotResponse: %v, wantResponse: %v", gotResponse, wantResponse)
+	}
+}
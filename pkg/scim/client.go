@@ -5,6 +5,9 @@ import (
 	"context"
 
 	framework "github.com/sgnl-ai/adapter-framework"
+	"github.com/sgnl-ai/sample-adapter/pkg/auth"
+	"github.com/sgnl-ai/sample-adapter/pkg/config"
+	"github.com/sgnl-ai/sample-adapter/pkg/retry"
 )
 
 // Client is a client that allows querying a SCIM SoR which
@@ -15,6 +18,19 @@ type Client interface {
 	// Returns a (possibly empty) list of JSON objects, each object being
 	// unmarshaled into a map by Golang's JSON unmarshaler.
 	GetPage(ctx context.Context, request *Request) (*AdapterResponse, *framework.Error)
+
+	// GetServiceProviderConfig returns the ServiceProviderConfig describing the capabilities of
+	// the datasource.
+	// Returns nil if the datasource's capabilities are unknown.
+	GetServiceProviderConfig(ctx context.Context, request *Request) (*ServiceProviderConfig, *framework.Error)
+
+	// GetSchemas returns the Schemas describing the attributes of the resources of the datasource,
+	// including extension schemas.
+	GetSchemas(ctx context.Context, request *Request) ([]Schema, *framework.Error)
+
+	// GetGroupMembers returns the `members` attribute of the group with the provided ID, as a list
+	// of JSON objects. The group is requested from the request's entity.
+	GetGroupMembers(ctx context.Context, request *Request, groupID string) ([]any, *framework.Error)
 }
 
 // Request is a request to a SCIM SoR.
@@ -25,6 +41,13 @@ type Request struct {
 	// AuthorizationHeader is the Authorization header sent to the SCIM SoR.
 	AuthorizationHeader string
 
+	// OAuth2 is the configuration of the OAuth2 grant used to request the access tokens sent to the SCIM SoR
+	// instead of AuthorizationHeader. Access tokens are cached per BaseURL and credentials until shortly before
+	// they expire, requested once for concurrent requests, and requested again once if the SCIM SoR responds
+	// with 401 Unauthorized.
+	// Optional. If not set, AuthorizationHeader is sent.
+	OAuth2 *auth.OAuth2Config
+
 	// PageSize is the maximum number of objects to return from the entity.
 	PageSize int64
 
@@ -34,15 +57,59 @@ type Request struct {
 
 	// Cursor identifies the first object of the page to return, as returned by
 	// the last request for the entity.
+	// This is a start index when using index paging, or the SCIM server's "nextCursor"
+	// when using cursor paging.
 	// Optional. If not set, return the first page for this entity.
 	Cursor string
 
+	// PagingMode is the pagination method used to request the page.
+	// If PagingModeAuto, the pagination method is detected from the SCIM server's response.
+	// Defaults to PagingModeIndex if not set.
+	PagingMode PagingMode
+
 	// QueryParams contains the query parameters required to generate the URL for the datasource request
 	QueryParams QueryParams
 
+	// Attributes is the list of attributes to return in the returned resources, sent in
+	// the "attributes" query parameter.
+	// Optional. If not set, the default set of attributes is returned.
+	Attributes []string
+
+	// ExcludedAttributes is the list of attributes to exclude from the returned resources, sent in
+	// the "excludedAttributes" query parameter. Ignored if Attributes is set.
+	// Optional. If not set, the default set of attributes is returned.
+	ExcludedAttributes []string
+
 	// RequestTimeoutSeconds is the timeout duration for requests made to datasources.
 	// This should be set to the number of seconds to wait before timing out.
 	RequestTimeoutSeconds int
+
+	// MaxURLLength is the maximum length of the URL of a "GET /{resource}" request.
+	// If the URL would be longer, the page is requested with a SearchRequest instead, or with the long URL
+	// if the SCIM server does not implement SearchRequests.
+	// Optional. If not set, the URL length is not limited.
+	MaxURLLength int
+
+	// MaxResponseBodyBytes is the maximum size of a response body. Larger responses fail with a
+	// DATASOURCE_FAILED error.
+	// Optional. If not set, the response body size is not limited.
+	MaxResponseBodyBytes int64
+
+	// RetryPolicy decides whether and when failed requests are retried.
+	// All the attempts of a request must complete within RequestTimeoutSeconds.
+	// Optional. If not set, requests are not retried.
+	RetryPolicy *retry.Policy
+
+	// RateLimit limits the rate and concurrency of requests made to the datasource with the same
+	// AuthorizationHeader, or OAuth2 client. Requests wait for the rate limit within RequestTimeoutSeconds.
+	// Optional. If not set, requests are not limited.
+	RateLimit *config.RateLimitConfig
+
+	// TLS configures the TLS connections to the SCIM SoR and, as returned by transport.TokenEndpointConfig,
+	// to its token endpoint, e.g. to present a client certificate. Connections are only reused by requests
+	// with the same TLS config.
+	// Optional. If not set, the Client's default TLS config is used.
+	TLS *config.TLSConfig
 }
 
 // AdapterResponse is a response returned by the adapter.
@@ -53,11 +120,23 @@ type AdapterResponse struct {
 	// RetryAfterHeader is the Retry-After response HTTP header, if set.
 	RetryAfterHeader string
 
+	// ErrorResponse is the SCIM error returned in the body of an unsuccessful response.
+	// nil if the response is successful or its body is not a SCIM error.
+	ErrorResponse *ErrorResponse
+
 	// Objects is the list of items returned by the datasource.
 	// May be empty.
 	Objects []map[string]any
 
+	// TotalResults is the total number of results matching the query, as reported by the datasource.
+	// 0 if not reported.
+	TotalResults int64
+
 	// NextCursor is the cursor that identifies the first object of the next page.
 	// nil if this is the last page in this full sync.
 	NextCursor string
+
+	// PagingMode is the pagination method the page was returned with.
+	// This is never PagingModeAuto.
+	PagingMode PagingMode
 }
@@ -0,0 +1,520 @@
+// Copyright 2025 SGNL.ai, Inc.
+package scim
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	"github.com/sgnl-ai/adapter-framework/web"
+)
+
+// coercionDateTimeFormats are the formats of the date-time values coerced into RFC 3339 timestamps.
+// These are the default formats of web.ConvertJSONObjectList, and common formats it does not support.
+// Unix timestamps are handled separately, to tell milliseconds apart from seconds.
+// Numbers of compactDateDigits digits are compact dates, and are parsed with the last format.
+var coercionDateTimeFormats = []web.DateTimeFormatWithTimeZone{
+	{Format: time.RFC3339, HasTimeZone: true},
+	{Format: "2006-01-02T15:04:05Z0700", HasTimeZone: true},
+	{Format: "2006-01-02 15:04:05Z07:00", HasTimeZone: true},
+	{Format: "2006-01-02T15:04:05", HasTimeZone: false},
+	{Format: time.RFC1123Z, HasTimeZone: true},
+	{Format: time.RFC1123, HasTimeZone: true},
+	{Format: time.RFC822, HasTimeZone: true},
+	{Format: time.RFC822Z, HasTimeZone: true},
+	{Format: time.RFC850, HasTimeZone: true},
+	{Format: time.UnixDate, HasTimeZone: true},
+	{Format: time.RubyDate, HasTimeZone: true},
+	{Format: "2006-01-02 15:04:05", HasTimeZone: false},
+	{Format: "1/2/2006 3:04:05 PM", HasTimeZone: false},
+	{Format: time.ANSIC, HasTimeZone: false},
+	{Format: "2006-01-02", HasTimeZone: false},
+	{Format: "2006/01/02", HasTimeZone: false},
+	{Format: "01-02-2006", HasTimeZone: false},
+	{Format: "01/02/2006", HasTimeZone: false},
+	{Format: "01/02/06", HasTimeZone: false},
+	{Format: web.SGNLGeneralizedTime, HasTimeZone: true},
+	{Format: "20060102", HasTimeZone: false},
+}
+
+const (
+	// compactDateDigits is the number of digits of a compact date, e.g. "20250101".
+	compactDateDigits = 8
+
+	// minUnixDigits and maxUnixDigits are the numbers of digits of the Unix timestamps in seconds of the dates
+	// between 1973 and 2286. Other numbers are not considered to be Unix timestamps in seconds.
+	minUnixDigits, maxUnixDigits = 9, 10
+
+	// minUnixMilliDigits and maxUnixMilliDigits are the numbers of digits of the Unix timestamps in milliseconds
+	// of the dates between 1973 and 2286. Other numbers are not considered to be Unix timestamps in milliseconds.
+	minUnixMilliDigits, maxUnixMilliDigits = 12, 13
+)
+
+// CoercionFailureHandler is called for each attribute of an entity whose values could not be coerced into
+// their type, with the number of such values in the page and the error of the first one. These values are
+// removed from the page. attribute is the path of the attribute in the resources, e.g. "emails.primary".
+type CoercionFailureHandler func(datasourceAddress string, entityExternalID string, attribute string, count int, err error)
+
+// coerceObjects coerces the attribute values of the resources of a page into the types of the SCIM server's
+// Schemas, if enabled and available, and into the types of the entity's attributes.
+func (a *Adapter) coerceObjects(
+	ctx context.Context,
+	req *Request,
+	request *framework.Request[Config],
+	objects []map[string]any,
+	localTimeZoneOffset int,
+) {
+	c := newCoercer(localTimeZoneOffset)
+
+	// Errors are ignored as the Schemas are not required to coerce the attribute values into the types of the
+	// entity's attributes.
+	if request.Config.TypeCoercion == TypeCoercionSchema {
+		if schemas, _ := a.Client.GetSchemas(ctx, req); len(schemas) > 0 {
+			schemasByID := make(map[string]*Schema, len(schemas))
+			for i := range schemas {
+				schemasByID[strings.ToLower(schemas[i].ID)] = &schemas[i]
+			}
+
+			for _, object := range objects {
+				c.coerceSchemas(object, schemasByID)
+			}
+		}
+	}
+
+	for _, object := range objects {
+		c.coerceEntity(&request.Entity, object, "")
+	}
+
+	if a.CoercionFailureHandler == nil {
+		return
+	}
+
+	attributes := make([]string, 0, len(c.failures))
+	for attribute := range c.failures {
+		attributes = append(attributes, attribute)
+	}
+
+	slices.Sort(attributes)
+
+	for _, attribute := range attributes {
+		failure := c.failures[attribute]
+		a.CoercionFailureHandler(request.Address, request.Entity.ExternalId, attribute, failure.count, failure.err)
+	}
+}
+
+// coercer coerces the attribute values of the resources of a page into the expected types, and records the
+// attributes whose values could not be coerced. Such values are removed, so that they do not fail the page.
+type coercer struct {
+	// localTimeZoneOffset is the offset of date-time values without time zone, in seconds.
+	localTimeZoneOffset int
+
+	// failures are the coercion failures of each attribute.
+	failures map[string]*coercionFailure
+}
+
+// coercionFailure records the values of an attribute which could not be coerced.
+type coercionFailure struct {
+	count int
+
+	// err is the error of the first value which could not be coerced.
+	err error
+}
+
+func newCoercer(localTimeZoneOffset int) *coercer {
+	return &coercer{
+		localTimeZoneOffset: localTimeZoneOffset,
+		failures:            make(map[string]*coercionFailure),
+	}
+}
+
+// coerceEntity coerces the values of the attributes of an entity and of its child entities in an object.
+// Attributes whose external IDs are JSONPaths with segments other than member names are not coerced.
+// path is the path of the object in the resource, used to report failures.
+func (c *coercer) coerceEntity(entity *framework.EntityConfig, object map[string]any, path string) {
+	for _, attribute := range entity.Attributes {
+		if names, ok := attributeNames(attribute.ExternalId); ok {
+			c.coerceAttribute(object, names, path+attribute.ExternalId, attribute.Type, attribute.List)
+		}
+	}
+
+	for _, childEntity := range entity.ChildEntities {
+		names, ok := attributeNames(childEntity.ExternalId)
+		if !ok {
+			continue
+		}
+
+		for _, element := range complexValues(object, names) {
+			c.coerceEntity(childEntity, element, path+childEntity.ExternalId+".")
+		}
+	}
+}
+
+// coerceAttribute coerces the value of the attribute at the path of member names in an object.
+// The values of multi-valued complex attributes along the path are coerced individually.
+func (c *coercer) coerceAttribute(
+	object map[string]any,
+	names []string,
+	path string,
+	attributeType framework.AttributeType,
+	list bool,
+) {
+	value := object[names[0]]
+	if value == nil {
+		return
+	}
+
+	if len(names) > 1 {
+		switch value := value.(type) {
+		case map[string]any:
+			c.coerceAttribute(value, names[1:], path, attributeType, list)
+		case []any:
+			// The JSONPath matches a value in each element, so each value is a single value.
+			for _, element := range value {
+				if element, ok := element.(map[string]any); ok {
+					c.coerceAttribute(element, names[1:], path, attributeType, false)
+				}
+			}
+		}
+
+		return
+	}
+
+	c.set(object, names[0], path, attributeType, list)
+}
+
+// coerceSchemas coerces the values of the attributes of a resource into the types defined by its schemas,
+// listed in its "schemas" attribute. schemas are keyed by lowercase schema URI.
+func (c *coercer) coerceSchemas(resource map[string]any, schemas map[string]*Schema) {
+	uris, _ := resource["schemas"].([]any)
+
+	var coreAttributes []SchemaAttribute
+
+	for _, uri := range uris {
+		uri, _ := uri.(string)
+
+		schema, found := schemas[strings.ToLower(uri)]
+		if !found {
+			continue
+		}
+
+		// The attributes of extension schemas are nested under their schema URI.
+		if extension, ok := resource[uri].(map[string]any); ok {
+			c.coerceSchemaAttributes(extension, schema.Attributes, uri+":")
+		} else {
+			coreAttributes = append(coreAttributes, schema.Attributes...)
+		}
+	}
+
+	c.coerceSchemaAttributes(resource, coreAttributes, "")
+}
+
+// coerceSchemaAttributes coerces the values of an object into the types of its schema attributes.
+// path is the path of the object in the resource, used to report failures.
+func (c *coercer) coerceSchemaAttributes(object map[string]any, attributes []SchemaAttribute, path string) {
+	if len(attributes) == 0 {
+		return
+	}
+
+	for name, value := range object {
+		// Attribute names are case-insensitive.
+		i := slices.IndexFunc(attributes, func(attribute SchemaAttribute) bool {
+			return strings.EqualFold(attribute.Name, name)
+		})
+		if i == -1 || value == nil {
+			continue
+		}
+
+		attribute := &attributes[i]
+
+		if strings.EqualFold(attribute.Type, "complex") {
+			for _, element := range complexValues(object, []string{name}) {
+				c.coerceSchemaAttributes(element, attribute.SubAttributes, path+attribute.Name+".")
+			}
+
+			continue
+		}
+
+		if attributeType, ok := SchemaAttributeType(attribute.Type); ok {
+			c.set(object, name, path+attribute.Name, attributeType, attribute.MultiValued)
+		}
+	}
+}
+
+// set coerces the value of an object member, or removes it and records the failure if it cannot be coerced.
+func (c *coercer) set(object map[string]any, name string, path string, attributeType framework.AttributeType, list bool) {
+	coerced, err := c.coerceValue(object[name], attributeType, list)
+	if err != nil {
+		delete(object, name)
+
+		failure, found := c.failures[path]
+		if !found {
+			failure = &coercionFailure{err: err}
+			c.failures[path] = failure
+		}
+
+		failure.count++
+
+		return
+	}
+
+	object[name] = coerced
+}
+
+// coerceValue coerces a JSON value into a value of an attribute type, as expected by web.ConvertJSONObjectList.
+// A single value of a list attribute is coerced into a list, and a list of one value of a single-valued
+// attribute into the value.
+func (c *coercer) coerceValue(value any, attributeType framework.AttributeType, list bool) (any, error) {
+	values, isList := value.([]any)
+
+	switch {
+	case value == nil:
+		return nil, nil
+	case list && isList:
+		coerced := make([]any, len(values))
+
+		for i, value := range values {
+			var err error
+			if coerced[i], err = c.coerceValue(value, attributeType, false); err != nil {
+				return nil, err
+			}
+		}
+
+		return coerced, nil
+	case list:
+		coerced, err := c.coerceValue(value, attributeType, false)
+		if err != nil {
+			return nil, err
+		}
+
+		return []any{coerced}, nil
+	case isList && len(values) == 1:
+		return c.coerceValue(values[0], attributeType, false)
+	case isList:
+		return nil, fmt.Errorf("cannot coerce a list of %d values into a single value of type %s", len(values), typeName(attributeType))
+	}
+
+	var coerced any
+
+	switch attributeType {
+	case framework.AttributeTypeString:
+		coerced = coerceString(value)
+	case framework.AttributeTypeBool:
+		coerced = coerceBool(value)
+	case framework.AttributeTypeInt64:
+		coerced = coerceInt64(value)
+	case framework.AttributeTypeDouble:
+		coerced = coerceDouble(value)
+	case framework.AttributeTypeDateTime:
+		coerced = c.coerceDateTime(value)
+	case framework.AttributeTypeDuration:
+		if s, ok := value.(string); ok {
+			if _, err := framework.ParseISO8601Duration(s); err == nil {
+				coerced = s
+			}
+		}
+	}
+
+	if coerced == nil {
+		return nil, fmt.Errorf("cannot coerce a %s into a value of type %s", jsonTypeName(value), typeName(attributeType))
+	}
+
+	return coerced, nil
+}
+
+// coerceString returns the string value of a string, number or boolean, or nil.
+func coerceString(value any) any {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		return nil
+	}
+}
+
+// coerceBool returns the boolean value of a boolean, a string such as "true" or "no", or the number 0 or 1, or nil.
+func coerceBool(value any) any {
+	switch value := value.(type) {
+	case bool:
+		return value
+	case string:
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "true", "t", "yes", "y", "1":
+			return true
+		case "false", "f", "no", "n", "0":
+			return false
+		}
+	case float64:
+		switch value {
+		case 0:
+			return false
+		case 1:
+			return true
+		}
+	}
+
+	return nil
+}
+
+// coerceInt64 returns the float64 value of an integer number or string, as JSON numbers are decoded into
+// float64 values, or nil.
+func coerceInt64(value any) any {
+	switch value := value.(type) {
+	case float64:
+		if value == math.Trunc(value) && !math.IsInf(value, 0) {
+			return value
+		}
+	case string:
+		s := strings.TrimSpace(value)
+
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return float64(n)
+		}
+
+		if f, err := strconv.ParseFloat(s, 64); err == nil && f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// coerceDouble returns the float64 value of a number or numeric string, or nil.
+func coerceDouble(value any) any {
+	switch value := value.(type) {
+	case float64:
+		return value
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// coerceDateTime returns the RFC 3339 timestamp of a date-time string in one of coercionDateTimeFormats,
+// of a compact date, or of a Unix timestamp in seconds or milliseconds, or nil. Numbers of any other length
+// are not date-times. Empty strings are returned as is, as they are converted into null values.
+func (c *coercer) coerceDateTime(value any) any {
+	var s string
+
+	switch value := value.(type) {
+	case float64:
+		if value != math.Trunc(value) || math.Abs(value) > math.MaxInt64 {
+			return nil
+		}
+
+		s = strconv.FormatInt(int64(value), 10)
+	case string:
+		s = strings.TrimSpace(value)
+		if s == "" {
+			return value
+		}
+	default:
+		return nil
+	}
+
+	unix, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || len(s) == compactDateDigits {
+		t, err := web.ParseDateTime(coercionDateTimeFormats, c.localTimeZoneOffset, s)
+		if err != nil {
+			return nil
+		}
+
+		return t.Format(time.RFC3339Nano)
+	}
+
+	var t time.Time
+
+	switch digits := len(s); {
+	case unix < 0:
+		return nil
+	case digits >= minUnixDigits && digits <= maxUnixDigits:
+		t = time.Unix(unix, 0)
+	case digits >= minUnixMilliDigits && digits <= maxUnixMilliDigits:
+		t = time.UnixMilli(unix)
+	default:
+		return nil
+	}
+
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// complexValues returns the objects at a path of member names in an object. The values of multi-valued complex
+// attributes along the path are flattened.
+func complexValues(object map[string]any, names []string) []map[string]any {
+	values := []any{object}
+
+	for _, name := range names {
+		var next []any
+
+		for _, value := range values {
+			if value, ok := value.(map[string]any); ok {
+				switch value := value[name].(type) {
+				case map[string]any:
+					next = append(next, value)
+				case []any:
+					next = append(next, value...)
+				}
+			}
+		}
+
+		values = next
+	}
+
+	objects := make([]map[string]any, 0, len(values))
+
+	for _, value := range values {
+		if value, ok := value.(map[string]any); ok {
+			objects = append(objects, value)
+		}
+	}
+
+	return objects
+}
+
+// jsonTypeName returns the name of the JSON type of a decoded JSON value, for error messages.
+// Values are not included in error messages, as they may be sensitive.
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "complex value"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// typeName returns the name of an attribute type, for error messages.
+func typeName(attributeType framework.AttributeType) string {
+	switch attributeType {
+	case framework.AttributeTypeBool:
+		return "bool"
+	case framework.AttributeTypeDateTime:
+		return "date-time"
+	case framework.AttributeTypeDouble:
+		return "double"
+	case framework.AttributeTypeDuration:
+		return "duration"
+	case framework.AttributeTypeInt64:
+		return "int64"
+	case framework.AttributeTypeString:
+		return "string"
+	default:
+		return fmt.Sprintf("type %d", attributeType)
+	}
+}
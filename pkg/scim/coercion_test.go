@@ -0,0 +1,515 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// nolint: lll
+package scim_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
+	"github.com/sgnl-ai/sample-adapter/pkg/scim"
+)
+
+const (
+	// testCoercionUsers are User resources with attribute values which do not match their types.
+	testCoercionUsers = `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+		"totalResults": 2,
+		"itemsPerPage": 2,
+		"startIndex": 1,
+		"Resources": [
+			{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+				"id": "2819c223-7f76-453a-919d-413861904646",
+				"userName": "bjensen",
+				"active": "True",
+				"loginCount": "42",
+				"emails": [
+					{"value": "bjensen@example.com", "primary": "true"},
+					{"value": "babs@example.com", "primary": "false"}
+				],
+				"meta": {"lastModified": "1748736000000"},
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": 701984}
+			},
+			{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+				"id": "c75ad752-64ae-4823-840d-ffa80929976c",
+				"userName": "jsmith",
+				"active": "maybe",
+				"loginCount": 7,
+				"meta": {"lastModified": "2025-06-01 10:00:00Z"}
+			}
+		]
+	}`
+
+	// testCoercionSchemas are the Schemas of testCoercionUsers.
+	testCoercionSchemas = `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+		"totalResults": 2,
+		"Resources": [
+			{
+				"id": "urn:ietf:params:scim:schemas:core:2.0:User",
+				"attributes": [
+					{"name": "userName", "type": "string"},
+					{"name": "active", "type": "boolean"},
+					{"name": "loginCount", "type": "integer"},
+					{"name": "emails", "type": "complex", "multiValued": true, "subAttributes": [
+						{"name": "value", "type": "string"},
+						{"name": "primary", "type": "boolean"}
+					]},
+					{"name": "meta", "type": "complex", "subAttributes": [
+						{"name": "lastModified", "type": "dateTime"}
+					]}
+				]
+			},
+			{
+				"id": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+				"attributes": [
+					{"name": "employeeNumber", "type": "string"}
+				]
+			}
+		]
+	}`
+)
+
+func TestAdapterGetPageTypeCoercion(t *testing.T) {
+	entity := framework.EntityConfig{
+		ExternalId: scimUser,
+		Attributes: []*framework.AttributeConfig{
+			{
+				ExternalId: "id",
+				Type:       framework.AttributeTypeString,
+				UniqueId:   true,
+			},
+			{
+				ExternalId: "active",
+				Type:       framework.AttributeTypeBool,
+			},
+			{
+				ExternalId: "loginCount",
+				Type:       framework.AttributeTypeInt64,
+			},
+			{
+				ExternalId: "$.meta.lastModified",
+				Type:       framework.AttributeTypeDateTime,
+			},
+			{
+				ExternalId: `$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].employeeNumber`,
+				Type:       framework.AttributeTypeString,
+			},
+			{
+				// Only matches the primary email once its "primary" value is coerced into a boolean, which is only
+				// known from the Schemas as "primary" is not an attribute of the entity.
+				ExternalId: "$.emails[?(@.primary==true)].value",
+				Type:       framework.AttributeTypeString,
+			},
+		},
+		ChildEntities: []*framework.EntityConfig{
+			{
+				ExternalId: "emails",
+				Attributes: []*framework.AttributeConfig{
+					{
+						ExternalId: "value",
+						Type:       framework.AttributeTypeString,
+					},
+				},
+			},
+		},
+	}
+
+	type coercionFailure struct {
+		attribute string
+		count     int
+		err       string
+	}
+
+	tests := map[string]struct {
+		typeCoercion  scim.TypeCoercionMode
+		schemasStatus int
+		// onlyRequestedAttributes, if true, makes the server only return the requested attributes.
+		onlyRequestedAttributes bool
+		wantResponse            framework.Response
+		wantFailures            []coercionFailure
+	}{
+		"no_coercion": {
+			typeCoercion:  scim.TypeCoercionNone,
+			schemasStatus: http.StatusOK,
+			wantResponse: framework.Response{
+				Error: &framework.Error{
+					Message: "Failed to convert SCIM response objects to JSON: attribute loginCount cannot be parsed into an int64 value.",
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+				},
+			},
+		},
+		"entity_coercion": {
+			typeCoercion:  scim.TypeCoercionEntity,
+			schemasStatus: http.StatusOK,
+			wantResponse: framework.Response{
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{
+							"id":                  "2819c223-7f76-453a-919d-413861904646",
+							"active":              true,
+							"loginCount":          int64(42),
+							"$.meta.lastModified": time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+							`$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].employeeNumber`: "701984",
+							"emails": []framework.Object{
+								{"value": "bjensen@example.com"},
+								{"value": "babs@example.com"},
+							},
+						},
+						{
+							"id":                  "c75ad752-64ae-4823-840d-ffa80929976c",
+							"loginCount":          int64(7),
+							"$.meta.lastModified": time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC),
+						},
+					},
+				},
+			},
+			wantFailures: []coercionFailure{
+				{attribute: "active", count: 1, err: "cannot coerce a string into a value of type bool"},
+			},
+		},
+		"schema_coercion": {
+			typeCoercion:  scim.TypeCoercionSchema,
+			schemasStatus: http.StatusOK,
+			wantResponse: framework.Response{
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{
+							"id":                  "2819c223-7f76-453a-919d-413861904646",
+							"active":              true,
+							"loginCount":          int64(42),
+							"$.meta.lastModified": time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+							`$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].employeeNumber`: "701984",
+							"$.emails[?(@.primary==true)].value":                                             "bjensen@example.com",
+							"emails": []framework.Object{
+								{"value": "bjensen@example.com"},
+								{"value": "babs@example.com"},
+							},
+						},
+						{
+							"id":                  "c75ad752-64ae-4823-840d-ffa80929976c",
+							"loginCount":          int64(7),
+							"$.meta.lastModified": time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC),
+						},
+					},
+				},
+			},
+			wantFailures: []coercionFailure{
+				{attribute: "active", count: 1, err: "cannot coerce a string into a value of type bool"},
+			},
+		},
+		"schema_coercion_only_requested_attributes": {
+			typeCoercion:  scim.TypeCoercionSchema,
+			schemasStatus: http.StatusOK,
+			// The schemas of each resource are requested, otherwise the Schemas would not be applied.
+			onlyRequestedAttributes: true,
+			wantResponse: framework.Response{
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{
+							"id":                  "2819c223-7f76-453a-919d-413861904646",
+							"active":              true,
+							"loginCount":          int64(42),
+							"$.meta.lastModified": time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+							`$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].employeeNumber`: "701984",
+							"$.emails[?(@.primary==true)].value":                                             "bjensen@example.com",
+							"emails": []framework.Object{
+								{"value": "bjensen@example.com"},
+								{"value": "babs@example.com"},
+							},
+						},
+						{
+							"id":                  "c75ad752-64ae-4823-840d-ffa80929976c",
+							"loginCount":          int64(7),
+							"$.meta.lastModified": time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC),
+						},
+					},
+				},
+			},
+			wantFailures: []coercionFailure{
+				{attribute: "active", count: 1, err: "cannot coerce a string into a value of type bool"},
+			},
+		},
+		"schemas_not_available": {
+			typeCoercion:  scim.TypeCoercionSchema,
+			schemasStatus: http.StatusNotFound,
+			wantResponse: framework.Response{
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{
+							"id":                  "2819c223-7f76-453a-919d-413861904646",
+							"active":              true,
+							"loginCount":          int64(42),
+							"$.meta.lastModified": time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+							`$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].employeeNumber`: "701984",
+							"emails": []framework.Object{
+								{"value": "bjensen@example.com"},
+								{"value": "babs@example.com"},
+							},
+						},
+						{
+							"id":                  "c75ad752-64ae-4823-840d-ffa80929976c",
+							"loginCount":          int64(7),
+							"$.meta.lastModified": time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC),
+						},
+					},
+				},
+			},
+			wantFailures: []coercionFailure{
+				{attribute: "active", count: 1, err: "cannot coerce a string into a value of type bool"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/" + scimUser:
+					body := testCoercionUsers
+					if tt.onlyRequestedAttributes {
+						body = onlyRequestedAttributes(t, body, r.URL.Query().Get("attributes"))
+					}
+
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(body))
+				case "/Schemas":
+					w.WriteHeader(tt.schemasStatus)
+
+					if tt.schemasStatus == http.StatusOK {
+						w.Write([]byte(testCoercionSchemas))
+					}
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			var gotFailures []coercionFailure
+
+			adapter := scim.NewAdapter(
+				&scim.Datasource{
+					Client: server.Client(),
+				},
+				scim.WithCoercionFailureHandler(func(_, entityExternalID, attribute string, count int, err error) {
+					if entityExternalID != scimUser {
+						t.Errorf("Unexpected entity: %s", entityExternalID)
+					}
+
+					gotFailures = append(gotFailures, coercionFailure{attribute: attribute, count: count, err: err.Error()})
+				}),
+			)
+
+			request := &framework.Request[scim.Config]{
+				Address: server.URL,
+				Auth: &framework.DatasourceAuthCredentials{
+					HTTPAuthorization: "Bearer token",
+				},
+				Entity: entity,
+				Config: &scim.Config{
+					TypeCoercion: tt.typeCoercion,
+				},
+				PageSize: 2,
+			}
+
+			gotResponse := adapter.GetPage(context.Background(), request)
+
+			if !reflect.DeepEqual(gotResponse, tt.wantResponse) {
+				t.Errorf("gotResponse: %v, wantResponse: %v", gotResponse, tt.wantResponse)
+			}
+
+			if !reflect.DeepEqual(gotFailures, tt.wantFailures) {
+				t.Errorf("gotFailures: %v, wantFailures: %v", gotFailures, tt.wantFailures)
+			}
+		})
+	}
+}
+
+// onlyRequestedAttributes removes the attributes of the resources of a ListResponse which are not requested by
+// the comma-separated SCIM attribute paths, like servers which only return the requested attributes.
+func onlyRequestedAttributes(t *testing.T, body string, attributes string) string {
+	requested := make(map[string]bool)
+
+	for _, path := range strings.Split(attributes, ",") {
+		// Extension attributes are prefixed with their schema URI.
+		if i := strings.LastIndex(path, ":"); i >= 0 {
+			requested[path[:i]] = true
+		} else {
+			name, _, _ := strings.Cut(path, ".")
+			requested[name] = true
+		}
+	}
+
+	var response map[string]any
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	for _, resource := range response["Resources"].([]any) {
+		for name := range resource.(map[string]any) {
+			if !requested[name] {
+				delete(resource.(map[string]any), name)
+			}
+		}
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		t.Fatalf("Failed to encode response: %v", err)
+	}
+
+	return string(data)
+}
+
+func TestCoercionFailureErrors(t *testing.T) {
+	var gotErrs []string
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"totalResults": 1, "Resources": [{"id": "1", "loginCount": "1.5", "roles": [true, "admin"], "createdAt": "yesterday"}]}`))
+	}))
+	defer server.Close()
+
+	adapter := scim.NewAdapter(
+		&scim.Datasource{
+			Client: server.Client(),
+		},
+		scim.WithCoercionFailureHandler(func(_, _, _ string, _ int, err error) {
+			gotErrs = append(gotErrs, err.Error())
+		}),
+	)
+
+	gotResponse := adapter.GetPage(context.Background(), &framework.Request[scim.Config]{
+		Address: server.URL,
+		Auth: &framework.DatasourceAuthCredentials{
+			HTTPAuthorization: "Bearer token",
+		},
+		Entity: framework.EntityConfig{
+			ExternalId: scimUser,
+			Attributes: []*framework.AttributeConfig{
+				{ExternalId: "id", Type: framework.AttributeTypeString, UniqueId: true},
+				{ExternalId: "loginCount", Type: framework.AttributeTypeInt64},
+				{ExternalId: "roles", Type: framework.AttributeTypeString},
+				{ExternalId: "createdAt", Type: framework.AttributeTypeDateTime},
+			},
+		},
+		Config: &scim.Config{
+			TypeCoercion: scim.TypeCoercionEntity,
+		},
+		PageSize: 1,
+	})
+
+	wantResponse := framework.Response{
+		Success: &framework.Page{
+			Objects: []framework.Object{{"id": "1"}},
+		},
+	}
+
+	if !reflect.DeepEqual(gotResponse, wantResponse) {
+		t.Errorf("gotResponse: %v, wantResponse: %v", gotResponse, wantResponse)
+	}
+
+	// Failures are reported in the order of the attribute paths, and do not include the values.
+	wantErrs := []string{
+		"cannot coerce a string into a value of type date-time",
+		"cannot coerce a string into a value of type int64",
+		"cannot coerce a list of 2 values into a single value of type string",
+	}
+
+	if !reflect.DeepEqual(gotErrs, wantErrs) {
+		t.Errorf("gotErrs: %v, wantErrs: %v", gotErrs, wantErrs)
+	}
+}
+
+func TestAdapterGetPageDateTimeCoercion(t *testing.T) {
+	tests := map[string]struct {
+		value string
+		want  any
+	}{
+		"unix_seconds_string": {
+			value: `"1748736000"`,
+			want:  time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"unix_milliseconds_number": {
+			value: `1748736000500`,
+			want:  time.Date(2025, 6, 1, 0, 0, 0, 500000000, time.UTC),
+		},
+		"compact_date_string": {
+			value: `"20250101"`,
+			want:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"compact_date_number": {
+			value: `20250101`,
+			want:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"invalid_compact_date": {
+			value: `"20251301"`,
+		},
+		"number_too_short_for_timestamp": {
+			value: `"1234"`,
+		},
+		"number_too_long_for_timestamp": {
+			value: `"17487360000000"`,
+		},
+		"negative_number": {
+			value: `"-1748736000"`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"totalResults": 1, "Resources": [{"id": "1", "createdAt": ` + tt.value + `}]}`))
+			}))
+			defer server.Close()
+
+			adapter := scim.NewAdapter(&scim.Datasource{
+				Client: server.Client(),
+			})
+
+			gotResponse := adapter.GetPage(context.Background(), &framework.Request[scim.Config]{
+				Address: server.URL,
+				Auth: &framework.DatasourceAuthCredentials{
+					HTTPAuthorization: "Bearer token",
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{ExternalId: "id", Type: framework.AttributeTypeString, UniqueId: true},
+						{ExternalId: "createdAt", Type: framework.AttributeTypeDateTime},
+					},
+				},
+				Config: &scim.Config{
+					TypeCoercion: scim.TypeCoercionEntity,
+				},
+				PageSize: 1,
+			})
+
+			wantObject := framework.Object{"id": "1"}
+			if tt.want != nil {
+				wantObject["createdAt"] = tt.want
+			}
+
+			wantResponse := framework.Response{
+				Success: &framework.Page{
+					Objects: []framework.Object{wantObject},
+				},
+			}
+
+			if !reflect.DeepEqual(gotResponse, wantResponse) {
+				t.Errorf("gotResponse: %v, wantResponse: %v", gotResponse, wantResponse)
+			}
+		})
+	}
+}
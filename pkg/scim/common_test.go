@@ -2,14 +2,112 @@
 package scim_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
 	"net/http"
+	"slices"
+	"strings"
+	"testing"
 	"time"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	"github.com/sgnl-ai/sample-adapter/pkg/scim"
 )
 
+// encodeTestCursor encodes a cursor for the query of the provided request, as returned by the adapter.
+func encodeTestCursor(request *framework.Request[scim.Config], cursor *scim.Cursor) string {
+	withQueryHash := *cursor
+	withQueryHash.QueryHash = scim.QueryHash(request)
+
+	return scim.EncodeCursor(&withQueryHash, nil)
+}
+
+// testCertificate is a certificate and its private key, generated for tests.
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func (c *testCertificate) certificatePEM() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}))
+}
+
+func (c *testCertificate) keyPEM(t *testing.T) string {
+	der, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatalf("Failed to marshal private key: %v", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+}
+
+// newTestCertificate generates a certificate for 127.0.0.1 and example.com, like httptest's certificate, usable by
+// servers and clients, signed by the parent, or a self-signed CA certificate if the parent is nil.
+func newTestCertificate(t *testing.T, commonName string, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour).Truncate(time.Second),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:     []string{"example.com"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	issuer, issuerKey := template, key
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		issuer, issuerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+
+	return &testCertificate{cert: cert, key: key}
+}
+
+// fixtureRequestURI returns the request URI used to match the endpoints of the mock SCIM server.
+// The "attributes" query parameter is derived from the requested entity's attributes, so it is removed
+// from requests listing resources, which match the same endpoints regardless of the requested attributes.
+func fixtureRequestURI(r *http.Request) string {
+	if strings.Count(r.URL.Path, "/") > 1 || r.URL.RawQuery == "" {
+		return r.URL.RequestURI()
+	}
+
+	params := slices.DeleteFunc(strings.Split(r.URL.RawQuery, "&"), func(param string) bool {
+		return strings.HasPrefix(param, "attributes=")
+	})
+
+	return r.URL.Path + "?" + strings.Join(params, "&")
+}
+
 // Define the endpoints and responses for the mock SCIM server.
 // This handler is intended to be re-used throughout the test package.
 var TestServerHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-	switch r.URL.RequestURI() {
+	switch fixtureRequestURI(r) {
 
 	// User endpoints
 	case "/Users?startIndex=1&count=2":
@@ -216,10 +314,71 @@ var TestServerHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Req
 			]
 		}`))
 
+	// User endpoints using cursor paging
+	// https://datatracker.ietf.org/doc/html/rfc9865
+	case "/Users?cursor&count=2":
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"schemas": [
+				"urn:ietf:params:scim:api:messages:2.0:ListResponse"
+			],
+			"totalResults": 5,
+			"itemsPerPage": 2,
+			"nextCursor": "VZUTiyhEQJ94IR",
+			"Resources": [
+				{
+					"id": "2819c223-7f76-453a-919d-413861904646",
+					"userName": "Alex"
+				},
+				{
+					"id": "c75ad752-64ae-4823-840d-ffa80929976c",
+					"userName": "Bacong"
+				}
+			]
+		}`))
+	case "/Users?cursor=VZUTiyhEQJ94IR&count=2":
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"schemas": [
+				"urn:ietf:params:scim:api:messages:2.0:ListResponse"
+			],
+			"totalResults": 5,
+			"itemsPerPage": 2,
+			"previousCursor": "ze7L30kMiiLX6x",
+			"nextCursor": "YkU3OF86Pz0rGv",
+			"Resources": [
+				{
+					"id": "e2be737c-61f5-4abe-8797-1e816b15cec8",
+					"userName": "Carol"
+				},
+				{
+					"id": "89fa657e-3ef5-49e3-bb34-b3255e04a8bb",
+					"userName": "David"
+				}
+			]
+		}`))
+	case "/Users?cursor=YkU3OF86Pz0rGv&count=2":
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"schemas": [
+				"urn:ietf:params:scim:api:messages:2.0:ListResponse"
+			],
+			"totalResults": 5,
+			"itemsPerPage": 1,
+			"previousCursor": "VZUTiyhEQJ94IR",
+			"Resources": [
+				{
+					"id": "2819c223-7f76-453a-919d-413861904000",
+					"userName": "bjensen@example.com"
+				}
+			]
+		}`))
+
 	// Group endpoints
 	// Group B is a member of Group A. Group D is a member of Group C.
 	// Members of group "Tour Guides" is a user and a group.
-	case "/Groups?startIndex=1&count=2":
+	// This server does not support cursor paging, so the cursor query parameter is ignored.
+	case "/Groups?startIndex=1&count=2", "/Groups?cursor&count=2":
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{
 			"schemas": [
@@ -313,6 +472,87 @@ var TestServerHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Req
 			]
 		}`))
 
+	// Group endpoints excluding members, with members requested separately for each group
+	case "/Groups?startIndex=1&count=2&excludedAttributes=members":
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"schemas": [
+				"urn:ietf:params:scim:api:messages:2.0:ListResponse"
+			],
+			"totalResults": 5,
+			"itemsPerPage": 2,
+			"startIndex": 1,
+			"Resources": [
+				{
+					"id": "c3a26dd3-27a0-4dec-a2ac-ce211e105f97",
+					"displayName": "Group A"
+				},
+				{
+					"id": "6c5bb468-14b2-4183-baf2-06d523e03bd3",
+					"displayName": "Group B"
+				}
+			]
+		}`))
+	case "/Groups/c3a26dd3-27a0-4dec-a2ac-ce211e105f97?attributes=members":
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"schemas": [
+				"urn:ietf:params:scim:schemas:core:2.0:Group"
+			],
+			"id": "c3a26dd3-27a0-4dec-a2ac-ce211e105f97",
+			"members": [
+				{
+					"value": "6c5bb468-14b2-4183-baf2-06d523e03bd3",
+					"$ref": "https://example.com/v2/Groups/6c5bb468-14b2-4183-baf2-06d523e03bd3",
+					"display": "Group"
+				}
+			]
+		}`))
+	case "/Groups/6c5bb468-14b2-4183-baf2-06d523e03bd3?attributes=members":
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"schemas": [
+				"urn:ietf:params:scim:schemas:core:2.0:Group"
+			],
+			"id": "6c5bb468-14b2-4183-baf2-06d523e03bd3"
+		}`))
+	case "/Groups?startIndex=5&count=2&excludedAttributes=members":
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"schemas": [
+				"urn:ietf:params:scim:api:messages:2.0:ListResponse"
+			],
+			"totalResults": 5,
+			"itemsPerPage": 2,
+			"startIndex": 5,
+			"Resources": [
+				{
+					"id": "e9e30dba-f08f-4109-8486-d5c6a331660a",
+					"displayName": "Tour Guides"
+				}
+			]
+		}`))
+	case "/Groups/e9e30dba-f08f-4109-8486-d5c6a331660a?attributes=members":
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"schemas": [
+				"urn:ietf:params:scim:schemas:core:2.0:Group"
+			],
+			"id": "e9e30dba-f08f-4109-8486-d5c6a331660a",
+			"members": [
+				{
+					"value": "2819c223-7f76-453a-919d-413861904646",
+					"$ref": "https://example.com/v2/Users/2819c223-7f76-453a-919d-413861904646",
+					"type": "User"
+				},
+				{
+					"value": "6c5bb468-14b2-4183-baf2-06d523e03bd3",
+					"$ref": "https://example.com/v2/Groups/6c5bb468-14b2-4183-baf2-06d523e03bd3",
+					"type": "Group"
+				}
+			]
+		}`))
+
 	// Additional endpoints to facilitate testing
 	// Simulate a bad request
 	case "/Users?startIndex=400&count=1":
@@ -0,0 +1,228 @@
+// Copyright 2025 SGNL.ai, Inc.
+package scim
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+)
+
+// CursorVersion is the version of the cursor format returned by the adapter.
+const CursorVersion = 1
+
+// cursorSignatureSeparator separates the encoded cursor from its signature in signed cursors.
+// It is not part of the base64url alphabet.
+const cursorSignatureSeparator = "."
+
+// ErrInvalidCursorSignature is returned when decoding a cursor which is not signed, or whose signature
+// does not match any of the verification keys.
+var ErrInvalidCursorSignature = errors.New("invalid cursor signature")
+
+// Cursor is the position of the next page of a sync, as returned by the adapter to the ingestion service.
+// It is encoded by EncodeCursor as base64 JSON, so it is opaque to the ingestion service.
+type Cursor struct {
+	// Version is the version of the cursor format. 0 for a legacy cursor, i.e. a bare start index.
+	Version int `json:"v"`
+
+	// PagingMode is the pagination method used to request the pages of the sync.
+	// This is never PagingModeAuto. Legacy cursors use PagingModeIndex.
+	PagingMode PagingMode `json:"mode,omitempty"`
+
+	// StartIndex is the start index of the next page, when using index paging.
+	StartIndex int64 `json:"startIndex,omitempty"`
+
+	// ServerCursor is the cursor of the next page returned by the SCIM server, when using cursor paging.
+	ServerCursor string `json:"cursor,omitempty"`
+
+	// TotalResults is the total number of results reported by the SCIM server on the previous page.
+	TotalResults int64 `json:"totalResults,omitempty"`
+
+	// QueryHash is the hash of the query of the sync, as returned by QueryHash.
+	// Empty for a legacy cursor.
+	QueryHash string `json:"query,omitempty"`
+
+	// LocalFilter is true if the filter is evaluated in the adapter rather than by the SCIM server,
+	// e.g. after the SCIM server responded with 501 Not Implemented to a filtered request.
+	LocalFilter bool `json:"localFilter,omitempty"`
+
+	// IncrementalSync is the state of the incremental sync, if the entity is synced incrementally.
+	IncrementalSync *IncrementalSyncState `json:"sync,omitempty"`
+}
+
+// EncodeCursor encodes a cursor with the current cursor format.
+// If signingKey is set, the cursor is signed with HMAC-SHA256 using the key.
+func EncodeCursor(cursor *Cursor, signingKey []byte) string {
+	encoded := *cursor
+	encoded.Version = CursorVersion
+
+	// Marshaling a struct of strings, integers and booleans cannot fail.
+	data, _ := json.Marshal(&encoded)
+	payload := base64.RawURLEncoding.EncodeToString(data)
+
+	if len(signingKey) == 0 {
+		return payload
+	}
+
+	return payload + cursorSignatureSeparator + signCursor(payload, signingKey)
+}
+
+// DecodeCursor decodes a cursor returned by EncodeCursor.
+// A legacy cursor, i.e. a bare start index, is decoded as a cursor of version 0 using index paging.
+//
+// If verificationKeys are set, the cursor must be signed with one of the keys, so that cursors cannot be
+// crafted or modified by clients. ErrInvalidCursorSignature is returned otherwise, including for legacy
+// cursors, which are not signed. Multiple keys are accepted to rotate the signing key without invalidating
+// the cursors of syncs in progress.
+func DecodeCursor(encoded string, verificationKeys [][]byte) (*Cursor, error) {
+	if len(verificationKeys) > 0 {
+		payload, signature, found := strings.Cut(encoded, cursorSignatureSeparator)
+		if !found || !verifyCursor(payload, signature, verificationKeys) {
+			return nil, ErrInvalidCursorSignature
+		}
+
+		encoded = payload
+	} else {
+		// Cursors signed before signing was disabled are accepted without verification.
+		encoded, _, _ = strings.Cut(encoded, cursorSignatureSeparator)
+	}
+
+	if startIndex, err := strconv.ParseInt(encoded, 10, 64); err == nil {
+		if startIndex < 1 {
+			return nil, fmt.Errorf("invalid start index %d", startIndex)
+		}
+
+		return &Cursor{
+			PagingMode: PagingModeIndex,
+			StartIndex: startIndex,
+		}, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor *Cursor
+
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+
+	if cursor == nil {
+		return nil, errors.New("empty cursor")
+	}
+
+	if cursor.Version != CursorVersion {
+		return nil, fmt.Errorf("unsupported cursor version %d", cursor.Version)
+	}
+
+	switch cursor.PagingMode {
+	case PagingModeIndex:
+		if cursor.StartIndex < 1 {
+			return nil, fmt.Errorf("invalid start index %d", cursor.StartIndex)
+		}
+	case PagingModeCursor:
+		if cursor.ServerCursor == "" {
+			return nil, errors.New("missing server cursor")
+		}
+	default:
+		return nil, fmt.Errorf("invalid paging mode %q", cursor.PagingMode)
+	}
+
+	return cursor, nil
+}
+
+// QueryHash returns a hash of the query used to request the pages of the requested entity.
+// A cursor is only valid for requests with the same query hash, as the position of the next page depends
+// on the entity, its attributes, and its query parameters.
+func QueryHash(request *framework.Request[Config]) string {
+	query := struct {
+		Entity               string      `json:"entity"`
+		Attributes           []string    `json:"attributes"`
+		ChildEntities        []string    `json:"childEntities"`
+		QueryParams          QueryParams `json:"queryParams"`
+		RequestAllAttributes bool        `json:"requestAllAttributes"`
+		GroupMembers         bool        `json:"groupMembers"`
+		IncrementalSync      bool        `json:"incrementalSync"`
+	}{
+		Entity: request.Entity.ExternalId,
+	}
+
+	for _, attribute := range request.Entity.Attributes {
+		query.Attributes = append(query.Attributes, attribute.ExternalId)
+	}
+
+	for _, childEntity := range request.Entity.ChildEntities {
+		query.ChildEntities = append(query.ChildEntities, childEntity.ExternalId)
+	}
+
+	if request.Config != nil {
+		query.QueryParams = request.Config.QueryParams[request.Entity.ExternalId]
+		query.RequestAllAttributes = request.Config.RequestAllAttributes
+		query.GroupMembers = request.Config.GroupMembers != nil
+		_, query.IncrementalSync = request.Config.IncrementalSync[request.Entity.ExternalId]
+	}
+
+	// Marshaling a struct of strings and booleans cannot fail.
+	data, _ := json.Marshal(&query)
+	sum := sha256.Sum256(data)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// signCursor returns the base64url-encoded HMAC-SHA256 signature of an encoded cursor.
+func signCursor(payload string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyCursor returns whether the signature of an encoded cursor matches any of the provided keys.
+func verifyCursor(payload string, signature string, keys [][]byte) bool {
+	for _, key := range keys {
+		if hmac.Equal([]byte(signature), []byte(signCursor(payload, key))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cursorMatchesQuery returns whether a cursor can be used to request the next page of a sync with the
+// provided query hash and configured paging mode.
+// Legacy cursors have no query hash, so only their paging mode is checked.
+func cursorMatchesQuery(cursor *Cursor, queryHash string, pagingMode PagingMode) bool {
+	if cursor.Version > 0 && cursor.QueryHash != queryHash {
+		return false
+	}
+
+	if pagingMode == "" {
+		pagingMode = PagingModeIndex
+	}
+
+	return pagingMode == PagingModeAuto || pagingMode == cursor.PagingMode
+}
+
+// adjustNextStartIndex returns the start index of the page following the page requested with the
+// provided cursor, given the next start index computed from the page and its total number of results.
+//
+// If the total number of results decreased since the previous page, resources were likely deleted
+// before the current position, shifting the following resources to lower indexes. The next start index
+// is moved back by the decrease so that they are not skipped, at the cost of returning some resources
+// again, but always past the start index of the current page so that the sync progresses.
+func adjustNextStartIndex(cursor *Cursor, nextStartIndex int64, totalResults int64) int64 {
+	decrease := cursor.TotalResults - totalResults
+	if cursor.TotalResults == 0 || decrease <= 0 {
+		return nextStartIndex
+	}
+
+	return max(nextStartIndex-decrease, max(cursor.StartIndex, 1)+1)
+}
@@ -0,0 +1,511 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// nolint: lll
+package scim_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
+	"github.com/sgnl-ai/sample-adapter/pkg/scim"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	tests := map[string]struct {
+		cursor     string
+		wantCursor *scim.Cursor
+		wantErr    string
+	}{
+		"index_cursor": {
+			cursor: scim.EncodeCursor(&scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   3,
+				TotalResults: 5,
+				QueryHash:    "hash",
+			}, nil),
+			wantCursor: &scim.Cursor{
+				Version:      scim.CursorVersion,
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   3,
+				TotalResults: 5,
+				QueryHash:    "hash",
+			},
+		},
+		"server_cursor_with_incremental_sync": {
+			cursor: scim.EncodeCursor(&scim.Cursor{
+				PagingMode:   scim.PagingModeCursor,
+				ServerCursor: "VZUTiyhEQJ94IR",
+				QueryHash:    "hash",
+				IncrementalSync: &scim.IncrementalSyncState{
+					Watermark:       "2025-06-01T11:55:00Z",
+					MaxLastModified: "2025-06-03T09:30:00.5Z",
+				},
+			}, nil),
+			wantCursor: &scim.Cursor{
+				Version:      scim.CursorVersion,
+				PagingMode:   scim.PagingModeCursor,
+				ServerCursor: "VZUTiyhEQJ94IR",
+				QueryHash:    "hash",
+				IncrementalSync: &scim.IncrementalSyncState{
+					Watermark:       "2025-06-01T11:55:00Z",
+					MaxLastModified: "2025-06-03T09:30:00.5Z",
+				},
+			},
+		},
+		"legacy_start_index": {
+			cursor: "3",
+			wantCursor: &scim.Cursor{
+				PagingMode: scim.PagingModeIndex,
+				StartIndex: 3,
+			},
+		},
+		"legacy_invalid_start_index": {
+			cursor:  "0",
+			wantErr: "invalid start index 0",
+		},
+		"not_base64": {
+			cursor:  "cursor:VZUTiyhEQJ94IR",
+			wantErr: "illegal base64 data at input byte 6",
+		},
+		"not_json": {
+			cursor:  base64.RawURLEncoding.EncodeToString([]byte("cursor")),
+			wantErr: "invalid character 'c' looking for beginning of value",
+		},
+		"null": {
+			cursor:  base64.RawURLEncoding.EncodeToString([]byte("null")),
+			wantErr: "empty cursor",
+		},
+		"unsupported_version": {
+			cursor:  base64.RawURLEncoding.EncodeToString([]byte(`{"v": 2, "mode": "index", "startIndex": 3}`)),
+			wantErr: "unsupported cursor version 2",
+		},
+		"invalid_paging_mode": {
+			cursor:  base64.RawURLEncoding.EncodeToString([]byte(`{"v": 1, "mode": "auto", "startIndex": 3}`)),
+			wantErr: `invalid paging mode "auto"`,
+		},
+		"missing_start_index": {
+			cursor:  base64.RawURLEncoding.EncodeToString([]byte(`{"v": 1, "mode": "index"}`)),
+			wantErr: "invalid start index 0",
+		},
+		"missing_server_cursor": {
+			cursor:  base64.RawURLEncoding.EncodeToString([]byte(`{"v": 1, "mode": "cursor"}`)),
+			wantErr: "missing server cursor",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotCursor, gotErr := scim.DecodeCursor(tt.cursor, nil)
+
+			if !reflect.DeepEqual(gotCursor, tt.wantCursor) {
+				t.Errorf("gotCursor: %+v, wantCursor: %+v", gotCursor, tt.wantCursor)
+			}
+
+			var gotErrMessage string
+			if gotErr != nil {
+				gotErrMessage = gotErr.Error()
+			}
+
+			if gotErrMessage != tt.wantErr {
+				t.Errorf("gotErr: %v, wantErr: %v", gotErrMessage, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQueryHash(t *testing.T) {
+	newRequest := func(filter string, attributes ...string) *framework.Request[scim.Config] {
+		request := &framework.Request[scim.Config]{
+			Entity: framework.EntityConfig{
+				ExternalId: scimUser,
+			},
+			Config: &scim.Config{
+				QueryParams: map[string]scim.QueryParams{
+					scimUser: {
+						Filter: filter,
+					},
+					scimGroup: {
+						Filter: `displayName eq "Admins"`,
+					},
+				},
+			},
+			Cursor:   "3",
+			PageSize: 2,
+		}
+
+		for _, attribute := range attributes {
+			request.Entity.Attributes = append(request.Entity.Attributes, &framework.AttributeConfig{
+				ExternalId: attribute,
+			})
+		}
+
+		return request
+	}
+
+	hash := scim.QueryHash(newRequest(`userType eq "Employee"`, "id"))
+
+	if hash != scim.QueryHash(newRequest(`userType eq "Employee"`, "id")) {
+		t.Error("QueryHash is not deterministic")
+	}
+
+	if hash == scim.QueryHash(newRequest(`userType eq "Contractor"`, "id")) {
+		t.Error("Hashes of different filters are equal")
+	}
+
+	if hash == scim.QueryHash(newRequest(`userType eq "Employee"`, "id", "userName")) {
+		t.Error("Hashes of different attributes are equal")
+	}
+
+	// The query parameters of other entities, the cursor and the page size are not part of the query.
+	otherRequest := newRequest(`userType eq "Employee"`, "id")
+	otherRequest.Config.QueryParams[scimGroup] = scim.QueryParams{}
+	otherRequest.Cursor = "5"
+	otherRequest.PageSize = 10
+
+	if hash != scim.QueryHash(otherRequest) {
+		t.Error("Hashes of the same query are different")
+	}
+}
+
+func TestAdapterGetPageCursor(t *testing.T) {
+	server := httptest.NewTLSServer(TestServerHandler)
+	defer server.Close()
+
+	adapter := scim.NewAdapter(&scim.Datasource{
+		Client: server.Client(),
+	})
+
+	newRequest := func(pagingMode scim.PagingMode) *framework.Request[scim.Config] {
+		return &framework.Request[scim.Config]{
+			Address: server.URL,
+			Auth: &framework.DatasourceAuthCredentials{
+				Basic: &framework.BasicAuthCredentials{
+					Username: testUsername,
+					Password: testPassword,
+				},
+			},
+			Entity: framework.EntityConfig{
+				ExternalId: scimUser,
+				Attributes: []*framework.AttributeConfig{
+					{
+						ExternalId: "id",
+						Type:       framework.AttributeTypeString,
+					},
+				},
+			},
+			Config: &scim.Config{
+				QueryParams: map[string]scim.QueryParams{
+					scimUser: {
+						PagingMode: pagingMode,
+					},
+				},
+			},
+			PageSize: 2,
+		}
+	}
+
+	middlePage := []framework.Object{
+		{"id": "e2be737c-61f5-4abe-8797-1e816b15cec8"},
+		{"id": "89fa657e-3ef5-49e3-bb34-b3255e04a8bb"},
+	}
+
+	tests := map[string]struct {
+		request *framework.Request[scim.Config]
+		// cursor is encoded for the query of cursorRequest, or of request if not set.
+		cursor         *scim.Cursor
+		cursorRequest  *framework.Request[scim.Config]
+		legacyCursor   string
+		wantObjects    []framework.Object
+		wantNextCursor *scim.Cursor
+		wantErr        *framework.Error
+	}{
+		"index_cursor": {
+			request: newRequest(scim.PagingModeIndex),
+			cursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   3,
+				TotalResults: 5,
+			},
+			wantObjects: middlePage,
+			wantNextCursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   5,
+				TotalResults: 5,
+			},
+		},
+		"legacy_cursor": {
+			request:      newRequest(""),
+			legacyCursor: "3",
+			wantObjects:  middlePage,
+			wantNextCursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   5,
+				TotalResults: 5,
+			},
+		},
+		// 2 resources were deleted since the previous page, so the next page starts 2 indexes earlier,
+		// but after the start index of this page.
+		"total_results_decreased": {
+			request: newRequest(scim.PagingModeIndex),
+			cursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   3,
+				TotalResults: 7,
+			},
+			wantObjects: middlePage,
+			wantNextCursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   4,
+				TotalResults: 5,
+			},
+		},
+		"total_results_increased": {
+			request: newRequest(scim.PagingModeIndex),
+			cursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   3,
+				TotalResults: 4,
+			},
+			wantObjects: middlePage,
+			wantNextCursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   5,
+				TotalResults: 5,
+			},
+		},
+		"query_changed": {
+			request: newRequest(scim.PagingModeIndex),
+			cursor: &scim.Cursor{
+				PagingMode: scim.PagingModeIndex,
+				StartIndex: 3,
+			},
+			cursorRequest: newRequest(scim.PagingModeAuto),
+			wantErr: &framework.Error{
+				Message: "The cursor does not match the current query of entity Users. Restart the sync.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_PAGE_REQUEST_CONFIG,
+			},
+		},
+		"paging_mode_changed": {
+			request: newRequest(scim.PagingModeCursor),
+			cursor: &scim.Cursor{
+				PagingMode: scim.PagingModeIndex,
+				StartIndex: 3,
+			},
+			wantErr: &framework.Error{
+				Message: "The cursor does not match the current query of entity Users. Restart the sync.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_PAGE_REQUEST_CONFIG,
+			},
+		},
+		"legacy_cursor_with_cursor_paging": {
+			request:      newRequest(scim.PagingModeCursor),
+			legacyCursor: "3",
+			wantErr: &framework.Error{
+				Message: "The cursor does not match the current query of entity Users. Restart the sync.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_PAGE_REQUEST_CONFIG,
+			},
+		},
+		"invalid_cursor": {
+			request:      newRequest(scim.PagingModeIndex),
+			legacyCursor: "-1",
+			wantErr: &framework.Error{
+				Message: "Invalid cursor: invalid start index -1.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_PAGE_REQUEST_CONFIG,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tt.request.Cursor = tt.legacyCursor
+
+			if tt.cursor != nil {
+				cursorRequest := tt.cursorRequest
+				if cursorRequest == nil {
+					cursorRequest = tt.request
+				}
+
+				tt.request.Cursor = encodeTestCursor(cursorRequest, tt.cursor)
+			}
+
+			wantResponse := framework.Response{
+				Error: tt.wantErr,
+			}
+
+			if tt.wantErr == nil {
+				wantResponse.Success = &framework.Page{
+					Objects:    tt.wantObjects,
+					NextCursor: encodeTestCursor(tt.request, tt.wantNextCursor),
+				}
+			}
+
+			gotResponse := adapter.GetPage(context.Background(), tt.request)
+
+			if !reflect.DeepEqual(gotResponse, wantResponse) {
+				t.Errorf("gotResponse: %v, wantResponse: %v", gotResponse, wantResponse)
+			}
+		})
+	}
+}
+
+func TestDecodeSignedCursor(t *testing.T) {
+	oldKey := []byte("old-cursor-signing-key-0123456789")
+	newKey := []byte("new-cursor-signing-key-0123456789")
+
+	cursor := &scim.Cursor{
+		PagingMode: scim.PagingModeIndex,
+		StartIndex: 3,
+	}
+
+	signedWithNewKey := scim.EncodeCursor(cursor, newKey)
+	signedWithOldKey := scim.EncodeCursor(cursor, oldKey)
+	unsigned := scim.EncodeCursor(cursor, nil)
+
+	wantCursor := &scim.Cursor{
+		Version:    scim.CursorVersion,
+		PagingMode: scim.PagingModeIndex,
+		StartIndex: 3,
+	}
+
+	tampered := scim.EncodeCursor(&scim.Cursor{
+		PagingMode: scim.PagingModeIndex,
+		StartIndex: 1000,
+	}, nil) + signedWithNewKey[strings.Index(signedWithNewKey, "."):]
+
+	tests := map[string]struct {
+		cursor           string
+		verificationKeys [][]byte
+		wantCursor       *scim.Cursor
+		wantErr          error
+	}{
+		"signed_with_current_key": {
+			cursor:           signedWithNewKey,
+			verificationKeys: [][]byte{newKey, oldKey},
+			wantCursor:       wantCursor,
+		},
+		"signed_with_previous_key": {
+			cursor:           signedWithOldKey,
+			verificationKeys: [][]byte{newKey, oldKey},
+			wantCursor:       wantCursor,
+		},
+		"signed_with_removed_key": {
+			cursor:           signedWithOldKey,
+			verificationKeys: [][]byte{newKey},
+			wantErr:          scim.ErrInvalidCursorSignature,
+		},
+		"tampered": {
+			cursor:           tampered,
+			verificationKeys: [][]byte{newKey},
+			wantErr:          scim.ErrInvalidCursorSignature,
+		},
+		"unsigned": {
+			cursor:           unsigned,
+			verificationKeys: [][]byte{newKey},
+			wantErr:          scim.ErrInvalidCursorSignature,
+		},
+		"legacy_start_index": {
+			cursor:           "3",
+			verificationKeys: [][]byte{newKey},
+			wantErr:          scim.ErrInvalidCursorSignature,
+		},
+		"signed_without_verification_keys": {
+			cursor:     signedWithNewKey,
+			wantCursor: wantCursor,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotCursor, gotErr := scim.DecodeCursor(tt.cursor, tt.verificationKeys)
+
+			if !reflect.DeepEqual(gotCursor, tt.wantCursor) {
+				t.Errorf("gotCursor: %+v, wantCursor: %+v", gotCursor, tt.wantCursor)
+			}
+
+			if !errors.Is(gotErr, tt.wantErr) {
+				t.Errorf("gotErr: %v, wantErr: %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAdapterGetPageSignedCursor(t *testing.T) {
+	server := httptest.NewTLSServer(TestServerHandler)
+	defer server.Close()
+
+	key := []byte("cursor-signing-key-0123456789abcdef")
+
+	adapter := scim.NewAdapter(
+		&scim.Datasource{
+			Client: server.Client(),
+		},
+		scim.WithCursorSigningKeys(key),
+	)
+
+	request := &framework.Request[scim.Config]{
+		Address: server.URL,
+		Auth: &framework.DatasourceAuthCredentials{
+			Basic: &framework.BasicAuthCredentials{
+				Username: testUsername,
+				Password: testPassword,
+			},
+		},
+		Entity: framework.EntityConfig{
+			ExternalId: scimUser,
+			Attributes: []*framework.AttributeConfig{
+				{
+					ExternalId: "id",
+					Type:       framework.AttributeTypeString,
+				},
+			},
+		},
+		PageSize: 2,
+	}
+
+	firstPage := adapter.GetPage(context.Background(), request)
+	if firstPage.Error != nil {
+		t.Fatalf("Unexpected error on the first page: %v", firstPage.Error)
+	}
+
+	wantNextCursor := &scim.Cursor{
+		Version:      scim.CursorVersion,
+		PagingMode:   scim.PagingModeIndex,
+		StartIndex:   3,
+		TotalResults: 5,
+		QueryHash:    scim.QueryHash(request),
+	}
+
+	gotNextCursor, err := scim.DecodeCursor(firstPage.Success.NextCursor, [][]byte{key})
+	if err != nil {
+		t.Fatalf("Failed to decode the next cursor: %v", err)
+	}
+
+	if !reflect.DeepEqual(gotNextCursor, wantNextCursor) {
+		t.Errorf("gotNextCursor: %+v, wantNextCursor: %+v", gotNextCursor, wantNextCursor)
+	}
+
+	request.Cursor = firstPage.Success.NextCursor
+
+	if secondPage := adapter.GetPage(context.Background(), request); secondPage.Error != nil {
+		t.Errorf("Unexpected error on the second page: %v", secondPage.Error)
+	}
+
+	wantErr := &framework.Error{
+		Message: "Invalid cursor signature. The cursor was not issued by this adapter, or its signing key is no longer accepted. Restart the sync.",
+		Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_PAGE_REQUEST_CONFIG,
+	}
+
+	for _, cursor := range []string{"3", encodeTestCursor(request, &scim.Cursor{PagingMode: scim.PagingModeIndex, StartIndex: 3})} {
+		request.Cursor = cursor
+
+		if gotResponse := adapter.GetPage(context.Background(), request); !reflect.DeepEqual(gotResponse.Error, wantErr) {
+			t.Errorf("gotErr: %v, wantErr: %v", gotResponse.Error, wantErr)
+		}
+	}
+}
@@ -2,6 +2,7 @@
 package scim
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -12,13 +13,28 @@ import (
 
 	framework "github.com/sgnl-ai/adapter-framework"
 	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
+	"github.com/sgnl-ai/sample-adapter/pkg/auth"
 	customerror "github.com/sgnl-ai/sample-adapter/pkg/errors"
+	"github.com/sgnl-ai/sample-adapter/pkg/ratelimit"
+	"github.com/sgnl-ai/sample-adapter/pkg/transport"
 )
 
 // Datasource directly implements a Client interface to allow querying
 // an external datasource.
 type Datasource struct {
 	Client *http.Client
+
+	serviceProviderConfigs discoveryCache[*ServiceProviderConfig]
+
+	schemas discoveryCache[schemasResult]
+
+	rateLimiters ratelimit.Registry
+
+	tokens auth.TokenCache
+
+	tokenFetchers auth.TokenFetcherCache
+
+	transports transport.Registry
 }
 
 type Response struct {
@@ -26,6 +42,7 @@ type Response struct {
 	TotalResults int64            `json:"totalResults"`
 	StartIndex   int64            `json:"startIndex"`
 	ItemsPerPage int64            `json:"itemsPerPage"`
+	NextCursor   string           `json:"nextCursor"`
 }
 
 // NewClient instantiates and returns a new SCIM Client used to query the SCIM datasource.
@@ -37,44 +54,92 @@ func NewClient(client *http.Client) Client {
 
 // GetPage makes a request to the SCIM SoR to get a page of JSON objects. If a response is received,
 // regardless of status code, a Response object is returned with the response body and the status code.
+// If the status code is not 200, the SCIM error returned in the response body, if any, is returned instead
+// of the response body.
 // If the request fails, an appropriate framework.Error is returned.
 func (d *Datasource) GetPage(ctx context.Context, request *Request) (*AdapterResponse, *framework.Error) {
-	cursor := "1"
-	if request.Cursor != "" {
-		cursor = request.Cursor
+	pagingMode := request.PagingMode
+	if pagingMode == "" {
+		pagingMode = PagingModeIndex
 	}
 
-	url := GenerateURL(
-		request.BaseURL,
-		request.EntityExternalID,
-		request.PageSize,
-		cursor,
-		request.QueryParams,
-	)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
+	// The paging mode can only be detected from the response to the first page.
+	// A cursor on a subsequent page must have been generated with a known paging mode.
+	if pagingMode == PagingModeAuto && request.Cursor != "" {
 		return nil, &framework.Error{
-			Message: "Failed to create HTTP request to datasource.",
+			Message: "Cannot request a page with a cursor without a resolved paging mode.",
 			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
 		}
 	}
 
+	cursor := request.Cursor
+	if cursor == "" && pagingMode == PagingModeIndex {
+		cursor = "1"
+	}
+
+	method := http.MethodGet
+	url := GenerateURL(request, cursor)
+	getURL := url
+
+	var requestBody []byte
+
+	// Long URLs, e.g. due to long filters, may be rejected by the SCIM server or by proxies, so
+	// the page is requested with a SearchRequest instead.
+	if request.QueryParams.ForcePostSearch || (request.MaxURLLength > 0 && len(url) > request.MaxURLLength) {
+		searchURL, searchRequest, err := GenerateSearchRequest(request, cursor)
+		if err != nil {
+			return nil, &framework.Error{
+				Message: fmt.Sprintf("Failed to generate SCIM SearchRequest: %v.", err),
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_PAGE_REQUEST_CONFIG,
+			}
+		}
+
+		searchRequestBody, err := json.Marshal(searchRequest)
+		if err != nil {
+			return nil, &framework.Error{
+				Message: fmt.Sprintf("Failed to marshal SCIM SearchRequest: %v.", err),
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			}
+		}
+
+		method = http.MethodPost
+		url = searchURL
+		requestBody = searchRequestBody
+	}
+
 	// Timeout API calls that take longer than the configured timeout.
 	apiCtx, cancel := context.WithTimeout(ctx, time.Duration(request.RequestTimeoutSeconds)*time.Second)
 	defer cancel()
 
-	req = req.WithContext(apiCtx)
-	req.Header.Add("Accept", "application/scim+json")
-	req.Header.Add("Authorization", request.AuthorizationHeader)
+	req, frameworkErr := newPageRequest(apiCtx, request, method, url, requestBody)
+	if frameworkErr != nil {
+		return nil, frameworkErr
+	}
+
+	res, err := d.send(req, request)
+
+	// SearchRequests are optional, so if the SCIM server does not implement them, a page whose URL is too
+	// long is requested with "GET /{resource}" anyway, unless SearchRequests are forced.
+	if err == nil && method == http.MethodPost && !request.QueryParams.ForcePostSearch &&
+		(res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusMethodNotAllowed ||
+			res.StatusCode == http.StatusNotImplemented) {
+		res.Body.Close()
+
+		if req, frameworkErr = newPageRequest(apiCtx, request, http.MethodGet, getURL, nil); frameworkErr != nil {
+			return nil, frameworkErr
+		}
+
+		res, err = d.send(req, request)
+	}
 
-	res, err := d.Client.Do(req)
 	if err != nil {
 		return nil, customerror.UpdateError(&framework.Error{
 			Message: fmt.Sprintf("Failed to execute SCIM request: %v.", err),
 			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
 		},
 			customerror.WithRequestTimeoutMessage(err, request.RequestTimeoutSeconds),
+			WithTokenErrorCode(err),
+			WithConfigErrorCode(err),
 		)
 	}
 
@@ -86,51 +151,248 @@ func (d *Datasource) GetPage(ctx context.Context, request *Request) (*AdapterRes
 	}
 
 	if res.StatusCode != http.StatusOK {
+		// The body of an unsuccessful response may contain a SCIM error describing the cause.
+		// Failures to read it are ignored, as the status code is enough to report the error.
+		body, _ := io.ReadAll(io.LimitReader(res.Body, MaxErrorResponseBodyBytes))
+		response.ErrorResponse = ParseErrorResponse(body)
+
 		return response, nil
 	}
 
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, &framework.Error{
-			Message: "Failed to read response body.",
-			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_FAILED,
-		}
+	body := NewMaxBytesReader(res.Body, request.MaxResponseBodyBytes)
+
+	// The start index is validated by the SCIM server, so an invalid start index is not checked against
+	// the response.
+	var startIndex int64
+	if pagingMode == PagingModeIndex {
+		startIndex, _ = strconv.ParseInt(cursor, 10, 64)
 	}
 
-	objects, nextCursor, frameworkErr := ParseResponse(body, request.PageSize)
+	objects, totalResults, nextCursor, resolvedPagingMode, frameworkErr := ParseResponse(
+		body, request.PageSize, pagingMode, startIndex,
+	)
 	if frameworkErr != nil {
 		return nil, frameworkErr
 	}
 
 	response.Objects = objects
+	response.TotalResults = totalResults
 	response.NextCursor = nextCursor
+	response.PagingMode = resolvedPagingMode
 
 	return response, nil
 }
 
-func ParseResponse(body []byte, pageSize int64) (objects []map[string]any, nextCursor string, err *framework.Error) {
-	var scimResponse *Response
+// send sends an HTTP request to the datasource, and retries it according to the request's retry policy.
+// Each attempt waits for the rate limiter of the datasource address and credential.
+// All the attempts must complete within the deadline of the HTTP request's context.
+//
+// If the request has a TLS config, the request is sent with the HTTP client of the datasource address and
+// TLS config, and access tokens are requested with the HTTP client of the token URL and the TLS config
+// returned by transport.TokenEndpointConfig.
+//
+// If the request is authenticated with OAuth2 access tokens, the Authorization header is set to the cached
+// access token, and the request is sent again once with a new access token if the datasource responds with
+// 401 Unauthorized, as the access token may have been revoked before its expiry.
+func (d *Datasource) send(req *http.Request, request *Request) (*http.Response, error) {
+	client, err := d.transports.Client(request.BaseURL, d.Client, request.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.OAuth2 == nil {
+		return d.sendAttempts(client, req, request, ratelimit.Key(request.BaseURL, request.AuthorizationHeader))
+	}
+
+	tokenClient, err := d.transports.Client(request.OAuth2.TokenURL, d.Client, transport.TokenEndpointConfig(request.TLS))
+	if err != nil {
+		return nil, err
+	}
+
+	tokenSource, err := d.tokenSource(tokenClient, request)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimitKey := ratelimit.Key(request.BaseURL, tokenSource.Key)
 
-	if unmarshalErr := json.Unmarshal(body, &scimResponse); unmarshalErr != nil {
-		return nil, "", &framework.Error{
-			Message: fmt.Sprintf("Failed to unmarshal the datasource response: %v.", unmarshalErr),
+	token, err := tokenSource.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", token.AuthorizationHeader())
+
+	res, err := d.sendAttempts(client, req, request, rateLimitKey)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	res.Body.Close()
+
+	tokenSource.Invalidate(token)
+
+	if token, err = tokenSource.Token(req.Context()); err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", token.AuthorizationHeader())
+
+	return d.sendAttempts(client, req, request, rateLimitKey)
+}
+
+// tokenSource returns the source of the OAuth2 access tokens of a request, cached per datasource address and
+// OAuth2 client credentials. Access tokens are requested with the provided HTTP client, by a TokenFetcher also
+// cached per datasource address and OAuth2 client credentials, so that the private key of JWT assertions is only
+// parsed once. Errors caused by an invalid OAuth2 config are returned as *auth.ConfigError.
+func (d *Datasource) tokenSource(client *http.Client, request *Request) (*auth.TokenSource, error) {
+	key := auth.TokenCacheKey(request.BaseURL, request.OAuth2)
+
+	fetch, err := d.tokenFetchers.Fetcher(key, client, request.OAuth2)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth.TokenSource{
+		Key:   key,
+		Cache: &d.tokens,
+		Fetch: fetch,
+	}, nil
+}
+
+// sendAttempts sends an HTTP request to the datasource with the provided HTTP client, and retries it
+// according to the request's retry policy. Each attempt waits for the rate limiter of the provided key.
+func (d *Datasource) sendAttempts(
+	client *http.Client, req *http.Request, request *Request, rateLimitKey string,
+) (*http.Response, error) {
+	return request.RetryPolicy.Do(req.Context(), func() (*http.Response, error) {
+		release, err := d.rateLimiters.Wait(req.Context(), rateLimitKey, request.RateLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		attemptReq := req.Clone(req.Context())
+
+		// The body of the request must be read again for each attempt.
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				release()
+
+				return nil, err
+			}
+
+			attemptReq.Body = body
+		}
+
+		res, err := client.Do(attemptReq)
+		if err != nil {
+			release()
+
+			// Certificate verification errors name the certificate chain presented by the datasource.
+			return nil, transport.WrapVerificationError(err)
+		}
+
+		// The request is in flight until its response body is closed.
+		res.Body = &releaseOnCloseBody{ReadCloser: res.Body, release: release}
+
+		return res, nil
+	})
+}
+
+// releaseOnCloseBody is a response body which releases the rate limiter's concurrency slot of the request
+// when closed.
+type releaseOnCloseBody struct {
+	io.ReadCloser
+
+	release func()
+}
+
+func (b *releaseOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+
+	b.release()
+
+	return err
+}
+
+// newPageRequest returns the HTTP request for a page of resources, with the SearchRequest body, if any.
+func newPageRequest(
+	ctx context.Context, request *Request, method string, url string, body []byte,
+) (*http.Request, *framework.Error) {
+	var requestBody io.Reader
+	if body != nil {
+		requestBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, requestBody)
+	if err != nil {
+		return nil, &framework.Error{
+			Message: "Failed to create HTTP request to datasource.",
 			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
 		}
 	}
 
-	if scimResponse.ItemsPerPage > pageSize {
-		return nil, "", &framework.Error{
-			Message: fmt.Sprintf("SCIM SoR returned more than the requested page size: %v.", scimResponse.ItemsPerPage),
-			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_FAILED,
+	req.Header.Add("Accept", "application/scim+json")
+	req.Header.Add("Authorization", request.AuthorizationHeader)
+
+	if method == http.MethodPost {
+		req.Header.Add("Content-Type", "application/scim+json")
+	}
+
+	return req, nil
+}
+
+// GetGroupMembers makes a request to the SCIM SoR to get the `members` attribute of a single group.
+// If the response status code is not successful, an appropriate framework.Error is returned.
+func (d *Datasource) GetGroupMembers(ctx context.Context, request *Request, groupID string) ([]any, *framework.Error) {
+	url := GenerateResourceURL(request.BaseURL, request.EntityExternalID, groupID, GroupMembersAttribute)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, &framework.Error{
+			Message: "Failed to create HTTP request to datasource.",
+			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
 		}
 	}
 
-	nextCursor = ""
+	// Timeout API calls that take longer than the configured timeout.
+	apiCtx, cancel := context.WithTimeout(ctx, time.Duration(request.RequestTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	req = req.WithContext(apiCtx)
+	req.Header.Add("Accept", "application/scim+json")
+	req.Header.Add("Authorization", request.AuthorizationHeader)
+
+	res, err := d.send(req, request)
+	if err != nil {
+		return nil, customerror.UpdateError(&framework.Error{
+			Message: fmt.Sprintf("Failed to execute SCIM request: %v.", err),
+			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+		},
+			customerror.WithRequestTimeoutMessage(err, request.RequestTimeoutSeconds),
+			WithTokenErrorCode(err),
+			WithConfigErrorCode(err),
+		)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, MaxErrorResponseBodyBytes))
+
+		return nil, HTTPError(res.StatusCode, res.Header.Get("Retry-After"), ParseErrorResponse(body))
+	}
+
+	var group struct {
+		Members []any `json:"members"`
+	}
+
+	body := NewMaxBytesReader(res.Body, request.MaxResponseBodyBytes)
 
-	nextStartIndex := scimResponse.StartIndex + scimResponse.ItemsPerPage
-	if nextStartIndex <= scimResponse.TotalResults {
-		nextCursor = strconv.FormatInt(nextStartIndex, 10)
+	if decodeErr := json.NewDecoder(body).Decode(&group); decodeErr != nil {
+		return nil, responseDecodeError(decodeErr)
 	}
 
-	return scimResponse.Resources, nextCursor, nil
+	return group.Members, nil
 }
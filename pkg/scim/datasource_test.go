@@ -3,6 +3,7 @@ package scim_test
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	framework "github.com/sgnl-ai/adapter-framework"
+	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
 	"github.com/sgnl-ai/sample-adapter/pkg/scim"
 	"github.com/sgnl-ai/sample-adapter/pkg/testutil"
 )
@@ -43,7 +45,9 @@ func TestUserGetPage(t *testing.T) {
 				PageSize:         2,
 			},
 			wantRes: &scim.AdapterResponse{
-				StatusCode: http.StatusOK,
+				StatusCode:   http.StatusOK,
+				PagingMode:   scim.PagingModeIndex,
+				TotalResults: 5,
 				Objects: []map[string]interface{}{
 					{"id": "2819c223-7f76-453a-919d-413861904646", "userName": "Alex"},
 					{"id": "c75ad752-64ae-4823-840d-ffa80929976c", "userName": "Bacong"},
@@ -63,7 +67,9 @@ func TestUserGetPage(t *testing.T) {
 				Cursor:           "3",
 			},
 			wantRes: &scim.AdapterResponse{
-				StatusCode: http.StatusOK,
+				StatusCode:   http.StatusOK,
+				PagingMode:   scim.PagingModeIndex,
+				TotalResults: 5,
 				Objects: []map[string]interface{}{
 					{"id": "e2be737c-61f5-4abe-8797-1e816b15cec8", "userName": "Carol"},
 					{"id": "89fa657e-3ef5-49e3-bb34-b3255e04a8bb", "userName": "David"},
@@ -83,7 +89,9 @@ func TestUserGetPage(t *testing.T) {
 				Cursor:           "5",
 			},
 			wantRes: &scim.AdapterResponse{
-				StatusCode: http.StatusOK,
+				StatusCode:   http.StatusOK,
+				PagingMode:   scim.PagingModeIndex,
+				TotalResults: 5,
 				Objects: []map[string]interface{}{
 					{
 						"schemas": []interface{}{
@@ -258,7 +266,7 @@ func TestUserGetPage(t *testing.T) {
 	}
 }
 
-func TestGroupGetPage(t *testing.T) {
+func TestUserGetPageCursorPaging(t *testing.T) {
 	scimClient := scim.NewClient(&http.Client{
 		Timeout: time.Duration(60) * time.Second,
 	})
@@ -273,6 +281,91 @@ func TestGroupGetPage(t *testing.T) {
 		wantErr *framework.Error
 	}{
 		"first_page": {
+			context: context.Background(),
+			request: &scim.Request{
+				BaseURL:               server.URL,
+				RequestTimeoutSeconds: 5,
+
+				EntityExternalID: scimUser,
+				PageSize:         2,
+				PagingMode:       scim.PagingModeCursor,
+			},
+			wantRes: &scim.AdapterResponse{
+				StatusCode: http.StatusOK,
+				Objects: []map[string]interface{}{
+					{"id": "2819c223-7f76-453a-919d-413861904646", "userName": "Alex"},
+					{"id": "c75ad752-64ae-4823-840d-ffa80929976c", "userName": "Bacong"},
+				},
+				NextCursor:   "VZUTiyhEQJ94IR",
+				PagingMode:   scim.PagingModeCursor,
+				TotalResults: 5,
+			},
+		},
+		"middle_page": {
+			context: context.Background(),
+			request: &scim.Request{
+				BaseURL:               server.URL,
+				RequestTimeoutSeconds: 5,
+
+				EntityExternalID: scimUser,
+				PageSize:         2,
+				PagingMode:       scim.PagingModeCursor,
+				Cursor:           "VZUTiyhEQJ94IR",
+			},
+			wantRes: &scim.AdapterResponse{
+				StatusCode: http.StatusOK,
+				Objects: []map[string]interface{}{
+					{"id": "e2be737c-61f5-4abe-8797-1e816b15cec8", "userName": "Carol"},
+					{"id": "89fa657e-3ef5-49e3-bb34-b3255e04a8bb", "userName": "David"},
+				},
+				NextCursor:   "YkU3OF86Pz0rGv",
+				PagingMode:   scim.PagingModeCursor,
+				TotalResults: 5,
+			},
+		},
+		"last_page": {
+			context: context.Background(),
+			request: &scim.Request{
+				BaseURL:               server.URL,
+				RequestTimeoutSeconds: 5,
+
+				EntityExternalID: scimUser,
+				PageSize:         2,
+				PagingMode:       scim.PagingModeCursor,
+				Cursor:           "YkU3OF86Pz0rGv",
+			},
+			wantRes: &scim.AdapterResponse{
+				StatusCode: http.StatusOK,
+				Objects: []map[string]interface{}{
+					{"id": "2819c223-7f76-453a-919d-413861904000", "userName": "bjensen@example.com"},
+				},
+				NextCursor:   "",
+				PagingMode:   scim.PagingModeCursor,
+				TotalResults: 5,
+			},
+		},
+		"auto_detects_cursor_paging": {
+			context: context.Background(),
+			request: &scim.Request{
+				BaseURL:               server.URL,
+				RequestTimeoutSeconds: 5,
+
+				EntityExternalID: scimUser,
+				PageSize:         2,
+				PagingMode:       scim.PagingModeAuto,
+			},
+			wantRes: &scim.AdapterResponse{
+				StatusCode: http.StatusOK,
+				Objects: []map[string]interface{}{
+					{"id": "2819c223-7f76-453a-919d-413861904646", "userName": "Alex"},
+					{"id": "c75ad752-64ae-4823-840d-ffa80929976c", "userName": "Bacong"},
+				},
+				NextCursor:   "VZUTiyhEQJ94IR",
+				PagingMode:   scim.PagingModeCursor,
+				TotalResults: 5,
+			},
+		},
+		"auto_detects_index_paging": {
 			context: context.Background(),
 			request: &scim.Request{
 				BaseURL:               server.URL,
@@ -280,6 +373,7 @@ func TestGroupGetPage(t *testing.T) {
 
 				EntityExternalID: scimGroup,
 				PageSize:         2,
+				PagingMode:       scim.PagingModeAuto,
 			},
 			wantRes: &scim.AdapterResponse{
 				StatusCode: http.StatusOK,
@@ -300,6 +394,72 @@ func TestGroupGetPage(t *testing.T) {
 						"displayName": "Group B",
 					},
 				},
+				NextCursor:   "3",
+				PagingMode:   scim.PagingModeIndex,
+				TotalResults: 5,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotRes, gotErr := scimClient.GetPage(tt.context, tt.request)
+
+			if !reflect.DeepEqual(gotRes, tt.wantRes) {
+				t.Errorf("gotRes: %+v, wantRes: %+v", gotRes, tt.wantRes)
+			}
+
+			if !reflect.DeepEqual(gotErr, tt.wantErr) {
+				t.Errorf("gotErr: %v, wantErr: %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGroupGetPage(t *testing.T) {
+	scimClient := scim.NewClient(&http.Client{
+		Timeout: time.Duration(60) * time.Second,
+	})
+
+	server := httptest.NewServer(TestServerHandler)
+	defer server.Close()
+
+	tests := map[string]struct {
+		context context.Context
+		request *scim.Request
+		wantRes *scim.AdapterResponse
+		wantErr *framework.Error
+	}{
+		"first_page": {
+			context: context.Background(),
+			request: &scim.Request{
+				BaseURL:               server.URL,
+				RequestTimeoutSeconds: 5,
+
+				EntityExternalID: scimGroup,
+				PageSize:         2,
+			},
+			wantRes: &scim.AdapterResponse{
+				StatusCode:   http.StatusOK,
+				PagingMode:   scim.PagingModeIndex,
+				TotalResults: 5,
+				Objects: []map[string]interface{}{
+					{
+						"id":          "c3a26dd3-27a0-4dec-a2ac-ce211e105f97",
+						"displayName": "Group A",
+						"members": []interface{}{
+							map[string]interface{}{
+								"value":   "6c5bb468-14b2-4183-baf2-06d523e03bd3",
+								"$ref":    "https://example.com/v2/Groups/6c5bb468-14b2-4183-baf2-06d523e03bd3",
+								"display": "Group",
+							},
+						},
+					},
+					{
+						"id":          "6c5bb468-14b2-4183-baf2-06d523e03bd3",
+						"displayName": "Group B",
+					},
+				},
 				NextCursor: "3",
 			},
 			wantErr: nil,
@@ -315,7 +475,9 @@ func TestGroupGetPage(t *testing.T) {
 				Cursor:           "3",
 			},
 			wantRes: &scim.AdapterResponse{
-				StatusCode: http.StatusOK,
+				StatusCode:   http.StatusOK,
+				PagingMode:   scim.PagingModeIndex,
+				TotalResults: 5,
 				Objects: []map[string]interface{}{
 					{
 						"id":          "e2be737c-61f5-4abe-8797-1e816b15cec8",
@@ -348,7 +510,9 @@ func TestGroupGetPage(t *testing.T) {
 				Cursor:           "5",
 			},
 			wantRes: &scim.AdapterResponse{
-				StatusCode: http.StatusOK,
+				StatusCode:   http.StatusOK,
+				PagingMode:   scim.PagingModeIndex,
+				TotalResults: 5,
 				Objects: []map[string]interface{}{
 					{
 						"id":          "e9e30dba-f08f-4109-8486-d5c6a331660a",
@@ -463,17 +627,74 @@ func TestConstructURL(t *testing.T) {
 				`sortBy=displayName&` +
 				`sortOrder=ascending`,
 		},
+		"groupsExcludedAttributes": {
+			request: &scim.Request{
+				BaseURL: "https://scim.com",
+
+				PageSize:           10,
+				EntityExternalID:   scimGroup,
+				ExcludedAttributes: []string{"members", "meta"},
+			},
+			cursor:  "1",
+			wantURL: "https://scim.com/Groups?startIndex=1&count=10&excludedAttributes=members%2Cmeta",
+		},
+		"usersAttributes": {
+			request: &scim.Request{
+				BaseURL: "https://scim.com",
+
+				PageSize:           10,
+				EntityExternalID:   scimUser,
+				Attributes:         []string{"id", "name.givenName", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber"},
+				ExcludedAttributes: []string{"groups"},
+			},
+			cursor: "1",
+			wantURL: "https://scim.com/Users?startIndex=1&count=10&" +
+				"attributes=id%2Cname.givenName%2Curn%3Aietf%3Aparams%3Ascim%3Aschemas%3Aextension%3Aenterprise%3A2.0%3AUser%3AemployeeNumber",
+		},
+		"users_cursor_paging_first_page": {
+			request: &scim.Request{
+				BaseURL: "https://scim.com",
+
+				PageSize:         10,
+				EntityExternalID: scimUser,
+				PagingMode:       scim.PagingModeCursor,
+			},
+			cursor:  "",
+			wantURL: "https://scim.com/Users?cursor&count=10",
+		},
+		"users_cursor_paging": {
+			request: &scim.Request{
+				BaseURL: "https://scim.com",
+
+				PageSize:         10,
+				EntityExternalID: scimUser,
+				PagingMode:       scim.PagingModeCursor,
+				QueryParams: scim.QueryParams{
+					Filter: `displayName eq "SGNL"`,
+				},
+			},
+			cursor: "VZUTiy+hEQJ94IR=&startIndex=1",
+			wantURL: `https://scim.com/Users?` +
+				`cursor=VZUTiy%2BhEQJ94IR%3D%26startIndex%3D1&` +
+				`count=10&` +
+				`filter=displayName+eq+%22SGNL%22`,
+		},
+		"users_auto_paging_first_page": {
+			request: &scim.Request{
+				BaseURL: "https://scim.com",
+
+				PageSize:         10,
+				EntityExternalID: scimUser,
+				PagingMode:       scim.PagingModeAuto,
+			},
+			cursor:  "",
+			wantURL: "https://scim.com/Users?cursor&count=10",
+		},
 	}
 
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			gotURL := scim.GenerateURL(
-				tt.request.BaseURL,
-				tt.request.EntityExternalID,
-				tt.request.PageSize,
-				tt.cursor,
-				tt.request.QueryParams,
-			)
+			gotURL := scim.GenerateURL(tt.request, tt.cursor)
 
 			if !reflect.DeepEqual(gotURL, tt.wantURL) {
 				t.Errorf("gotURL: %v, wantURL: %v", gotURL, tt.wantURL)
@@ -481,3 +702,233 @@ func TestConstructURL(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPageSearchRequest(t *testing.T) {
+	tests := map[string]struct {
+		request          *scim.Request
+		searchStatusCode int
+		wantStatusCode   int
+		wantMethod       string
+		wantRequestURI   string
+		wantContentType  string
+		wantBody         map[string]any
+		wantErr          *framework.Error
+	}{
+		"forced_search_request": {
+			request: &scim.Request{
+				EntityExternalID: scimUser,
+				PageSize:         2,
+				Cursor:           "3",
+				Attributes:       []string{"id", "userName"},
+				QueryParams: scim.QueryParams{
+					Filter:          `userName sw "A"`,
+					SortBy:          "userName",
+					Ascending:       testutil.GenPtr(false),
+					ForcePostSearch: true,
+				},
+			},
+			wantMethod:      http.MethodPost,
+			wantRequestURI:  "/Users/.search",
+			wantContentType: "application/scim+json",
+			wantBody: map[string]any{
+				"schemas":    []any{"urn:ietf:params:scim:api:messages:2.0:SearchRequest"},
+				"attributes": []any{"id", "userName"},
+				"filter":     `userName sw "A"`,
+				"sortBy":     "userName",
+				"sortOrder":  "descending",
+				"startIndex": float64(3),
+				"count":      float64(2),
+			},
+		},
+		"url_exceeds_max_length": {
+			request: &scim.Request{
+				EntityExternalID:   scimGroup,
+				PageSize:           2,
+				ExcludedAttributes: []string{"members"},
+				MaxURLLength:       64,
+				QueryParams: scim.QueryParams{
+					Filter: `displayName eq "Group A" or displayName eq "Group B" or displayName eq "Group C"`,
+				},
+			},
+			wantMethod:      http.MethodPost,
+			wantRequestURI:  "/Groups/.search",
+			wantContentType: "application/scim+json",
+			wantBody: map[string]any{
+				"schemas":            []any{"urn:ietf:params:scim:api:messages:2.0:SearchRequest"},
+				"excludedAttributes": []any{"members"},
+				"filter":             `displayName eq "Group A" or displayName eq "Group B" or displayName eq "Group C"`,
+				"startIndex":         float64(1),
+				"count":              float64(2),
+			},
+		},
+		"search_request_not_implemented_falls_back_to_get": {
+			request: &scim.Request{
+				EntityExternalID: scimGroup,
+				PageSize:         2,
+				MaxURLLength:     64,
+				QueryParams: scim.QueryParams{
+					Filter: `displayName eq "Group A" or displayName eq "Group B"`,
+				},
+			},
+			searchStatusCode: http.StatusNotImplemented,
+			wantStatusCode:   http.StatusOK,
+			wantMethod:       http.MethodGet,
+			wantRequestURI:   "/Groups?startIndex=1&count=2&filter=displayName+eq+%22Group+A%22+or+displayName+eq+%22Group+B%22",
+			wantBody: map[string]any{
+				"schemas":    []any{"urn:ietf:params:scim:api:messages:2.0:SearchRequest"},
+				"filter":     `displayName eq "Group A" or displayName eq "Group B"`,
+				"startIndex": float64(1),
+				"count":      float64(2),
+			},
+		},
+		"search_request_not_found_falls_back_to_get": {
+			request: &scim.Request{
+				EntityExternalID: scimGroup,
+				PageSize:         2,
+				MaxURLLength:     64,
+				QueryParams: scim.QueryParams{
+					Filter: `displayName eq "Group A" or displayName eq "Group B"`,
+				},
+			},
+			searchStatusCode: http.StatusNotFound,
+			wantStatusCode:   http.StatusOK,
+			wantMethod:       http.MethodGet,
+			wantRequestURI:   "/Groups?startIndex=1&count=2&filter=displayName+eq+%22Group+A%22+or+displayName+eq+%22Group+B%22",
+			wantBody: map[string]any{
+				"schemas":    []any{"urn:ietf:params:scim:api:messages:2.0:SearchRequest"},
+				"filter":     `displayName eq "Group A" or displayName eq "Group B"`,
+				"startIndex": float64(1),
+				"count":      float64(2),
+			},
+		},
+		"forced_search_request_not_implemented": {
+			request: &scim.Request{
+				EntityExternalID: scimUser,
+				PageSize:         2,
+				QueryParams: scim.QueryParams{
+					ForcePostSearch: true,
+				},
+			},
+			searchStatusCode: http.StatusNotImplemented,
+			wantStatusCode:   http.StatusNotImplemented,
+			wantMethod:       http.MethodPost,
+			wantRequestURI:   "/Users/.search",
+			wantContentType:  "application/scim+json",
+			wantBody: map[string]any{
+				"schemas":    []any{"urn:ietf:params:scim:api:messages:2.0:SearchRequest"},
+				"startIndex": float64(1),
+				"count":      float64(2),
+			},
+		},
+		"url_within_max_length": {
+			request: &scim.Request{
+				EntityExternalID: scimGroup,
+				PageSize:         2,
+				MaxURLLength:     2048,
+				QueryParams: scim.QueryParams{
+					Filter: `displayName eq "Group A"`,
+				},
+			},
+			wantMethod:     http.MethodGet,
+			wantRequestURI: "/Groups?startIndex=1&count=2&filter=displayName+eq+%22Group+A%22",
+		},
+		"forced_search_request_cursor_paging_first_page": {
+			request: &scim.Request{
+				EntityExternalID: scimUser,
+				PageSize:         2,
+				PagingMode:       scim.PagingModeCursor,
+				QueryParams: scim.QueryParams{
+					ForcePostSearch: true,
+				},
+			},
+			wantMethod:      http.MethodPost,
+			wantRequestURI:  "/Users/.search",
+			wantContentType: "application/scim+json",
+			wantBody: map[string]any{
+				"schemas": []any{"urn:ietf:params:scim:api:messages:2.0:SearchRequest"},
+				"cursor":  "",
+				"count":   float64(2),
+			},
+		},
+		"forced_search_request_invalid_start_index": {
+			request: &scim.Request{
+				EntityExternalID: scimUser,
+				PageSize:         2,
+				Cursor:           "3&filter=",
+				QueryParams: scim.QueryParams{
+					ForcePostSearch: true,
+				},
+			},
+			wantErr: &framework.Error{
+				Message: `Failed to generate SCIM SearchRequest: invalid start index "3&filter=": strconv.ParseInt: parsing "3&filter=": invalid syntax.`,
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_PAGE_REQUEST_CONFIG,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var (
+				gotMethod      string
+				gotRequestURI  string
+				gotContentType string
+				gotBody        map[string]any
+			)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotMethod = r.Method
+				gotRequestURI = r.URL.RequestURI()
+				gotContentType = r.Header.Get("Content-Type")
+
+				if r.Method == http.MethodPost {
+					if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+						t.Errorf("Failed to decode SearchRequest: %v", err)
+					}
+
+					if tt.searchStatusCode != 0 {
+						w.WriteHeader(tt.searchStatusCode)
+
+						return
+					}
+				}
+
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{
+					"schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+					"totalResults": 0,
+					"Resources": []
+				}`))
+			}))
+			defer server.Close()
+
+			tt.request.BaseURL = server.URL
+			tt.request.RequestTimeoutSeconds = 5
+
+			gotResponse, gotErr := scim.NewClient(server.Client()).GetPage(context.Background(), tt.request)
+
+			if !reflect.DeepEqual(gotErr, tt.wantErr) {
+				t.Errorf("gotErr: %v, wantErr: %v", gotErr, tt.wantErr)
+			}
+
+			if tt.wantStatusCode != 0 && gotResponse.StatusCode != tt.wantStatusCode {
+				t.Errorf("gotStatusCode: %v, wantStatusCode: %v", gotResponse.StatusCode, tt.wantStatusCode)
+			}
+
+			if gotMethod != tt.wantMethod {
+				t.Errorf("gotMethod: %v, wantMethod: %v", gotMethod, tt.wantMethod)
+			}
+
+			if gotRequestURI != tt.wantRequestURI {
+				t.Errorf("gotRequestURI: %v, wantRequestURI: %v", gotRequestURI, tt.wantRequestURI)
+			}
+
+			if gotContentType != tt.wantContentType {
+				t.Errorf("gotContentType: %v, wantContentType: %v", gotContentType, tt.wantContentType)
+			}
+
+			if !reflect.DeepEqual(gotBody, tt.wantBody) {
+				t.Errorf("gotBody: %v, wantBody: %v", gotBody, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2025 SGNL.ai, Inc.
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
+	customerror "github.com/sgnl-ai/sample-adapter/pkg/errors"
+)
+
+// ServiceProviderConfigCacheTTL is the duration for which the ServiceProviderConfig of a SCIM server
+// is cached before being fetched again.
+var ServiceProviderConfigCacheTTL = time.Hour
+
+// ServiceProviderConfigFailureCacheTTL is the duration for which a SCIM server's ServiceProviderConfig is
+// considered unknown after an unsuccessful response which may be transient, e.g. 401, 403, 429 or 5xx,
+// before being fetched again.
+var ServiceProviderConfigFailureCacheTTL = time.Minute
+
+// ServiceProviderConfig is the subset of a SCIM server's ServiceProviderConfig resource used by the adapter.
+// https://datatracker.ietf.org/doc/html/rfc7643#section-5
+type ServiceProviderConfig struct {
+	// Filter describes the filtering capabilities of the SCIM server.
+	Filter FilterCapability `json:"filter"`
+
+	// Sort describes the sorting capabilities of the SCIM server.
+	Sort SortCapability `json:"sort"`
+}
+
+// FilterCapability describes the filtering capabilities of a SCIM server.
+type FilterCapability struct {
+	// Supported is true if the SCIM server supports the "filter" query parameter.
+	Supported bool `json:"supported"`
+
+	// MaxResults is the maximum number of resources returned in a response.
+	MaxResults int64 `json:"maxResults"`
+}
+
+// SortCapability describes the sorting capabilities of a SCIM server.
+type SortCapability struct {
+	// Supported is true if the SCIM server supports the "sortBy" and "sortOrder" query parameters.
+	Supported bool `json:"supported"`
+}
+
+// discoveryCache caches a discovery resource of each SCIM server, e.g. its ServiceProviderConfig,
+// keyed by the datasource address.
+type discoveryCache[T any] struct {
+	mu      sync.Mutex
+	entries map[string]discoveryCacheEntry[T]
+}
+
+type discoveryCacheEntry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+func (c *discoveryCache[T]) get(address string) (value T, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.entries[address]
+	if found && time.Now().After(entry.expiresAt) {
+		delete(c.entries, address)
+
+		return value, false
+	}
+
+	return entry.value, found
+}
+
+func (c *discoveryCache[T]) set(address string, value T, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]discoveryCacheEntry[T])
+	}
+
+	c.entries[address] = discoveryCacheEntry[T]{
+		value:     value,
+		expiresAt: time.Now().Add(ttl),
+	}
+}
+
+// GetServiceProviderConfig returns the ServiceProviderConfig of the SCIM server at the request's BaseURL.
+// Responses are cached for ServiceProviderConfigCacheTTL for each BaseURL.
+// If the SCIM server does not successfully return a ServiceProviderConfig, nil is returned without error,
+// and the server's capabilities are considered unknown. nil is cached for ServiceProviderConfigCacheTTL if the
+// SCIM server does not implement the endpoint (404 or 501), or returns an invalid ServiceProviderConfig.
+// Other unsuccessful responses, e.g. 401, 403, 429 or 5xx, may be transient, so nil is only cached for
+// ServiceProviderConfigFailureCacheTTL, which still avoids requesting it again for every page.
+// If the request fails, an appropriate framework.Error is returned and nothing is cached.
+func (d *Datasource) GetServiceProviderConfig(
+	ctx context.Context,
+	request *Request,
+) (*ServiceProviderConfig, *framework.Error) {
+	// The cached config is nil if the SCIM server does not expose a ServiceProviderConfig.
+	if config, found := d.serviceProviderConfigs.get(request.BaseURL); found {
+		return config, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, request.BaseURL+"/ServiceProviderConfig", nil)
+	if err != nil {
+		return nil, &framework.Error{
+			Message: "Failed to create HTTP request to datasource.",
+			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+		}
+	}
+
+	// Timeout API calls that take longer than the configured timeout.
+	apiCtx, cancel := context.WithTimeout(ctx, time.Duration(request.RequestTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	req = req.WithContext(apiCtx)
+	req.Header.Add("Accept", "application/scim+json")
+	req.Header.Add("Authorization", request.AuthorizationHeader)
+
+	res, err := d.send(req, request)
+	if err != nil {
+		return nil, customerror.UpdateError(&framework.Error{
+			Message: fmt.Sprintf("Failed to execute SCIM ServiceProviderConfig request: %v.", err),
+			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+		},
+			customerror.WithRequestTimeoutMessage(err, request.RequestTimeoutSeconds),
+			WithTokenErrorCode(err),
+			WithConfigErrorCode(err),
+		)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		// The ServiceProviderConfig endpoint is not implemented by all SCIM servers.
+		// Other failures may be transient, so they are cached for a shorter time.
+		ttl := ServiceProviderConfigFailureCacheTTL
+		if res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusNotImplemented {
+			ttl = ServiceProviderConfigCacheTTL
+		}
+
+		d.serviceProviderConfigs.set(request.BaseURL, nil, ttl)
+
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(NewMaxBytesReader(res.Body, request.MaxResponseBodyBytes))
+	if err != nil {
+		return nil, responseDecodeError(err)
+	}
+
+	var config *ServiceProviderConfig
+
+	if unmarshalErr := json.Unmarshal(body, &config); unmarshalErr != nil {
+		d.serviceProviderConfigs.set(request.BaseURL, nil, ServiceProviderConfigCacheTTL)
+
+		return nil, nil
+	}
+
+	d.serviceProviderConfigs.set(request.BaseURL, config, ServiceProviderConfigCacheTTL)
+
+	return config, nil
+}
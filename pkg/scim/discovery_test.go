@@ -0,0 +1,338 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// nolint: lll
+package scim_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
+	"github.com/sgnl-ai/sample-adapter/pkg/scim"
+)
+
+// newServiceProviderConfigHandler returns a handler that serves the provided ServiceProviderConfig
+// and delegates any other request to TestServerHandler.
+// The number of ServiceProviderConfig requests is counted in the provided counter.
+func newServiceProviderConfigHandler(serviceProviderConfig string, count *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ServiceProviderConfig" {
+			TestServerHandler(w, r)
+
+			return
+		}
+
+		count.Add(1)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(serviceProviderConfig))
+	})
+}
+
+func TestGetServiceProviderConfig(t *testing.T) {
+	tests := map[string]struct {
+		handler    http.Handler
+		wantConfig *scim.ServiceProviderConfig
+		wantErr    *framework.Error
+	}{
+		"full_config": {
+			handler: newServiceProviderConfigHandler(`{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"],
+				"patch": {"supported": true},
+				"bulk": {"supported": true, "maxOperations": 1000, "maxPayloadSize": 1048576},
+				"filter": {"supported": true, "maxResults": 200},
+				"changePassword": {"supported": true},
+				"sort": {"supported": true},
+				"etag": {"supported": true}
+			}`, &atomic.Int32{}),
+			wantConfig: &scim.ServiceProviderConfig{
+				Filter: scim.FilterCapability{Supported: true, MaxResults: 200},
+				Sort:   scim.SortCapability{Supported: true},
+			},
+		},
+		"unsupported_filter_and_sort": {
+			handler: newServiceProviderConfigHandler(`{
+				"filter": {"supported": false, "maxResults": 0},
+				"sort": {"supported": false}
+			}`, &atomic.Int32{}),
+			wantConfig: &scim.ServiceProviderConfig{},
+		},
+		"not_implemented": {
+			handler:    TestServerHandler,
+			wantConfig: nil,
+		},
+		"invalid_json": {
+			handler:    newServiceProviderConfigHandler(`{"filter": `, &atomic.Int32{}),
+			wantConfig: nil,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			scimClient := scim.NewClient(server.Client())
+
+			gotConfig, gotErr := scimClient.GetServiceProviderConfig(context.Background(), &scim.Request{
+				BaseURL:               server.URL,
+				RequestTimeoutSeconds: 5,
+			})
+
+			if !reflect.DeepEqual(gotConfig, tt.wantConfig) {
+				t.Errorf("gotConfig: %+v, wantConfig: %+v", gotConfig, tt.wantConfig)
+			}
+
+			if !reflect.DeepEqual(gotErr, tt.wantErr) {
+				t.Errorf("gotErr: %v, wantErr: %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetServiceProviderConfigIsCachedPerAddress(t *testing.T) {
+	var count atomic.Int32
+
+	server := httptest.NewServer(newServiceProviderConfigHandler(`{"filter": {"supported": true}}`, &count))
+	defer server.Close()
+
+	scimClient := scim.NewClient(server.Client())
+
+	for range 3 {
+		if _, err := scimClient.GetServiceProviderConfig(context.Background(), &scim.Request{
+			BaseURL:               server.URL,
+			RequestTimeoutSeconds: 5,
+		}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if got := count.Load(); got != 1 {
+		t.Errorf("got %d ServiceProviderConfig requests, want 1", got)
+	}
+}
+
+func TestGetServiceProviderConfigUnsuccessfulResponses(t *testing.T) {
+	tests := map[string]struct {
+		statusCode int
+		// failureCacheTTL, if set, replaces ServiceProviderConfigFailureCacheTTL, and is waited for between requests.
+		failureCacheTTL time.Duration
+		wantRequests    int32
+	}{
+		"not_found_is_cached": {
+			statusCode:   http.StatusNotFound,
+			wantRequests: 1,
+		},
+		"not_implemented_is_cached": {
+			statusCode:   http.StatusNotImplemented,
+			wantRequests: 1,
+		},
+		"unauthorized_is_cached": {
+			statusCode:   http.StatusUnauthorized,
+			wantRequests: 1,
+		},
+		"forbidden_is_cached": {
+			statusCode:   http.StatusForbidden,
+			wantRequests: 1,
+		},
+		"too_many_requests_is_cached": {
+			statusCode:   http.StatusTooManyRequests,
+			wantRequests: 1,
+		},
+		"server_error_is_cached": {
+			statusCode:   http.StatusServiceUnavailable,
+			wantRequests: 1,
+		},
+		"server_error_is_requested_again_once_expired": {
+			statusCode:      http.StatusServiceUnavailable,
+			failureCacheTTL: 10 * time.Millisecond,
+			wantRequests:    3,
+		},
+		"not_found_is_not_requested_again_once_failure_expired": {
+			statusCode:      http.StatusNotFound,
+			failureCacheTTL: 10 * time.Millisecond,
+			wantRequests:    1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var count atomic.Int32
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				count.Add(1)
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			if tt.failureCacheTTL != 0 {
+				defer func(ttl time.Duration) { scim.ServiceProviderConfigFailureCacheTTL = ttl }(scim.ServiceProviderConfigFailureCacheTTL)
+
+				scim.ServiceProviderConfigFailureCacheTTL = tt.failureCacheTTL
+			}
+
+			scimClient := scim.NewClient(server.Client())
+
+			for range 3 {
+				time.Sleep(2 * tt.failureCacheTTL)
+
+				gotConfig, gotErr := scimClient.GetServiceProviderConfig(context.Background(), &scim.Request{
+					BaseURL:               server.URL,
+					RequestTimeoutSeconds: 5,
+				})
+
+				if gotConfig != nil || gotErr != nil {
+					t.Fatalf("gotConfig: %+v, gotErr: %v, want neither", gotConfig, gotErr)
+				}
+			}
+
+			if got := count.Load(); got != tt.wantRequests {
+				t.Errorf("got %d ServiceProviderConfig requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestGetServiceProviderConfigResponseTooLarge(t *testing.T) {
+	server := httptest.NewServer(newServiceProviderConfigHandler(`{"filter": {"supported": true, "maxResults": 200}}`, &atomic.Int32{}))
+	defer server.Close()
+
+	scimClient := scim.NewClient(server.Client())
+
+	gotConfig, gotErr := scimClient.GetServiceProviderConfig(context.Background(), &scim.Request{
+		BaseURL:               server.URL,
+		RequestTimeoutSeconds: 5,
+		MaxResponseBodyBytes:  16,
+	})
+
+	wantErr := &framework.Error{
+		Message: "The datasource response exceeds the maximum size of 16 bytes. Reduce the page size or increase maxResponseBodyBytes in the datasource config.",
+		Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_FAILED,
+	}
+
+	if gotConfig != nil {
+		t.Errorf("gotConfig: %+v, wantConfig: nil", gotConfig)
+	}
+
+	if !reflect.DeepEqual(gotErr, wantErr) {
+		t.Errorf("gotErr: %v, wantErr: %v", gotErr, wantErr)
+	}
+}
+
+func TestAdapterGetPageWithServiceProviderConfig(t *testing.T) {
+	tests := map[string]struct {
+		serviceProviderConfig string
+		queryParams           scim.QueryParams
+		pageSize              int64
+		cursor                string
+		wantResponse          framework.Response
+		wantNextCursor        *scim.Cursor
+	}{
+		// The filter is evaluated in the adapter, as the SCIM server does not support filtering.
+		"filter_not_supported": {
+			serviceProviderConfig: `{"filter": {"supported": false}, "sort": {"supported": true}}`,
+			queryParams: scim.QueryParams{
+				Filter: `userName eq "Alex"`,
+			},
+			pageSize: 2,
+			wantResponse: framework.Response{
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{"id": "2819c223-7f76-453a-919d-413861904646"},
+					},
+				},
+			},
+			wantNextCursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   3,
+				TotalResults: 5,
+				LocalFilter:  true,
+			},
+		},
+		"sort_not_supported": {
+			serviceProviderConfig: `{"filter": {"supported": true}, "sort": {"supported": false}}`,
+			queryParams: scim.QueryParams{
+				SortBy: "userName",
+			},
+			pageSize: 2,
+			wantResponse: framework.Response{
+				Error: &framework.Error{
+					Message: "A sortBy is configured for entity Users, but the SCIM server does not support sorting.",
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+				},
+			},
+		},
+		// The page size is clamped from 100 to 2, so the request matches the "/Users?startIndex=3&count=2" endpoint.
+		"page_size_clamped_to_max_results": {
+			serviceProviderConfig: `{"filter": {"supported": true, "maxResults": 2}, "sort": {"supported": true}}`,
+			pageSize:              100,
+			cursor:                "3",
+			wantResponse: framework.Response{
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{"id": "e2be737c-61f5-4abe-8797-1e816b15cec8"},
+						{"id": "89fa657e-3ef5-49e3-bb34-b3255e04a8bb"},
+					},
+				},
+			},
+			wantNextCursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   5,
+				TotalResults: 5,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewTLSServer(newServiceProviderConfigHandler(tt.serviceProviderConfig, &atomic.Int32{}))
+			defer server.Close()
+
+			adapter := scim.NewAdapter(&scim.Datasource{
+				Client: server.Client(),
+			})
+
+			request := &framework.Request[scim.Config]{
+				Address: server.URL,
+				Auth: &framework.DatasourceAuthCredentials{
+					Basic: &framework.BasicAuthCredentials{
+						Username: testUsername,
+						Password: testPassword,
+					},
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+						},
+					},
+				},
+				Config: &scim.Config{
+					QueryParams: map[string]scim.QueryParams{
+						scimUser: tt.queryParams,
+					},
+				},
+				PageSize: tt.pageSize,
+				Cursor:   tt.cursor,
+			}
+
+			if tt.wantNextCursor != nil {
+				tt.wantResponse.Success.NextCursor = encodeTestCursor(request, tt.wantNextCursor)
+			}
+
+			gotResponse := adapter.GetPage(context.Background(), request)
+
+			if !reflect.DeepEqual(gotResponse, tt.wantResponse) {
+				t.Errorf("gotResponse: %v, wantResponse: %v", gotResponse, tt.wantResponse)
+			}
+		})
+	}
+}
@@ -3,6 +3,47 @@
 /*
 Package scim implements an adapter for the System for Cross-domain Identity Management (SCIM) protocol v2.
 
+## Authentication
+
+Requests are authenticated with the datasource's basic auth credentials or Authorization header, or with OAuth2
+access tokens if `oauth2` is set in the datasource config. Access tokens are requested from `oauth2.tokenUrl`
+with the client credentials grant, using the datasource's basic auth credentials as client ID and secret unless
+set in the config. They are cached by a hash of the datasource address and OAuth2 credentials until one minute
+before they expire, per the token endpoint's `expires_in`, or for half their lifetime if it is shorter than two
+minutes, and requested again once if the SCIM server responds with 401 Unauthorized, e.g. if the token was
+revoked. Concurrent requests to the same datasource wait for a single token request. Up to 1000 tokens are cached, and the least recently used token is evicted beyond that.
+Access token requests rejected by the token endpoint, i.e. with 400 Bad Request, 401 Unauthorized or an OAuth2
+error code, fail with DATASOURCE_AUTHENTICATION_FAILED, while network errors, timeouts and server errors keep
+their usual error code.
+
+Token endpoints which do not accept client secrets are supported with JWT assertions signed with the RSA or
+ECDSA private key in `oauth2.assertion.privateKey` (RFC 7523). With `oauth2.grantType` set to
+`urn:ietf:params:oauth:grant-type:jwt-bearer`, the assertion is sent as the authorization grant, and with
+`oauth2.clientAuthMethod` set to `private_key_jwt`, it authenticates the client. The `kid` header and the
+`iss`, `sub` and `aud` claims are set from `keyId`, `issuer`, `subject` and `audience`, which default to the client
+ID and the token URL, and assertions expire after `lifetimeSeconds`, five minutes by default.
+The private key is parsed once per OAuth2 config, before the first access token request, and invalid keys
+fail with INVALID_DATASOURCE_CONFIG.
+
+## TLS
+
+SCIM servers requiring mutual TLS are sent the client certificate in `tls.clientCertificate` and
+`tls.clientKey`, both PEM-encoded, or in the base64-encoded PKCS #12 archive `tls.clientPkcs12`, encrypted with
+`tls.clientPkcs12Password`. `tls.caCertificates` is a PEM bundle of the CAs trusted to verify the SCIM server's
+certificate instead of the default trust store, while `tls.additionalCaCertificates` are trusted in addition to
+it, e.g. the root CAs of an internal PKI. `tls.pinnedPublicKeys` are the base64-encoded SHA-256 hashes of the
+SubjectPublicKeyInfo of the keys trusted in the SCIM server's certificate chain, e.g. the output of
+`openssl x509 -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64`.
+If set, a verified certificate chain must also contain one of the pinned keys.
+
+The token endpoint, if any, is sent the same client certificate and trusts `tls.additionalCaCertificates`, but
+`tls.caCertificates` and `tls.pinnedPublicKeys` only apply to the SCIM server, so the token endpoint is verified
+against the default trust store and its keys are not pinned.
+Each datasource address has its own HTTP transport, so connections are only reused by requests with the same TLS
+config. The transport is replaced, and its idle connections closed, when the address's TLS config changes.
+Invalid certificates, keys or pins fail with INVALID_DATASOURCE_CONFIG. Certificate chains which cannot be
+verified fail with an error naming the subject and expiry of each certificate presented by the SCIM server.
+
 ## Group membership
 
 SCIM does not provide a dedicated endpoint for group membership data. Instead, it provides
@@ -18,5 +59,113 @@ ingest group membership data from the User resource, which is relatively small
 and to ignore the `members` attribute on the Group resource.
 
 Group members are ingested as child entities on the SGNL Console.
+A child entity of the User entity with the external ID `groups` contains one object per group membership, with
+- `id`: a unique identifier for the membership i.e. `{userId}:{groupId}`
+- `userId`: the ID of the user
+- `value`: the ID of the group
+- `$ref`: the URI of the group, which must be requested with the JSONPath attribute external ID `$["$ref"]`
+- `display`: the display name of the group
+- `type`: whether the membership is `direct` or `indirect`, if returned by the SCIM server
+
+Some SCIM servers do not return the `groups` attribute of the User resource, as it is optional.
+For these servers, setting `groupMembers` in the datasource config ingests group membership from
+the `members` attribute of the Group resource instead. The `members` attribute is excluded from
+the request listing groups, and requested separately for each group with `attributes=members`.
+A child entity of the Group entity with the external ID `members` contains one object per member, with
+- `id`: a unique identifier for the membership i.e. `{groupId}:{memberId}`
+- `groupId`: the ID of the group
+- `value`: the ID of the member, which is a user or a group
+- `$ref`: the URI of the member, which must be requested with the JSONPath attribute external ID `$["$ref"]`
+- `display`: the display name of the member
+- `type`: whether the member is a `User` or a `Group`
+
+Requesting the members of each group costs one additional request per group of the page, i.e. up to the page
+size, on every page of groups. At most `groupMembers.maxConcurrentRequests` of these requests are in flight at
+once (one by default), and each waits for the datasource's `rateLimit`, if set, which should be configured to
+bound the load on SCIM servers with many groups.
+
+Only the first `groupMembers.maxMembers` members of a group with more members are ingested, and the group is
+reported to the adapter's TruncatedGroupMembersHandler, so that a single large group does not fail the page.
+
+## Paging
+
+With index paging, the start index of the next page is computed from the number of resources actually returned,
+as some SCIM servers omit `itemsPerPage` or return fewer resources than it reports. If a SCIM server omits
+`startIndex` in a response, the requested start index is assumed. A page fails if the SCIM server returns
+a page starting at another index than requested, or returns no resources before `totalResults`, as the sync
+would never complete.
+
+The last page is detected using the `totalResults` of each page. If resources are created or deleted during
+a sync, index paging may skip or duplicate resources. When `totalResults` decreases since the previous page,
+the next start index is moved back by the decrease, always past the current page, so that resources shifted
+to lower indexes are returned again rather than skipped. The sync may still complete early if `totalResults`
+decreases. Cursor paging should be used for SCIM servers which support it, as it is not affected.
+
+## Cursors
+
+The cursors returned by the adapter are versioned, base64-encoded JSON structures recording the paging mode,
+the start index or the SCIM server's cursor of the next page, the `totalResults` of the previous page,
+a hash of the entity's query, whether the filter is evaluated in the adapter, and the incremental sync state,
+if any. A cursor is rejected if the query changed
+since the sync started, e.g. if the entity's attributes, filter or paging mode changed, and the sync must be
+restarted. Legacy cursors, i.e. bare start indexes, are still accepted for index paging.
+
+If the adapter is configured with cursor signing keys, cursors are signed with HMAC-SHA256 using the first key,
+and requests are rejected unless their cursor is signed with one of the keys, including legacy cursors.
+
+## Local filtering
+
+Some SCIM servers ignore the `filter` query parameter, or do not support it. The entity's filter, including the
+incremental sync clause, is then evaluated in the adapter against the resources returned by the SCIM server,
+if `queryParams.localFilter` is set for the entity, if the ServiceProviderConfig reports that filtering is not
+supported, or if the SCIM server responds with 501 Not Implemented to a filtered request. Attribute names and
+string values are compared case-insensitively, and expressions on multi-valued attributes match if any value
+matches. The attributes referenced by the filter are requested in addition to the entity's attributes.
+
+Pages are requested as usual and advance by the number of resources returned by the SCIM server, so pages may
+contain fewer objects than the page size, or none, before the last page.
+
+## Incremental sync
+
+Setting `incrementalSync` for an entity in the datasource config only requests the resources modified since
+the previous sync, by adding a `meta.lastModified gt "<watermark>"` clause to the entity's filter.
+The watermark is `incrementalSync.since` minus `incrementalSync.overlapSeconds`, to tolerate clock skew
+between the adapter and the SCIM server. Resources modified within the overlap are ingested again.
+
+The watermark and the latest `meta.lastModified` returned so far are carried in the cursor, so the watermark is
+fixed for the duration of the sync. When the last page is returned, the latest `meta.lastModified` is reported
+to the adapter's WatermarkHandler as the new high-water mark, in the `incrementalSync` config of the next sync.
+
+The WatermarkHandler is the only output of the high-water mark, as the adapter does not store it. The caller must
+write the reported config back as the entity's `incrementalSync`, i.e. its `since`, in the datasource config of the
+next sync, otherwise the next sync requests the resources modified since the same `incrementalSync.since` again.
+
+## Attribute names
+
+SCIM attribute names are case-insensitive, while the external IDs of the entity's attributes are matched
+case-sensitively. The members of the resources returned by the SCIM server, including the members of complex
+attributes and extension schema URIs, are renamed to the casing of the entity's attributes and child entities,
+e.g. `username` to `userName` and `Emails` to `emails`. Members which do not match any attribute are not renamed.
+
+## Type coercion
+
+Some SCIM servers return attribute values which do not match their types, e.g. "true" for a boolean attribute
+or "42" for an integer attribute, which would fail the whole page. Setting `typeCoercion` in the datasource
+config normalizes the values of each page before converting them into objects:
+
+  - "entity" coerces values into the types of the entity's attributes and child entities' attributes.
+  - "schema" first coerces values into the types defined by the SCIM server's `/Schemas`, including the
+    attributes referenced by JSONPath filters, then into the types of the entity's attributes. The Schemas are
+    cached per datasource address, and "entity" coercion is used if they are not available. The `schemas`
+    attribute of each resource selects the Schemas applied to it, so it is always requested.
+
+Strings such as "true", "yes" or "1" are coerced into booleans, numeric strings into numbers, and numbers or
+booleans into strings. Unix timestamps in seconds or milliseconds, compact dates i.e. `YYYYMMDD` and date-times in
+common formats are coerced into RFC 3339 timestamps. Only numbers with 9 or 10 digits (seconds) or 12 or 13 digits
+(milliseconds), i.e. dates between 1973 and 2286, are considered to be Unix timestamps. A single value is coerced
+into a list for list attributes, and a list of one value into the value for single-valued attributes.
+
+Values which cannot be coerced are removed, so that the rest of the page is ingested. The number of removed values
+of each attribute is reported to the adapter's CoercionFailureHandler, without the values themselves.
 */
 package scim
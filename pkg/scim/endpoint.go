@@ -2,19 +2,38 @@
 package scim
 
 import (
+	"fmt"
 	"net/url"
 	"strconv"
 	"strings"
 )
 
+// SearchRequestSchema is the schema URI of a SCIM SearchRequest.
+const SearchRequestSchema = "urn:ietf:params:scim:api:messages:2.0:SearchRequest"
+
+// SearchRequest is the body of a "POST /{resource}/.search" request to fetch a page of SCIM objects.
+// https://datatracker.ietf.org/doc/html/rfc7644#section-3.4.3
+type SearchRequest struct {
+	Schemas            []string `json:"schemas"`
+	Attributes         []string `json:"attributes,omitempty"`
+	ExcludedAttributes []string `json:"excludedAttributes,omitempty"`
+	Filter             string   `json:"filter,omitempty"`
+	SortBy             string   `json:"sortBy,omitempty"`
+	SortOrder          string   `json:"sortOrder,omitempty"`
+	StartIndex         int64    `json:"startIndex,omitempty"`
+	Count              int64    `json:"count"`
+
+	// Cursor is set when using cursor paging. An empty cursor requests the first page.
+	// https://datatracker.ietf.org/doc/html/rfc9865
+	Cursor *string `json:"cursor,omitempty"`
+}
+
 // GenerateURL returns a URL to fetch a given page of SCIM objects.
-func GenerateURL(
-	baseURL string,
-	entityExternalID string,
-	pageSize int64,
-	startIndex string,
-	queryParams QueryParams,
-) string {
+// The cursor is the start index of the page when using index paging, or the SCIM server's cursor
+// when using cursor paging. An empty cursor requests the first page when using cursor paging.
+func GenerateURL(request *Request, cursor string) string {
+	queryParams := request.QueryParams
+
 	escapedFilter := url.QueryEscape(queryParams.Filter)
 
 	filterLen := len(escapedFilter)
@@ -32,28 +51,66 @@ func GenerateURL(
 		sortOrderLen += 21 // len("&sortOrder=") + max(len(descending), len(ascending)) == 21
 	}
 
+	attributes := url.QueryEscape(strings.Join(request.Attributes, ","))
+
+	attributesLen := len(attributes)
+	if attributesLen > 0 {
+		attributesLen += 12 // len("&attributes=") == 12
+	}
+
+	// The "attributes" and "excludedAttributes" query parameters are mutually exclusive.
+	var excludedAttributes string
+	if attributes == "" {
+		excludedAttributes = url.QueryEscape(strings.Join(request.ExcludedAttributes, ","))
+	}
+
+	excludedAttributesLen := len(excludedAttributes)
+	if excludedAttributesLen > 0 {
+		excludedAttributesLen += 20 // len("&excludedAttributes=") == 20
+	}
+
+	usesCursor := request.PagingMode == PagingModeCursor || request.PagingMode == PagingModeAuto
+	if usesCursor {
+		cursor = url.QueryEscape(cursor)
+	}
+
 	// len(baseURL) + len("/") + len(entityExternalID) +
 	// len("?count=") + len(strconv.FormatInt(pageSize, 10)) + len("&startIndex=") +
-	// len(startIndex) +
-	// filterLen + sortByLen + sortOrderLen ==
+	// len(cursor) +
+	// filterLen + sortByLen + sortOrderLen + attributesLen + excludedAttributesLen ==
 
 	// len(baseURL) + len(entityExternalID) +
-	// len(strconv.FormatInt(pageSize, 10)) + len(strconv.FormatInt(startIndex, 10)) +
-	// filterLen + sortByLen + sortOrderLen + 20
+	// len(strconv.FormatInt(pageSize, 10)) + len(cursor) +
+	// filterLen + sortByLen + sortOrderLen + attributesLen + excludedAttributesLen + 20
+	//
+	// len("?cursor=") is shorter than len("?startIndex="), so this is also enough for cursor paging.
 	var sb strings.Builder
 
 	sb.Grow(
-		len(baseURL) + len(entityExternalID) + len(strconv.FormatInt(pageSize, 10)) + len(startIndex) +
-			filterLen + sortByLen + sortOrderLen + 20,
+		len(request.BaseURL) + len(request.EntityExternalID) + len(strconv.FormatInt(request.PageSize, 10)) +
+			len(cursor) + filterLen + sortByLen + sortOrderLen + attributesLen + excludedAttributesLen + 20,
 	)
 
-	sb.WriteString(baseURL)
+	sb.WriteString(request.BaseURL)
 	sb.WriteString("/")
-	sb.WriteString(entityExternalID)
-	sb.WriteString("?startIndex=")
-	sb.WriteString(startIndex)
+	sb.WriteString(request.EntityExternalID)
+
+	if usesCursor {
+		// The first page is requested with an empty "cursor" query parameter.
+		// https://datatracker.ietf.org/doc/html/rfc9865
+		sb.WriteString("?cursor")
+
+		if cursor != "" {
+			sb.WriteString("=")
+			sb.WriteString(cursor)
+		}
+	} else {
+		sb.WriteString("?startIndex=")
+		sb.WriteString(cursor)
+	}
+
 	sb.WriteString("&count=")
-	sb.WriteString(strconv.FormatInt(pageSize, 10))
+	sb.WriteString(strconv.FormatInt(request.PageSize, 10))
 
 	if queryParams.Filter != "" {
 		sb.WriteString("&filter=")
@@ -73,5 +130,65 @@ func GenerateURL(
 		}
 	}
 
+	if attributes != "" {
+		sb.WriteString("&attributes=")
+		sb.WriteString(attributes)
+	}
+
+	if excludedAttributes != "" {
+		sb.WriteString("&excludedAttributes=")
+		sb.WriteString(excludedAttributes)
+	}
+
 	return sb.String()
 }
+
+// GenerateResourceURL returns a URL to fetch a single SCIM resource, with only the provided attributes.
+func GenerateResourceURL(baseURL string, entityExternalID string, id string, attributes ...string) string {
+	resourceURL := baseURL + "/" + entityExternalID + "/" + url.PathEscape(id)
+
+	if len(attributes) > 0 {
+		resourceURL += "?attributes=" + url.QueryEscape(strings.Join(attributes, ","))
+	}
+
+	return resourceURL
+}
+
+// GenerateSearchRequest returns the URL and the body of a SearchRequest to fetch a given page of SCIM objects.
+// The cursor is the start index of the page when using index paging, or the SCIM server's cursor
+// when using cursor paging. An empty cursor requests the first page when using cursor paging.
+func GenerateSearchRequest(request *Request, cursor string) (string, *SearchRequest, error) {
+	searchRequest := &SearchRequest{
+		Schemas:    []string{SearchRequestSchema},
+		Attributes: request.Attributes,
+		Filter:     request.QueryParams.Filter,
+		SortBy:     request.QueryParams.SortBy,
+		Count:      request.PageSize,
+	}
+
+	// The "attributes" and "excludedAttributes" parameters are mutually exclusive.
+	if len(request.Attributes) == 0 {
+		searchRequest.ExcludedAttributes = request.ExcludedAttributes
+	}
+
+	if request.QueryParams.Ascending != nil {
+		if *request.QueryParams.Ascending {
+			searchRequest.SortOrder = "ascending"
+		} else {
+			searchRequest.SortOrder = "descending"
+		}
+	}
+
+	if request.PagingMode == PagingModeCursor || request.PagingMode == PagingModeAuto {
+		searchRequest.Cursor = &cursor
+	} else {
+		startIndex, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid start index %q: %w", cursor, err)
+		}
+
+		searchRequest.StartIndex = startIndex
+	}
+
+	return request.BaseURL + "/" + request.EntityExternalID + "/.search", searchRequest, nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2025 SGNL.ai, Inc.
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
+	"github.com/sgnl-ai/adapter-framework/web"
+	"github.com/sgnl-ai/sample-adapter/pkg/auth"
+	customerror "github.com/sgnl-ai/sample-adapter/pkg/errors"
+	"github.com/sgnl-ai/sample-adapter/pkg/transport"
+)
+
+// MaxErrorResponseBodyBytes is the maximum number of bytes of an unsuccessful response body read to
+// parse a SCIM error. Longer bodies are truncated and usually fail to parse.
+const MaxErrorResponseBodyBytes = 64 << 10
+
+// ErrorResponse is a SCIM error returned in the body of an unsuccessful response.
+// https://datatracker.ietf.org/doc/html/rfc7644#section-3.12
+type ErrorResponse struct {
+	// ScimType is the SCIM detail error keyword, e.g. "invalidFilter".
+	ScimType string `json:"scimType"`
+
+	// Detail is a human-readable description of the error.
+	Detail string `json:"detail"`
+}
+
+// scimTypeErrorCodes maps the SCIM detail error keywords of errors caused by the request the adapter
+// was configured to send to the appropriate ErrorCode.
+// Other keywords do not change the ErrorCode derived from the response status code.
+var scimTypeErrorCodes = map[string]api_adapter_v1.ErrorCode{
+	// The filter, sortBy or attributes in the datasource config are invalid, or the filter matches
+	// too many resources.
+	"invalidFilter": api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+	"invalidPath":   api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+	"invalidSyntax": api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+	"invalidValue":  api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+	"invalidVers":   api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+	"sensitive":     api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+	"tooMany":       api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+
+	// The cursor of the requested page is no longer valid.
+	"invalidCursor": api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_PAGE_REQUEST_CONFIG,
+	"expiredCursor": api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_PAGE_REQUEST_CONFIG,
+}
+
+// ParseErrorResponse parses the body of an unsuccessful response as a SCIM error.
+// Returns nil if the body is not a SCIM error.
+func ParseErrorResponse(body []byte) *ErrorResponse {
+	var errorResponse *ErrorResponse
+
+	if err := json.Unmarshal(body, &errorResponse); err != nil || errorResponse == nil {
+		return nil
+	}
+
+	if errorResponse.ScimType == "" && errorResponse.Detail == "" {
+		return nil
+	}
+
+	return errorResponse
+}
+
+// WithTokenErrorCode sets the error code to DATASOURCE_AUTHENTICATION_FAILED if the request failed because
+// the token endpoint rejected the access token request, e.g. the client's credentials, i.e. if it responded
+// with 400 Bad Request, 401 Unauthorized, or an OAuth2 error code.
+// Other token request failures, e.g. network errors, timeouts or 5xx responses, keep their error code, as they
+// are not caused by the credentials.
+func WithTokenErrorCode(reqErr error) customerror.ErrorModifier {
+	return func(frameworkErr *framework.Error) {
+		if frameworkErr == nil {
+			return
+		}
+
+		var tokenErr *auth.TokenError
+		if !errors.As(reqErr, &tokenErr) {
+			return
+		}
+
+		rejected := tokenErr.StatusCode == http.StatusBadRequest || tokenErr.StatusCode == http.StatusUnauthorized
+		if rejected || tokenErr.ErrorCode != "" {
+			frameworkErr.Code = api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_AUTHENTICATION_FAILED
+		}
+	}
+}
+
+// WithConfigErrorCode sets the error code to INVALID_DATASOURCE_CONFIG if the request failed because of an
+// invalid TLS or OAuth2 config, e.g. because a client certificate or an assertion private key could not be parsed.
+func WithConfigErrorCode(reqErr error) customerror.ErrorModifier {
+	return func(frameworkErr *framework.Error) {
+		if frameworkErr == nil {
+			return
+		}
+
+		var (
+			tlsConfigErr    *transport.ConfigError
+			oauth2ConfigErr *auth.ConfigError
+		)
+
+		if errors.As(reqErr, &tlsConfigErr) || errors.As(reqErr, &oauth2ConfigErr) {
+			frameworkErr.Code = api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG
+		}
+	}
+}
+
+// HTTPError returns the framework.Error for an unsuccessful response status code, as returned by
+// web.HTTPError, updated with the SCIM error returned in the response body, if any.
+// Returns nil if the status code is successful.
+func HTTPError(statusCode int, retryAfterHeader string, errorResponse *ErrorResponse) *framework.Error {
+	adapterErr := web.HTTPError(statusCode, retryAfterHeader)
+	if adapterErr == nil {
+		return nil
+	}
+
+	return customerror.UpdateError(adapterErr, WithErrorResponse(errorResponse))
+}
+
+// WithErrorResponse appends the SCIM detail error keyword and the detail of a SCIM error to the error message,
+// and maps the keyword to the appropriate ErrorCode.
+func WithErrorResponse(errorResponse *ErrorResponse) customerror.ErrorModifier {
+	return func(frameworkErr *framework.Error) {
+		if frameworkErr == nil || errorResponse == nil {
+			return
+		}
+
+		var sb strings.Builder
+
+		sb.WriteString(frameworkErr.Message)
+
+		if errorResponse.ScimType != "" {
+			sb.WriteString(" SCIM error type: ")
+			sb.WriteString(errorResponse.ScimType)
+			sb.WriteString(".")
+		}
+
+		if detail := strings.TrimSpace(errorResponse.Detail); detail != "" {
+			sb.WriteString(" SCIM error detail: ")
+			sb.WriteString(detail)
+
+			if !strings.HasSuffix(detail, ".") {
+				sb.WriteString(".")
+			}
+		}
+
+		frameworkErr.Message = strings.TrimSpace(sb.String())
+
+		if code, found := scimTypeErrorCodes[errorResponse.ScimType]; found {
+			frameworkErr.Code = code
+		}
+	}
+}
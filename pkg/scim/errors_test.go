@@ -0,0 +1,374 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// nolint: lll
+package scim_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
+	"github.com/sgnl-ai/sample-adapter/pkg/auth"
+	customerror "github.com/sgnl-ai/sample-adapter/pkg/errors"
+	"github.com/sgnl-ai/sample-adapter/pkg/scim"
+	"github.com/sgnl-ai/sample-adapter/pkg/transport"
+)
+
+func TestParseErrorResponse(t *testing.T) {
+	tests := map[string]struct {
+		body              string
+		wantErrorResponse *scim.ErrorResponse
+	}{
+		"scim_error": {
+			body: `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"],
+				"scimType": "invalidFilter",
+				"detail": "Request is unparsable, syntactically incorrect, or violates schema.",
+				"status": "400"
+			}`,
+			wantErrorResponse: &scim.ErrorResponse{
+				ScimType: "invalidFilter",
+				Detail:   "Request is unparsable, syntactically incorrect, or violates schema.",
+			},
+		},
+		"scim_error_without_scim_type": {
+			body: `{"detail": "Resource 2819c223 not found", "status": 404}`,
+			wantErrorResponse: &scim.ErrorResponse{
+				Detail: "Resource 2819c223 not found",
+			},
+		},
+		"json_without_scim_error": {
+			body:              `{"error": "bad request"}`,
+			wantErrorResponse: nil,
+		},
+		"not_json": {
+			body:              `<html><body>Bad Request</body></html>`,
+			wantErrorResponse: nil,
+		},
+		"truncated_json": {
+			body:              `{"scimType": "invalidFilter", "detail": "The filt`,
+			wantErrorResponse: nil,
+		},
+		"empty": {
+			body:              ``,
+			wantErrorResponse: nil,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotErrorResponse := scim.ParseErrorResponse([]byte(tt.body))
+
+			if !reflect.DeepEqual(gotErrorResponse, tt.wantErrorResponse) {
+				t.Errorf("gotErrorResponse: %+v, wantErrorResponse: %+v", gotErrorResponse, tt.wantErrorResponse)
+			}
+		})
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	tests := map[string]struct {
+		statusCode    int
+		errorResponse *scim.ErrorResponse
+		wantErr       *framework.Error
+	}{
+		"success": {
+			statusCode: http.StatusOK,
+			errorResponse: &scim.ErrorResponse{
+				ScimType: "invalidFilter",
+			},
+			wantErr: nil,
+		},
+		"invalid_filter": {
+			statusCode: http.StatusBadRequest,
+			errorResponse: &scim.ErrorResponse{
+				ScimType: "invalidFilter",
+				Detail:   "The specified filter syntax was invalid",
+			},
+			wantErr: &framework.Error{
+				Message: "Datasource rejected request, returned status code: 400. SCIM error type: invalidFilter. SCIM error detail: The specified filter syntax was invalid.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			},
+		},
+		"too_many": {
+			statusCode: http.StatusBadRequest,
+			errorResponse: &scim.ErrorResponse{
+				ScimType: "tooMany",
+			},
+			wantErr: &framework.Error{
+				Message: "Datasource rejected request, returned status code: 400. SCIM error type: tooMany.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			},
+		},
+		"expired_cursor": {
+			statusCode: http.StatusBadRequest,
+			errorResponse: &scim.ErrorResponse{
+				ScimType: "expiredCursor",
+				Detail:   "The cursor has expired.",
+			},
+			wantErr: &framework.Error{
+				Message: "Datasource rejected request, returned status code: 400. SCIM error type: expiredCursor. SCIM error detail: The cursor has expired.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_PAGE_REQUEST_CONFIG,
+			},
+		},
+		"unmapped_scim_type_keeps_status_code_error_code": {
+			statusCode: http.StatusConflict,
+			errorResponse: &scim.ErrorResponse{
+				ScimType: "uniqueness",
+			},
+			wantErr: &framework.Error{
+				Message: "Datasource rejected request, returned status code: 409. SCIM error type: uniqueness.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+		},
+		"detail_without_scim_type": {
+			statusCode: http.StatusInternalServerError,
+			errorResponse: &scim.ErrorResponse{
+				Detail: "Database connection failed.",
+			},
+			wantErr: &framework.Error{
+				Message: "Datasource encountered an internal error. Contact datasource support for assistance. SCIM error detail: Database connection failed.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_FAILED,
+			},
+		},
+		"no_scim_error": {
+			statusCode: http.StatusBadRequest,
+			wantErr: &framework.Error{
+				Message: "Datasource rejected request, returned status code: 400.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotErr := scim.HTTPError(tt.statusCode, "", tt.errorResponse)
+
+			if !reflect.DeepEqual(gotErr, tt.wantErr) {
+				t.Errorf("gotErr: %v, wantErr: %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithTokenErrorCode(t *testing.T) {
+	errContextDeadlineExceeded := fmt.Errorf("timed out: %w", context.DeadlineExceeded)
+
+	tests := map[string]struct {
+		inputError *framework.Error
+		reqErr     error
+		wantError  *framework.Error
+	}{
+		"token_error": {
+			inputError: &framework.Error{
+				Message: "Failed to execute SCIM request: failed to request an access token: token endpoint returned status code 401: invalid_client.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+			reqErr: fmt.Errorf("wrapped: %w", &auth.TokenError{StatusCode: 401, ErrorCode: "invalid_client"}),
+			wantError: &framework.Error{
+				Message: "Failed to execute SCIM request: failed to request an access token: token endpoint returned status code 401: invalid_client.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_AUTHENTICATION_FAILED,
+			},
+		},
+		"token_error_code": {
+			inputError: &framework.Error{
+				Message: "Failed to execute SCIM request: failed to request an access token: token endpoint returned status code 403: unauthorized_client.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+			reqErr: &auth.TokenError{StatusCode: 403, ErrorCode: "unauthorized_client"},
+			wantError: &framework.Error{
+				Message: "Failed to execute SCIM request: failed to request an access token: token endpoint returned status code 403: unauthorized_client.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_AUTHENTICATION_FAILED,
+			},
+		},
+		"token_bad_request_without_error_code": {
+			inputError: &framework.Error{
+				Message: "Failed to execute SCIM request: failed to request an access token: token endpoint returned status code 400.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+			reqErr: &auth.TokenError{StatusCode: 400},
+			wantError: &framework.Error{
+				Message: "Failed to execute SCIM request: failed to request an access token: token endpoint returned status code 400.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_AUTHENTICATION_FAILED,
+			},
+		},
+		"token_endpoint_server_error": {
+			inputError: &framework.Error{
+				Message: "Failed to execute SCIM request: failed to request an access token: token endpoint returned status code 503.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+			reqErr: &auth.TokenError{StatusCode: 503, Err: errors.New("service unavailable")},
+			wantError: &framework.Error{
+				Message: "Failed to execute SCIM request: failed to request an access token: token endpoint returned status code 503.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+		},
+		"token_request_timeout": {
+			inputError: &framework.Error{
+				Message: "Failed to execute SCIM request: failed to request an access token: context deadline exceeded.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+			reqErr: &auth.TokenError{Err: context.DeadlineExceeded},
+			wantError: &framework.Error{
+				Message: "Failed to execute SCIM request: failed to request an access token: context deadline exceeded.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+		},
+		"not_token_error": {
+			inputError: &framework.Error{
+				Message: fmt.Sprintf("Failed to execute SCIM request: %v.", errContextDeadlineExceeded),
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+			reqErr: errContextDeadlineExceeded,
+			wantError: &framework.Error{
+				Message: fmt.Sprintf("Failed to execute SCIM request: %v.", errContextDeadlineExceeded),
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+		},
+		"nil_input": {
+			reqErr: &auth.TokenError{StatusCode: 401},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := customerror.UpdateError(tt.inputError, scim.WithTokenErrorCode(tt.reqErr)); !reflect.DeepEqual(got, tt.wantError) {
+				t.Errorf("gotError: %v, wantError: %v", got, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestWithConfigErrorCode(t *testing.T) {
+	tests := map[string]struct {
+		inputError *framework.Error
+		reqErr     error
+		wantError  *framework.Error
+	}{
+		"tls_config_error": {
+			inputError: &framework.Error{
+				Message: "Failed to execute SCIM request: invalid TLS config: the client certificate and key must be provided together.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+			reqErr: &transport.ConfigError{Err: errors.New("the client certificate and key must be provided together")},
+			wantError: &framework.Error{
+				Message: "Failed to execute SCIM request: invalid TLS config: the client certificate and key must be provided together.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			},
+		},
+		"oauth2_config_error": {
+			inputError: &framework.Error{
+				Message: "Failed to execute SCIM request: invalid OAuth2 config: invalid assertion private key: the private key is not PEM-encoded.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+			reqErr: fmt.Errorf("wrapped: %w", &auth.ConfigError{Err: errors.New("invalid assertion private key: the private key is not PEM-encoded")}),
+			wantError: &framework.Error{
+				Message: "Failed to execute SCIM request: invalid OAuth2 config: invalid assertion private key: the private key is not PEM-encoded.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			},
+		},
+		"not_config_error": {
+			inputError: &framework.Error{
+				Message: "Failed to execute SCIM request: connection refused.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+			reqErr: errors.New("connection refused"),
+			wantError: &framework.Error{
+				Message: "Failed to execute SCIM request: connection refused.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := customerror.UpdateError(tt.inputError, scim.WithConfigErrorCode(tt.reqErr)); !reflect.DeepEqual(got, tt.wantError) {
+				t.Errorf("gotError: %v, wantError: %v", got, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestAdapterGetPageSCIMError(t *testing.T) {
+	tests := map[string]struct {
+		body    string
+		wantErr *framework.Error
+	}{
+		"scim_error": {
+			body: `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"],
+				"scimType": "invalidFilter",
+				"detail": "Unsupported attribute 'userTyp' in filter.",
+				"status": "400"
+			}`,
+			wantErr: &framework.Error{
+				Message: "Datasource rejected request, returned status code: 400. SCIM error type: invalidFilter. SCIM error detail: Unsupported attribute 'userTyp' in filter.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			},
+		},
+		// The SCIM error is truncated to MaxErrorResponseBodyBytes, so it cannot be parsed.
+		"scim_error_exceeding_size_limit": {
+			body: `{"scimType": "invalidFilter", "detail": "` + strings.Repeat("a", scim.MaxErrorResponseBodyBytes) + `"}`,
+			wantErr: &framework.Error{
+				Message: "Datasource rejected request, returned status code: 400.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/Users" {
+					w.WriteHeader(http.StatusNotFound)
+
+					return
+				}
+
+				w.Header().Set("Content-Type", "application/scim+json")
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			adapter := scim.NewAdapter(&scim.Datasource{
+				Client: server.Client(),
+			})
+
+			gotResponse := adapter.GetPage(context.Background(), &framework.Request[scim.Config]{
+				Address: server.URL,
+				Auth: &framework.DatasourceAuthCredentials{
+					HTTPAuthorization: "Bearer token",
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+						},
+					},
+				},
+				Config: &scim.Config{
+					QueryParams: map[string]scim.QueryParams{
+						scimUser: {
+							Filter: `userTyp eq "Employee"`,
+						},
+					},
+				},
+				PageSize: 2,
+			})
+
+			if !reflect.DeepEqual(gotResponse.Error, tt.wantErr) {
+				t.Errorf("gotErr: %v, wantErr: %v", gotResponse.Error, tt.wantErr)
+			}
+		})
+	}
+}
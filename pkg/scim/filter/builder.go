@@ -0,0 +1,177 @@
+// Copyright 2025 SGNL.ai, Inc.
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Value is a comparison value of a filter expression built with the builder API.
+// Values are created with String, Bool, Int, Float, DateTime or Null, and are formatted by the String
+// method of the expression, which quotes and escapes strings.
+type Value struct {
+	value any
+}
+
+// String returns a string comparison value.
+func String(s string) Value {
+	return Value{value: s}
+}
+
+// Bool returns a boolean comparison value.
+func Bool(b bool) Value {
+	return Value{value: b}
+}
+
+// Int returns an integer comparison value.
+func Int(n int64) Value {
+	return Value{value: json.Number(strconv.FormatInt(n, 10))}
+}
+
+// Float returns a decimal comparison value. An error is returned for NaN and infinite values, which cannot be
+// represented in a filter expression.
+func Float(f float64) (Value, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Value{}, fmt.Errorf("%v cannot be represented in a filter expression", f)
+	}
+
+	return Value{value: json.Number(strconv.FormatFloat(f, 'g', -1, 64))}, nil
+}
+
+// DateTime returns a dateTime comparison value, formatted as an RFC 3339 timestamp in UTC.
+func DateTime(t time.Time) Value {
+	return Value{value: t.UTC().Format(time.RFC3339Nano)}
+}
+
+// Null returns the null comparison value.
+func Null() Value {
+	return Value{}
+}
+
+// Attr returns the attribute path of a SCIM attribute, e.g. "name.givenName" or
+// "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber".
+// The path is not validated, so it must be a valid attribute path, typically a constant.
+func Attr(path string) AttributePath {
+	var attributePath AttributePath
+
+	if i := strings.LastIndexByte(path, ':'); i >= 0 {
+		attributePath.URI, path = path[:i], path[i+1:]
+	}
+
+	attributePath.Name, attributePath.SubAttribute, _ = strings.Cut(path, ".")
+
+	return attributePath
+}
+
+// Eq returns an expression matching if the attribute is equal to the value.
+func (p AttributePath) Eq(value Value) Expression {
+	return p.compare(Equal, value)
+}
+
+// Ne returns an expression matching if the attribute is not equal to the value.
+func (p AttributePath) Ne(value Value) Expression {
+	return p.compare(NotEqual, value)
+}
+
+// Co returns an expression matching if the attribute contains the value.
+func (p AttributePath) Co(value Value) Expression {
+	return p.compare(Contains, value)
+}
+
+// Sw returns an expression matching if the attribute starts with the value.
+func (p AttributePath) Sw(value Value) Expression {
+	return p.compare(StartsWith, value)
+}
+
+// Ew returns an expression matching if the attribute ends with the value.
+func (p AttributePath) Ew(value Value) Expression {
+	return p.compare(EndsWith, value)
+}
+
+// Gt returns an expression matching if the attribute is greater than the value.
+func (p AttributePath) Gt(value Value) Expression {
+	return p.compare(GreaterThan, value)
+}
+
+// Ge returns an expression matching if the attribute is greater than or equal to the value.
+func (p AttributePath) Ge(value Value) Expression {
+	return p.compare(GreaterThanOrEqual, value)
+}
+
+// Lt returns an expression matching if the attribute is less than the value.
+func (p AttributePath) Lt(value Value) Expression {
+	return p.compare(LessThan, value)
+}
+
+// Le returns an expression matching if the attribute is less than or equal to the value.
+func (p AttributePath) Le(value Value) Expression {
+	return p.compare(LessThanOrEqual, value)
+}
+
+// Pr returns an expression matching if the attribute has a non-empty value.
+func (p AttributePath) Pr() Expression {
+	return &PresentExpression{Path: p}
+}
+
+// Where returns an expression matching if any value of the multi-valued complex attribute matches
+// the filter, e.g. Attr("emails").Where(Attr("type").Eq(String("work"))).
+func (p AttributePath) Where(filter Expression) Expression {
+	return &ValuePathExpression{Path: p, Filter: filter}
+}
+
+func (p AttributePath) compare(operator CompareOperator, value Value) Expression {
+	return &AttributeExpression{Path: p, Operator: operator, Value: value.value}
+}
+
+// AllOf returns an expression matching if all the expressions match. nil expressions are ignored.
+// Returns nil if there are no expressions.
+func AllOf(expressions ...Expression) Expression {
+	return combine(And, expressions)
+}
+
+// AnyOf returns an expression matching if any of the expressions matches. nil expressions are ignored.
+// Returns nil if there are no expressions.
+func AnyOf(expressions ...Expression) Expression {
+	return combine(Or, expressions)
+}
+
+// Not returns an expression matching if the expression does not match.
+func Not(expression Expression) Expression {
+	return &NotExpression{Expression: expression}
+}
+
+func combine(operator LogicalOperator, expressions []Expression) Expression {
+	var combined Expression
+
+	for _, expression := range expressions {
+		switch {
+		case expression == nil:
+		case combined == nil:
+			combined = expression
+		default:
+			combined = &LogicalExpression{Operator: operator, Left: combined, Right: expression}
+		}
+	}
+
+	return combined
+}
+
+// Restrict returns the filter expression matching the resources matched by both a user-supplied filter
+// expression, if any, and a generated clause. The filter is parsed rather than concatenated, so that its
+// operators cannot change the meaning of the clause. A *SyntaxError is returned if the filter is invalid.
+func Restrict(filter string, clause Expression) (string, error) {
+	if filter == "" {
+		return clause.String(), nil
+	}
+
+	expression, err := Parse(filter)
+	if err != nil {
+		return "", err
+	}
+
+	return AllOf(expression, clause).String(), nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// nolint: lll
+package filter_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sgnl-ai/sample-adapter/pkg/scim/filter"
+)
+
+func mustFloat(t *testing.T, f float64) filter.Value {
+	value, err := filter.Float(f)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	return value
+}
+
+func TestFloat(t *testing.T) {
+	tests := map[string]struct {
+		f       float64
+		wantErr string
+	}{
+		"nan": {
+			f:       math.NaN(),
+			wantErr: "NaN cannot be represented in a filter expression",
+		},
+		"positive_infinity": {
+			f:       math.Inf(1),
+			wantErr: "+Inf cannot be represented in a filter expression",
+		},
+		"negative_infinity": {
+			f:       math.Inf(-1),
+			wantErr: "-Inf cannot be represented in a filter expression",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, gotErr := filter.Float(tt.f); gotErr == nil || gotErr.Error() != tt.wantErr {
+				t.Errorf("gotErr: %v, wantErr: %s", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	tests := map[string]struct {
+		expression filter.Expression
+		wantFilter string
+	}{
+		"string_escaping": {
+			expression: filter.Attr("displayName").Eq(filter.String(`a "quoted" \ value <&>`)),
+			wantFilter: `displayName eq "a \"quoted\" \\ value <&>"`,
+		},
+		"injection": {
+			expression: filter.Attr("userName").Eq(filter.String(`x" or userName pr or userName eq "y`)),
+			wantFilter: `userName eq "x\" or userName pr or userName eq \"y"`,
+		},
+		"literals": {
+			expression: filter.AllOf(
+				filter.Attr("active").Eq(filter.Bool(true)),
+				filter.Attr("age").Ge(filter.Int(-42)),
+				filter.Attr("score").Lt(mustFloat(t, 0.5)),
+				filter.Attr("manager").Ne(filter.Null()),
+				filter.Attr("meta.lastModified").Gt(filter.DateTime(time.Date(2025, 6, 1, 14, 0, 0, 500, time.FixedZone("CEST", 2*60*60)))),
+			),
+			wantFilter: `active eq true and age ge -42 and score lt 0.5 and manager ne null and meta.lastModified gt "2025-06-01T12:00:00.0000005Z"`,
+		},
+		"string_operators": {
+			expression: filter.AnyOf(
+				filter.Attr("userName").Co(filter.String("jen")),
+				filter.Attr("userName").Sw(filter.String("b")),
+				filter.Attr("userName").Ew(filter.String("@example.com")),
+				filter.Attr("title").Pr(),
+			),
+			wantFilter: `userName co "jen" or userName sw "b" or userName ew "@example.com" or title pr`,
+		},
+		"precedence": {
+			expression: filter.AllOf(
+				filter.AnyOf(filter.Attr("a").Gt(filter.Int(1)), filter.Attr("b").Le(filter.Int(2))),
+				filter.Not(filter.AllOf(filter.Attr("c").Pr(), filter.Attr("d").Pr())),
+				nil,
+			),
+			wantFilter: `(a gt 1 or b le 2) and not (c pr and d pr)`,
+		},
+		"value_path": {
+			expression: filter.Attr("emails").Where(filter.AllOf(
+				filter.Attr("type").Eq(filter.String("work")),
+				filter.Attr("primary").Eq(filter.Bool(true)),
+			)),
+			wantFilter: `emails[type eq "work" and primary eq true]`,
+		},
+		"urn_prefix": {
+			expression: filter.Attr("urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value").Eq(filter.String("26118915")),
+			wantFilter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value eq "26118915"`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotFilter := tt.expression.String()
+			if gotFilter != tt.wantFilter {
+				t.Errorf("gotFilter: %s, wantFilter: %s", gotFilter, tt.wantFilter)
+			}
+
+			// The built filter must parse back to the same expression.
+			parsed, err := filter.Parse(gotFilter)
+			if err != nil {
+				t.Fatalf("Failed to parse the built filter: %v", err)
+			}
+
+			if !reflect.DeepEqual(parsed, tt.expression) {
+				t.Errorf("gotParsed: %v, wantParsed: %v", parsed, tt.expression)
+			}
+		})
+	}
+}
+
+func TestRestrict(t *testing.T) {
+	clause := filter.Attr("meta.lastModified").Gt(filter.String("2025-06-01T12:00:00Z"))
+
+	tests := map[string]struct {
+		filter     string
+		wantFilter string
+		wantErr    error
+	}{
+		"no_filter": {
+			wantFilter: `meta.lastModified gt "2025-06-01T12:00:00Z"`,
+		},
+		"and_filter": {
+			filter:     `userType eq "Employee" and active eq true`,
+			wantFilter: `userType eq "Employee" and active eq true and meta.lastModified gt "2025-06-01T12:00:00Z"`,
+		},
+		"or_filter": {
+			filter:     `userType eq "Employee" or title pr`,
+			wantFilter: `(userType eq "Employee" or title pr) and meta.lastModified gt "2025-06-01T12:00:00Z"`,
+		},
+		"invalid_filter": {
+			filter:  `userType eq "Employee") or (title pr`,
+			wantErr: &filter.SyntaxError{Position: 23, Message: `unexpected ")"`},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotFilter, gotErr := filter.Restrict(tt.filter, clause)
+
+			if gotFilter != tt.wantFilter {
+				t.Errorf("gotFilter: %s, wantFilter: %s", gotFilter, tt.wantFilter)
+			}
+
+			if !reflect.DeepEqual(gotErr, tt.wantErr) {
+				t.Errorf("gotErr: %v, wantErr: %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
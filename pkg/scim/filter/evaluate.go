@@ -0,0 +1,288 @@
+// Copyright 2025 SGNL.ai, Inc.
+package filter
+
+import (
+	"cmp"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Match returns whether a SCIM resource decoded from JSON matches a filter expression.
+//
+// As the SCIM schema of the resource is unknown, all attributes are assumed not to be case-exact:
+// attribute names and string values are compared case-insensitively. Strings which are both RFC 3339
+// timestamps are compared as dateTime values. An expression on a multi-valued attribute matches if any of
+// its values matches, and an expression on a multi-valued complex attribute without a sub-attribute applies
+// to its "value" sub-attribute. "ne" matches if no value is equal to the comparison value.
+// https://datatracker.ietf.org/doc/html/rfc7644#section-3.4.2.2
+func Match(expression Expression, resource map[string]any) bool {
+	switch expression := expression.(type) {
+	case *LogicalExpression:
+		if expression.Operator == And {
+			return Match(expression.Left, resource) && Match(expression.Right, resource)
+		}
+
+		return Match(expression.Left, resource) || Match(expression.Right, resource)
+	case *NotExpression:
+		return !Match(expression.Expression, resource)
+	case *PresentExpression:
+		for _, value := range attributeValues(resource, expression.Path) {
+			if isPresent(value) {
+				return true
+			}
+		}
+
+		return false
+	case *ValuePathExpression:
+		for _, value := range attributeValues(resource, expression.Path) {
+			if element, ok := value.(map[string]any); ok && Match(expression.Filter, element) {
+				return true
+			}
+		}
+
+		return false
+	case *AttributeExpression:
+		return matchAttribute(expression, attributeValues(resource, expression.Path))
+	default:
+		return false
+	}
+}
+
+func matchAttribute(expression *AttributeExpression, values []any) bool {
+	// "eq null" matches resources without the attribute, and "ne null" resources with the attribute.
+	if expression.Value == nil {
+		switch expression.Operator {
+		case Equal:
+			return len(values) == 0
+		case NotEqual:
+			return len(values) > 0
+		default:
+			return false
+		}
+	}
+
+	operator := expression.Operator
+	if operator == NotEqual {
+		operator = Equal
+	}
+
+	matched := false
+
+	for _, value := range values {
+		// Multi-valued complex attributes are compared using their "value" sub-attribute.
+		if element, ok := value.(map[string]any); ok {
+			value = lookup(element, "value")
+		}
+
+		if compareValue(operator, value, expression.Value) {
+			matched = true
+
+			break
+		}
+	}
+
+	if expression.Operator == NotEqual {
+		return !matched
+	}
+
+	return matched
+}
+
+// compareValue compares an attribute value with a comparison value.
+// Values of different types are never equal, and only strings support "co", "sw" and "ew".
+func compareValue(operator CompareOperator, value any, comparisonValue any) bool {
+	switch comparisonValue := comparisonValue.(type) {
+	case string:
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+
+		switch operator {
+		case Contains:
+			return strings.Contains(strings.ToLower(s), strings.ToLower(comparisonValue))
+		case StartsWith:
+			return strings.HasPrefix(strings.ToLower(s), strings.ToLower(comparisonValue))
+		case EndsWith:
+			return strings.HasSuffix(strings.ToLower(s), strings.ToLower(comparisonValue))
+		}
+
+		return compareOrdered(operator, compareStrings(s, comparisonValue))
+	case bool:
+		b, ok := value.(bool)
+
+		return ok && operator == Equal && b == comparisonValue
+	case json.Number:
+		n, ok := toFloat(value)
+		if !ok {
+			return false
+		}
+
+		comparisonNumber, err := comparisonValue.Float64()
+		if err != nil {
+			return false
+		}
+
+		switch operator {
+		case Contains, StartsWith, EndsWith:
+			return false
+		}
+
+		return compareOrdered(operator, cmp.Compare(n, comparisonNumber))
+	default:
+		return false
+	}
+}
+
+// compareOrdered returns whether the result of comparing two values satisfies an ordering operator.
+func compareOrdered(operator CompareOperator, result int) bool {
+	switch operator {
+	case Equal:
+		return result == 0
+	case GreaterThan:
+		return result > 0
+	case GreaterThanOrEqual:
+		return result >= 0
+	case LessThan:
+		return result < 0
+	case LessThanOrEqual:
+		return result <= 0
+	default:
+		return false
+	}
+}
+
+// compareStrings compares two strings as dateTime values if both are RFC 3339 timestamps,
+// or case-insensitively otherwise.
+func compareStrings(a, b string) int {
+	if timeA, err := time.Parse(time.RFC3339Nano, a); err == nil {
+		if timeB, err := time.Parse(time.RFC3339Nano, b); err == nil {
+			return timeA.Compare(timeB)
+		}
+	}
+
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func toFloat(value any) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case json.Number:
+		f, err := value.Float64()
+
+		return f, err == nil
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	default:
+		return 0, false
+	}
+}
+
+// isPresent returns whether a value is non-empty: not null, an empty string, an empty array or an empty object.
+func isPresent(value any) bool {
+	switch value := value.(type) {
+	case nil:
+		return false
+	case string:
+		return value != ""
+	case map[string]any:
+		return len(value) > 0
+	default:
+		return true
+	}
+}
+
+// attributeValues returns the values of an attribute path in a resource. The values of multi-valued
+// attributes are flattened, and nulls are omitted.
+func attributeValues(resource map[string]any, path AttributePath) []any {
+	container := resource
+
+	// Extension attributes are nested under their schema URI. Core attributes are not, so the URI
+	// of core schemas is ignored if there is no such object.
+	if path.URI != "" {
+		if extension, ok := lookup(resource, path.URI).(map[string]any); ok {
+			container = extension
+		}
+	}
+
+	values := flatten(lookup(container, path.Name))
+	if path.SubAttribute == "" {
+		return values
+	}
+
+	var subValues []any
+
+	for _, value := range values {
+		if element, ok := value.(map[string]any); ok {
+			subValues = append(subValues, flatten(lookup(element, path.SubAttribute))...)
+		}
+	}
+
+	return subValues
+}
+
+// lookup returns the value of an attribute of a complex value, matching its name case-insensitively.
+func lookup(object map[string]any, name string) any {
+	if value, found := object[name]; found {
+		return value
+	}
+
+	for key, value := range object {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+
+	return nil
+}
+
+func flatten(value any) []any {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		values := make([]any, 0, len(value))
+
+		for _, element := range value {
+			if element != nil {
+				values = append(values, element)
+			}
+		}
+
+		return values
+	default:
+		return []any{value}
+	}
+}
+
+// Paths returns the attribute paths referenced by a filter expression, e.g. to request the attributes
+// required to evaluate the expression. The paths of value path filters are not returned, as they are
+// relative to the value path, which is returned instead.
+func Paths(expression Expression) []string {
+	var paths []string
+
+	var walk func(Expression)
+	walk = func(expression Expression) {
+		switch expression := expression.(type) {
+		case *LogicalExpression:
+			walk(expression.Left)
+			walk(expression.Right)
+		case *NotExpression:
+			walk(expression.Expression)
+		case *PresentExpression:
+			paths = append(paths, expression.Path.String())
+		case *AttributeExpression:
+			paths = append(paths, expression.Path.String())
+		case *ValuePathExpression:
+			paths = append(paths, expression.Path.String())
+		}
+	}
+
+	walk(expression)
+
+	return paths
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// nolint: lll
+package filter_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sgnl-ai/sample-adapter/pkg/scim/filter"
+)
+
+const testResource = `{
+	"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+	"id": "2819c223-7f76-453a-919d-413861904646",
+	"userName": "bjensen@example.com",
+	"name": {"familyName": "Jensen", "givenName": "Barbara"},
+	"title": "",
+	"userType": "Employee",
+	"active": true,
+	"loginCount": 42,
+	"manager": null,
+	"emails": [
+		{"value": "bjensen@example.com", "type": "work", "primary": true},
+		{"value": "babs@jensen.org", "type": "home"}
+	],
+	"roles": ["admin", "auditor"],
+	"meta": {"lastModified": "2025-06-01T12:00:00.5+02:00"},
+	"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+		"employeeNumber": "701984",
+		"manager": {"value": "26118915"}
+	}
+}`
+
+func TestMatch(t *testing.T) {
+	var resource map[string]any
+
+	if err := json.Unmarshal([]byte(testResource), &resource); err != nil {
+		t.Fatalf("Failed to decode the test resource: %v", err)
+	}
+
+	tests := map[string]struct {
+		filter    string
+		wantMatch bool
+	}{
+		"equal":                               {filter: `userName eq "bjensen@example.com"`, wantMatch: true},
+		"equal_case_insensitive_value":        {filter: `userName eq "BJensen@Example.com"`, wantMatch: true},
+		"equal_case_insensitive_name":         {filter: `USERNAME eq "bjensen@example.com"`, wantMatch: true},
+		"not_equal":                           {filter: `userType ne "Employee"`, wantMatch: false},
+		"not_equal_absent":                    {filter: `nickName ne "Babs"`, wantMatch: true},
+		"contains":                            {filter: `name.familyName co "ENS"`, wantMatch: true},
+		"starts_with":                         {filter: `name.givenName sw "bar"`, wantMatch: true},
+		"ends_with":                           {filter: `name.givenName ew "bar"`, wantMatch: false},
+		"string_greater_than":                 {filter: `name.familyName gt "j"`, wantMatch: true},
+		"date_time_greater_than":              {filter: `meta.lastModified gt "2025-06-01T09:59:59Z"`, wantMatch: true},
+		"date_time_less_than_other_zone":      {filter: `meta.lastModified lt "2025-06-01T10:00:00Z"`, wantMatch: false},
+		"number_greater_than_or_equal":        {filter: `loginCount ge 42`, wantMatch: true},
+		"number_less_than":                    {filter: `loginCount lt 4.2e1`, wantMatch: false},
+		"number_not_compared_to_string":       {filter: `loginCount eq "42"`, wantMatch: false},
+		"boolean":                             {filter: `active eq true`, wantMatch: true},
+		"boolean_not_ordered":                 {filter: `active gt false`, wantMatch: false},
+		"null":                                {filter: `manager eq null`, wantMatch: true},
+		"not_null":                            {filter: `userName ne null`, wantMatch: true},
+		"present":                             {filter: `userName pr`, wantMatch: true},
+		"empty_string_not_present":            {filter: `title pr`, wantMatch: false},
+		"null_not_present":                    {filter: `manager pr`, wantMatch: false},
+		"multi_valued_any_value":              {filter: `roles eq "auditor"`, wantMatch: true},
+		"multi_valued_complex_value":          {filter: `emails co "jensen.org"`, wantMatch: true},
+		"multi_valued_sub_attribute":          {filter: `emails.type eq "home"`, wantMatch: true},
+		"multi_valued_not_equal":              {filter: `emails.type ne "home"`, wantMatch: false},
+		"value_path":                          {filter: `emails[type eq "work" and value ew "example.com"]`, wantMatch: true},
+		"value_path_same_value":               {filter: `emails[type eq "home" and value ew "example.com"]`, wantMatch: false},
+		"value_path_without_value_path":       {filter: `emails.type eq "home" and emails.value ew "example.com"`, wantMatch: true},
+		"extension_attribute":                 {filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "701984"`, wantMatch: true},
+		"extension_sub_attribute":             {filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value eq "26118915"`, wantMatch: true},
+		"core_schema_uri":                     {filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName sw "bjensen"`, wantMatch: true},
+		"and":                                 {filter: `userType eq "Employee" and active eq false`, wantMatch: false},
+		"or":                                  {filter: `userType eq "Contractor" or active eq true`, wantMatch: true},
+		"not":                                 {filter: `not (userType eq "Contractor")`, wantMatch: true},
+		"grouping":                            {filter: `userType eq "Employee" and (emails co "sgnl.com" or emails.value co "sgnl.org")`, wantMatch: false},
+		"incremental_sync_clause":             {filter: `userType eq "Employee" and meta.lastModified gt "2025-06-01T10:00:00.4Z"`, wantMatch: true},
+		"incremental_sync_clause_not_matched": {filter: `userType eq "Employee" and meta.lastModified gt "2025-06-01T10:00:00.5Z"`, wantMatch: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			expression, err := filter.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if gotMatch := filter.Match(expression, resource); gotMatch != tt.wantMatch {
+				t.Errorf("gotMatch: %v, wantMatch: %v", gotMatch, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestPaths(t *testing.T) {
+	tests := map[string]struct {
+		filter    string
+		wantPaths []string
+	}{
+		"attribute": {
+			filter:    `userName eq "bjensen"`,
+			wantPaths: []string{"userName"},
+		},
+		"logical": {
+			filter:    `not (title pr) and (name.givenName sw "B" or urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "701984")`,
+			wantPaths: []string{"title", "name.givenName", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber"},
+		},
+		"value_path": {
+			filter:    `emails[type eq "work"]`,
+			wantPaths: []string{"emails"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			expression, err := filter.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if gotPaths := filter.Paths(expression); !reflect.DeepEqual(gotPaths, tt.wantPaths) {
+				t.Errorf("gotPaths: %v, wantPaths: %v", gotPaths, tt.wantPaths)
+			}
+		})
+	}
+}
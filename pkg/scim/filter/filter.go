@@ -0,0 +1,199 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// Package filter parses SCIM filter expressions into an abstract syntax tree, builds filter expressions,
+// and evaluates them against SCIM resources for servers which do not support filtering.
+// https://datatracker.ietf.org/doc/html/rfc7644#section-3.4.2.2
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Expression is a node of a parsed filter expression. Its String method returns the filter expression
+// in the SCIM filter syntax.
+type Expression interface {
+	String() string
+
+	expression()
+}
+
+// CompareOperator is a SCIM attribute comparison operator.
+type CompareOperator string
+
+const (
+	// Equal matches if the attribute value and the comparison value are equal.
+	Equal CompareOperator = "eq"
+
+	// NotEqual matches if the attribute value and the comparison value are not equal.
+	NotEqual CompareOperator = "ne"
+
+	// Contains matches if the comparison value is a substring of the attribute value.
+	Contains CompareOperator = "co"
+
+	// StartsWith matches if the attribute value starts with the comparison value.
+	StartsWith CompareOperator = "sw"
+
+	// EndsWith matches if the attribute value ends with the comparison value.
+	EndsWith CompareOperator = "ew"
+
+	// GreaterThan matches if the attribute value is greater than the comparison value.
+	GreaterThan CompareOperator = "gt"
+
+	// GreaterThanOrEqual matches if the attribute value is greater than or equal to the comparison value.
+	GreaterThanOrEqual CompareOperator = "ge"
+
+	// LessThan matches if the attribute value is less than the comparison value.
+	LessThan CompareOperator = "lt"
+
+	// LessThanOrEqual matches if the attribute value is less than or equal to the comparison value.
+	LessThanOrEqual CompareOperator = "le"
+)
+
+// LogicalOperator is a SCIM logical operator.
+type LogicalOperator string
+
+const (
+	// And matches if both expressions match.
+	And LogicalOperator = "and"
+
+	// Or matches if any of the expressions matches.
+	Or LogicalOperator = "or"
+)
+
+// AttributePath is the path of an attribute, e.g. "name.givenName" or
+// "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber".
+type AttributePath struct {
+	// URI is the schema URI prefixing the attribute name, if any.
+	URI string
+
+	// Name is the attribute name.
+	Name string
+
+	// SubAttribute is the name of the sub-attribute, if any.
+	SubAttribute string
+}
+
+func (p AttributePath) String() string {
+	var sb strings.Builder
+
+	if p.URI != "" {
+		sb.WriteString(p.URI)
+		sb.WriteByte(':')
+	}
+
+	sb.WriteString(p.Name)
+
+	if p.SubAttribute != "" {
+		sb.WriteByte('.')
+		sb.WriteString(p.SubAttribute)
+	}
+
+	return sb.String()
+}
+
+// AttributeExpression compares the value of an attribute with a comparison value, e.g. `userName eq "bjensen"`.
+type AttributeExpression struct {
+	Path     AttributePath
+	Operator CompareOperator
+
+	// Value is the comparison value: a string, a bool, a json.Number, or nil for null.
+	Value any
+}
+
+func (*AttributeExpression) expression() {}
+
+func (e *AttributeExpression) String() string {
+	return e.Path.String() + " " + string(e.Operator) + " " + FormatValue(e.Value)
+}
+
+// PresentExpression matches if an attribute has a non-empty value, e.g. `title pr`.
+type PresentExpression struct {
+	Path AttributePath
+}
+
+func (*PresentExpression) expression() {}
+
+func (e *PresentExpression) String() string {
+	return e.Path.String() + " pr"
+}
+
+// LogicalExpression combines two expressions with a logical operator, e.g. `title pr and userType eq "Employee"`.
+type LogicalExpression struct {
+	Operator LogicalOperator
+	Left     Expression
+	Right    Expression
+}
+
+func (*LogicalExpression) expression() {}
+
+func (e *LogicalExpression) String() string {
+	return e.operand(e.Left, false) + " " + string(e.Operator) + " " + e.operand(e.Right, true)
+}
+
+// operand returns an operand of the expression, grouped if it would otherwise be parsed differently:
+// "and" has a higher precedence than "or", and operators are left-associative.
+func (e *LogicalExpression) operand(operand Expression, right bool) string {
+	logical, ok := operand.(*LogicalExpression)
+	if ok && (e.Operator == And && logical.Operator == Or || right && e.Operator == logical.Operator) {
+		return "(" + operand.String() + ")"
+	}
+
+	return operand.String()
+}
+
+// NotExpression negates an expression, e.g. `not (userType eq "Employee")`.
+type NotExpression struct {
+	Expression Expression
+}
+
+func (*NotExpression) expression() {}
+
+func (e *NotExpression) String() string {
+	return "not (" + e.Expression.String() + ")"
+}
+
+// ValuePathExpression filters the values of a multi-valued complex attribute, e.g. `emails[type eq "work"]`.
+// The attribute paths of the filter are relative to the attribute.
+type ValuePathExpression struct {
+	Path   AttributePath
+	Filter Expression
+}
+
+func (*ValuePathExpression) expression() {}
+
+func (e *ValuePathExpression) String() string {
+	return e.Path.String() + "[" + e.Filter.String() + "]"
+}
+
+// FormatValue formats a comparison value in the SCIM filter syntax. Strings are quoted and escaped as
+// JSON strings.
+func FormatValue(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		if value {
+			return "true"
+		}
+
+		return "false"
+	case json.Number:
+		return value.String()
+	case string:
+		var buf bytes.Buffer
+
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+
+		// Encoding a string cannot fail.
+		_ = enc.Encode(value)
+
+		return strings.TrimSuffix(buf.String(), "\n")
+	default:
+		data, _ := json.Marshal(value)
+
+		return string(data)
+	}
+}
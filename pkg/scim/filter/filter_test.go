@@ -0,0 +1,271 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// nolint: lll
+package filter_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sgnl-ai/sample-adapter/pkg/scim/filter"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		filter         string
+		wantExpression filter.Expression
+		// wantString is the expected String() of the parsed expression, if different from the filter.
+		wantString string
+	}{
+		"equal": {
+			filter: `userName eq "bjensen"`,
+			wantExpression: &filter.AttributeExpression{
+				Path:     filter.AttributePath{Name: "userName"},
+				Operator: filter.Equal,
+				Value:    "bjensen",
+			},
+		},
+		"case_insensitive_operator": {
+			filter: `userName EQ "bjensen"`,
+			wantExpression: &filter.AttributeExpression{
+				Path:     filter.AttributePath{Name: "userName"},
+				Operator: filter.Equal,
+				Value:    "bjensen",
+			},
+			wantString: `userName eq "bjensen"`,
+		},
+		"sub_attribute": {
+			filter: `name.familyName co "O'Malley"`,
+			wantExpression: &filter.AttributeExpression{
+				Path:     filter.AttributePath{Name: "name", SubAttribute: "familyName"},
+				Operator: filter.Contains,
+				Value:    "O'Malley",
+			},
+		},
+		"urn_prefix": {
+			filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value eq "26118915"`,
+			wantExpression: &filter.AttributeExpression{
+				Path: filter.AttributePath{
+					URI:          "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+					Name:         "manager",
+					SubAttribute: "value",
+				},
+				Operator: filter.Equal,
+				Value:    "26118915",
+			},
+		},
+		"escaped_string": {
+			filter: `displayName eq "a \"quoted\" \\ value"`,
+			wantExpression: &filter.AttributeExpression{
+				Path:     filter.AttributePath{Name: "displayName"},
+				Operator: filter.Equal,
+				Value:    `a "quoted" \ value`,
+			},
+		},
+		"literals": {
+			filter: `active eq true and deleted eq False and manager eq null and age ge -1.5e2`,
+			wantExpression: &filter.LogicalExpression{
+				Operator: filter.And,
+				Left: &filter.LogicalExpression{
+					Operator: filter.And,
+					Left: &filter.LogicalExpression{
+						Operator: filter.And,
+						Left:     &filter.AttributeExpression{Path: filter.AttributePath{Name: "active"}, Operator: filter.Equal, Value: true},
+						Right:    &filter.AttributeExpression{Path: filter.AttributePath{Name: "deleted"}, Operator: filter.Equal, Value: false},
+					},
+					Right: &filter.AttributeExpression{Path: filter.AttributePath{Name: "manager"}, Operator: filter.Equal, Value: nil},
+				},
+				Right: &filter.AttributeExpression{Path: filter.AttributePath{Name: "age"}, Operator: filter.GreaterThanOrEqual, Value: json.Number("-1.5e2")},
+			},
+			wantString: `active eq true and deleted eq false and manager eq null and age ge -1.5e2`,
+		},
+		"present": {
+			filter: `title pr`,
+			wantExpression: &filter.PresentExpression{
+				Path: filter.AttributePath{Name: "title"},
+			},
+		},
+		"and_has_precedence_over_or": {
+			filter: `title pr or userType eq "Employee" and active eq true`,
+			wantExpression: &filter.LogicalExpression{
+				Operator: filter.Or,
+				Left:     &filter.PresentExpression{Path: filter.AttributePath{Name: "title"}},
+				Right: &filter.LogicalExpression{
+					Operator: filter.And,
+					Left:     &filter.AttributeExpression{Path: filter.AttributePath{Name: "userType"}, Operator: filter.Equal, Value: "Employee"},
+					Right:    &filter.AttributeExpression{Path: filter.AttributePath{Name: "active"}, Operator: filter.Equal, Value: true},
+				},
+			},
+		},
+		"grouping": {
+			filter: `userType eq "Employee" and (emails co "sgnl.com" or emails.value co "sgnl.org")`,
+			wantExpression: &filter.LogicalExpression{
+				Operator: filter.And,
+				Left:     &filter.AttributeExpression{Path: filter.AttributePath{Name: "userType"}, Operator: filter.Equal, Value: "Employee"},
+				Right: &filter.LogicalExpression{
+					Operator: filter.Or,
+					Left:     &filter.AttributeExpression{Path: filter.AttributePath{Name: "emails"}, Operator: filter.Contains, Value: "sgnl.com"},
+					Right:    &filter.AttributeExpression{Path: filter.AttributePath{Name: "emails", SubAttribute: "value"}, Operator: filter.Contains, Value: "sgnl.org"},
+				},
+			},
+		},
+		"redundant_grouping": {
+			filter: `((title pr))`,
+			wantExpression: &filter.PresentExpression{
+				Path: filter.AttributePath{Name: "title"},
+			},
+			wantString: `title pr`,
+		},
+		"not": {
+			filter: `not (userType eq "Employee")`,
+			wantExpression: &filter.NotExpression{
+				Expression: &filter.AttributeExpression{Path: filter.AttributePath{Name: "userType"}, Operator: filter.Equal, Value: "Employee"},
+			},
+		},
+		"value_path": {
+			filter: `emails[type eq "work" and value co "@example.com"] or ims[type eq "xmpp"]`,
+			wantExpression: &filter.LogicalExpression{
+				Operator: filter.Or,
+				Left: &filter.ValuePathExpression{
+					Path: filter.AttributePath{Name: "emails"},
+					Filter: &filter.LogicalExpression{
+						Operator: filter.And,
+						Left:     &filter.AttributeExpression{Path: filter.AttributePath{Name: "type"}, Operator: filter.Equal, Value: "work"},
+						Right:    &filter.AttributeExpression{Path: filter.AttributePath{Name: "value"}, Operator: filter.Contains, Value: "@example.com"},
+					},
+				},
+				Right: &filter.ValuePathExpression{
+					Path:   filter.AttributePath{Name: "ims"},
+					Filter: &filter.AttributeExpression{Path: filter.AttributePath{Name: "type"}, Operator: filter.Equal, Value: "xmpp"},
+				},
+			},
+		},
+		"not_attribute": {
+			filter: `not pr`,
+			wantExpression: &filter.PresentExpression{
+				Path: filter.AttributePath{Name: "not"},
+			},
+		},
+		"ref_attribute": {
+			filter: `members.$ref sw "https://example.com/"`,
+			wantExpression: &filter.AttributeExpression{
+				Path:     filter.AttributePath{Name: "members", SubAttribute: "$ref"},
+				Operator: filter.StartsWith,
+				Value:    "https://example.com/",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotExpression, err := filter.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(gotExpression, tt.wantExpression) {
+				t.Errorf("gotExpression: %v, wantExpression: %v", gotExpression, tt.wantExpression)
+			}
+
+			wantString := tt.wantString
+			if wantString == "" {
+				wantString = tt.filter
+			}
+
+			if gotString := gotExpression.String(); gotString != wantString {
+				t.Errorf("gotString: %s, wantString: %s", gotString, wantString)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]struct {
+		filter  string
+		wantErr *filter.SyntaxError
+	}{
+		"empty": {
+			filter:  ``,
+			wantErr: &filter.SyntaxError{Position: 1, Message: "expected an attribute path, got end of filter"},
+		},
+		"unbalanced_parentheses": {
+			filter:  `userType eq "Employee" and (emails co "sgnl.com" or emails.value co "sgnl.org"`,
+			wantErr: &filter.SyntaxError{Position: 79, Message: `expected ")", got end of filter`},
+		},
+		"unexpected_closing_parenthesis": {
+			filter:  `title pr)`,
+			wantErr: &filter.SyntaxError{Position: 9, Message: `unexpected ")"`},
+		},
+		"missing_operator": {
+			filter:  `userName "bjensen"`,
+			wantErr: &filter.SyntaxError{Position: 10, Message: `expected an operator after "userName", got "\"bjensen\""`},
+		},
+		"unknown_operator": {
+			filter:  `userName like "bjensen"`,
+			wantErr: &filter.SyntaxError{Position: 10, Message: `unknown operator "like"`},
+		},
+		"missing_value": {
+			filter:  `userName eq`,
+			wantErr: &filter.SyntaxError{Position: 12, Message: "expected a comparison value, got end of filter"},
+		},
+		"unquoted_value": {
+			filter:  `userName eq bjensen`,
+			wantErr: &filter.SyntaxError{Position: 13, Message: `expected a comparison value, got "bjensen"`},
+		},
+		"unterminated_string": {
+			filter:  `userName eq "bjensen`,
+			wantErr: &filter.SyntaxError{Position: 13, Message: "unterminated string"},
+		},
+		"invalid_escape": {
+			filter:  `userName eq "\x"`,
+			wantErr: &filter.SyntaxError{Position: 13, Message: `invalid string "\x"`},
+		},
+		"invalid_number": {
+			filter:  `age gt 1.2.3`,
+			wantErr: &filter.SyntaxError{Position: 8, Message: `invalid number "1.2.3"`},
+		},
+		"invalid_attribute_path": {
+			filter:  `name.givenName.first eq "Barbara"`,
+			wantErr: &filter.SyntaxError{Position: 1, Message: `invalid attribute path "name.givenName.first"`},
+		},
+		"invalid_character": {
+			filter:  `userName eq "a" & title pr`,
+			wantErr: &filter.SyntaxError{Position: 17, Message: `unexpected character '&'`},
+		},
+		"missing_operand": {
+			filter:  `title pr and`,
+			wantErr: &filter.SyntaxError{Position: 13, Message: "expected an attribute path, got end of filter"},
+		},
+		"not_without_parentheses": {
+			filter:  `not title pr`,
+			wantErr: &filter.SyntaxError{Position: 5, Message: `expected "(", got "title"`},
+		},
+		"unclosed_value_path": {
+			filter:  `emails[type eq "work"`,
+			wantErr: &filter.SyntaxError{Position: 22, Message: `expected "]", got end of filter`},
+		},
+		"nested_value_path": {
+			filter:  `emails[type[value eq "a"]]`,
+			wantErr: &filter.SyntaxError{Position: 12, Message: "nested value paths are not allowed"},
+		},
+		"value_path_with_sub_attribute": {
+			filter:  `name.givenName[value eq "a"]`,
+			wantErr: &filter.SyntaxError{Position: 1, Message: `value path "name.givenName" must not have a sub-attribute`},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotExpression, gotErr := filter.Parse(tt.filter)
+
+			if gotExpression != nil {
+				t.Errorf("gotExpression: %v, wantExpression: nil", gotExpression)
+			}
+
+			if !reflect.DeepEqual(gotErr, tt.wantErr) {
+				t.Errorf("gotErr: %v, wantErr: %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,442 @@
+// Copyright 2025 SGNL.ai, Inc.
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SyntaxError is returned when parsing an invalid filter expression.
+type SyntaxError struct {
+	// Position is the 1-based position of the error in the filter expression, in bytes.
+	Position int
+
+	// Message describes the error.
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Position)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenNumber
+	tokenLeftParen
+	tokenRightParen
+	tokenLeftBracket
+	tokenRightBracket
+)
+
+type token struct {
+	kind tokenKind
+
+	// text is the text of the token in the filter expression.
+	text string
+
+	// offset is the 0-based offset of the token in the filter expression.
+	offset int
+}
+
+// describe returns a description of the token for error messages.
+func (t token) describe() string {
+	if t.kind == tokenEOF {
+		return "end of filter"
+	}
+
+	return fmt.Sprintf("%q", t.text)
+}
+
+var delimiterTokens = map[byte]tokenKind{
+	'(': tokenLeftParen,
+	')': tokenRightParen,
+	'[': tokenLeftBracket,
+	']': tokenRightBracket,
+}
+
+var compareOperators = map[string]CompareOperator{
+	string(Equal):              Equal,
+	string(NotEqual):           NotEqual,
+	string(Contains):           Contains,
+	string(StartsWith):         StartsWith,
+	string(EndsWith):           EndsWith,
+	string(GreaterThan):        GreaterThan,
+	string(GreaterThanOrEqual): GreaterThanOrEqual,
+	string(LessThan):           LessThan,
+	string(LessThanOrEqual):    LessThanOrEqual,
+}
+
+// Parse parses a SCIM filter expression. Operators and keywords are case-insensitive, and "and" has
+// a higher precedence than "or". A *SyntaxError is returned if the filter expression is invalid.
+// https://datatracker.ietf.org/doc/html/rfc7644#section-3.4.2.2
+func Parse(filter string) (Expression, error) {
+	p := &parser{
+		input: filter,
+	}
+
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+
+	expression, err := p.parseOr(false)
+	if err != nil {
+		return nil, err
+	}
+
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, p.errorf(next, "unexpected %s", next.describe())
+	}
+
+	return expression, nil
+}
+
+type parser struct {
+	input  string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) *SyntaxError {
+	return &SyntaxError{
+		Position: t.offset + 1,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *parser) expect(kind tokenKind, text string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, p.errorf(t, "expected %q, got %s", text, t.describe())
+	}
+
+	return t, nil
+}
+
+// parseOr parses: andExpr *("or" andExpr).
+// inValuePath is true when parsing the filter of a value path, in which value paths are not allowed.
+func (p *parser) parseOr(inValuePath bool) (Expression, error) {
+	left, err := p.parseAnd(inValuePath)
+	if err != nil {
+		return nil, err
+	}
+
+	for isKeyword(p.peek(), "or") {
+		p.next()
+
+		right, err := p.parseAnd(inValuePath)
+		if err != nil {
+			return nil, err
+		}
+
+		left = &LogicalExpression{Operator: Or, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+// parseAnd parses: unary *("and" unary).
+func (p *parser) parseAnd(inValuePath bool) (Expression, error) {
+	left, err := p.parseUnary(inValuePath)
+	if err != nil {
+		return nil, err
+	}
+
+	for isKeyword(p.peek(), "and") {
+		p.next()
+
+		right, err := p.parseUnary(inValuePath)
+		if err != nil {
+			return nil, err
+		}
+
+		left = &LogicalExpression{Operator: And, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+// parseUnary parses: "not" "(" filter ")" / "(" filter ")" / attrPath "[" valFilter "]" / attrExp.
+func (p *parser) parseUnary(inValuePath bool) (Expression, error) {
+	t := p.peek()
+
+	switch {
+	case t.kind == tokenLeftParen:
+		return p.parseGroup(inValuePath)
+	case isKeyword(t, "not") && !isOperator(p.tokens[p.pos+1]):
+		p.next()
+
+		expression, err := p.parseGroup(inValuePath)
+		if err != nil {
+			return nil, err
+		}
+
+		return &NotExpression{Expression: expression}, nil
+	case t.kind != tokenWord:
+		return nil, p.errorf(t, "expected an attribute path, got %s", t.describe())
+	}
+
+	p.next()
+
+	path, err := p.parseAttributePath(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind == tokenLeftBracket {
+		return p.parseValuePath(t, path, inValuePath)
+	}
+
+	operator := p.next()
+	if operator.kind != tokenWord {
+		return nil, p.errorf(operator, "expected an operator after %q, got %s", t.text, operator.describe())
+	}
+
+	if strings.EqualFold(operator.text, "pr") {
+		return &PresentExpression{Path: path}, nil
+	}
+
+	compareOperator, found := compareOperators[strings.ToLower(operator.text)]
+	if !found {
+		return nil, p.errorf(operator, "unknown operator %q", operator.text)
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return &AttributeExpression{Path: path, Operator: compareOperator, Value: value}, nil
+}
+
+// parseGroup parses: "(" filter ")".
+func (p *parser) parseGroup(inValuePath bool) (Expression, error) {
+	if _, err := p.expect(tokenLeftParen, "("); err != nil {
+		return nil, err
+	}
+
+	expression, err := p.parseOr(inValuePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(tokenRightParen, ")"); err != nil {
+		return nil, err
+	}
+
+	return expression, nil
+}
+
+// parseValuePath parses: attrPath "[" valFilter "]".
+func (p *parser) parseValuePath(t token, path AttributePath, inValuePath bool) (Expression, error) {
+	if inValuePath {
+		return nil, p.errorf(p.peek(), "nested value paths are not allowed")
+	}
+
+	if path.SubAttribute != "" {
+		return nil, p.errorf(t, "value path %q must not have a sub-attribute", t.text)
+	}
+
+	p.next()
+
+	expression, err := p.parseOr(true)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(tokenRightBracket, "]"); err != nil {
+		return nil, err
+	}
+
+	return &ValuePathExpression{Path: path, Filter: expression}, nil
+}
+
+// parseValue parses: compValue = false / null / true / number / string.
+func (p *parser) parseValue() (any, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenString:
+		var value string
+
+		if err := json.Unmarshal([]byte(t.text), &value); err != nil {
+			return nil, p.errorf(t, "invalid string %s", t.text)
+		}
+
+		return value, nil
+	case tokenNumber:
+		if !json.Valid([]byte(t.text)) {
+			return nil, p.errorf(t, "invalid number %q", t.text)
+		}
+
+		return json.Number(t.text), nil
+	case tokenWord:
+		switch strings.ToLower(t.text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	}
+
+	return nil, p.errorf(t, "expected a comparison value, got %s", t.describe())
+}
+
+// parseAttributePath parses: [URI ":"] ATTRNAME *1subAttr.
+// The URI is everything up to the last ":", as URIs may contain dots, e.g. "urn:...:2.0:User:manager.value".
+func (p *parser) parseAttributePath(t token) (AttributePath, error) {
+	var path AttributePath
+
+	name := t.text
+	if i := strings.LastIndexByte(name, ':'); i >= 0 {
+		path.URI, name = name[:i], name[i+1:]
+
+		if path.URI == "" {
+			return path, p.errorf(t, "invalid attribute path %q", t.text)
+		}
+	}
+
+	path.Name, path.SubAttribute, _ = strings.Cut(name, ".")
+
+	if !isAttributeName(path.Name) || strings.Contains(name, ".") && !isAttributeName(path.SubAttribute) {
+		return path, p.errorf(t, "invalid attribute path %q", t.text)
+	}
+
+	return path, nil
+}
+
+// isAttributeName returns whether a string is a valid attribute name: ALPHA *(nameChar).
+// "$ref" is allowed, as used by SCIM references.
+func isAttributeName(name string) bool {
+	name = strings.TrimPrefix(name, "$")
+	if name == "" || !isAlpha(name[0]) {
+		return false
+	}
+
+	for i := 1; i < len(name); i++ {
+		if c := name[i]; !isAlpha(c) && !isDigit(c) && c != '-' && c != '_' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isOperator returns whether a token is an attribute operator, e.g. to parse "not" as an attribute name.
+func isOperator(t token) bool {
+	_, found := compareOperators[strings.ToLower(t.text)]
+
+	return t.kind == tokenWord && (found || strings.EqualFold(t.text, "pr"))
+}
+
+func isKeyword(t token, keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func isAlpha(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordChar(c byte) bool {
+	return isAlpha(c) || isDigit(c) || strings.IndexByte(":._-$", c) >= 0
+}
+
+func isNumberChar(c byte) bool {
+	return isDigit(c) || strings.IndexByte("+-.eE", c) >= 0
+}
+
+// tokenize splits the filter expression into tokens, ending with a tokenEOF token.
+func (p *parser) tokenize() error {
+	input := p.input
+
+	for i := 0; i < len(input); {
+		c := input[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+			continue
+		case delimiterTokens[c] != tokenEOF:
+			p.tokens = append(p.tokens, token{kind: delimiterTokens[c], text: input[i : i+1], offset: i})
+			i++
+
+			continue
+		case c == '"':
+			end, err := scanString(input, i)
+			if err != nil {
+				return err
+			}
+
+			p.tokens = append(p.tokens, token{kind: tokenString, text: input[i:end], offset: i})
+			i = end
+
+			continue
+		}
+
+		var kind tokenKind
+
+		end := i
+
+		switch {
+		case c == '-' || isDigit(c):
+			kind = tokenNumber
+
+			for end < len(input) && isNumberChar(input[end]) {
+				end++
+			}
+		case isWordChar(c):
+			kind = tokenWord
+
+			for end < len(input) && isWordChar(input[end]) {
+				end++
+			}
+		default:
+			return &SyntaxError{Position: i + 1, Message: fmt.Sprintf("unexpected character %q", c)}
+		}
+
+		p.tokens = append(p.tokens, token{kind: kind, text: input[i:end], offset: i})
+		i = end
+	}
+
+	p.tokens = append(p.tokens, token{kind: tokenEOF, offset: len(input)})
+
+	return nil
+}
+
+// scanString returns the offset following the JSON string starting at offset start.
+func scanString(input string, start int) (int, error) {
+	for i := start + 1; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+
+	return 0, &SyntaxError{Position: start + 1, Message: "unterminated string"}
+}
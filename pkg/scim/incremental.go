@@ -0,0 +1,90 @@
+// Copyright 2025 SGNL.ai, Inc.
+package scim
+
+import (
+	"time"
+
+	"github.com/sgnl-ai/sample-adapter/pkg/scim/filter"
+)
+
+// LastModifiedAttribute is the SCIM attribute path of the date and time a resource was last modified.
+// https://datatracker.ietf.org/doc/html/rfc7643#section-3.1
+const LastModifiedAttribute = "meta.lastModified"
+
+// WatermarkHandler is called when an incremental sync of an entity completes, i.e. when the last page
+// is returned, with the IncrementalSyncConfig of the next sync. Its Since is the new high-water mark, i.e.
+// the latest "meta.lastModified" of the resources returned during the sync, or the previous high-water mark
+// if no later resource was returned, and its OverlapSeconds is unchanged.
+//
+// The WatermarkHandler is the only output of the high-water mark, as the adapter does not store it: the caller
+// must write next back into Config.IncrementalSync of the entity, i.e. its Since, in the datasource config of
+// the next sync. Otherwise, the next sync requests the resources modified since the same Since again.
+type WatermarkHandler func(datasourceAddress string, entityExternalID string, next IncrementalSyncConfig)
+
+// IncrementalSyncState is the state of an incremental sync, carried in the cursor between pages.
+type IncrementalSyncState struct {
+	// Watermark is the lower bound of "meta.lastModified" used to filter resources during the sync,
+	// i.e. IncrementalSyncConfig.Since minus the overlap. It is fixed when the sync starts, so that
+	// all the pages are requested with the same filter.
+	Watermark string `json:"watermark,omitempty"`
+
+	// MaxLastModified is the latest "meta.lastModified" of the resources returned so far during the sync.
+	MaxLastModified string `json:"maxLastModified,omitempty"`
+}
+
+// newIncrementalSyncState returns the state of an incremental sync starting from the provided config,
+// which must have been validated.
+func newIncrementalSyncState(config IncrementalSyncConfig) *IncrementalSyncState {
+	if config.Since == "" {
+		return &IncrementalSyncState{}
+	}
+
+	// The config is validated in ValidateGetPageRequest.
+	since, _ := time.Parse(time.RFC3339Nano, config.Since)
+
+	return &IncrementalSyncState{
+		Watermark:       formatLastModified(since.Add(-time.Duration(config.OverlapSeconds) * time.Second)),
+		MaxLastModified: formatLastModified(since),
+	}
+}
+
+// filter returns the filter expression restricting the provided user-supplied filter expression to the
+// resources modified after the watermark, or the filter as is if there is no watermark.
+func (c *IncrementalSyncState) filter(userFilter string) (string, error) {
+	if c.Watermark == "" {
+		return userFilter, nil
+	}
+
+	return filter.Restrict(userFilter, filter.Attr(LastModifiedAttribute).Gt(filter.String(c.Watermark)))
+}
+
+// observe updates MaxLastModified with the "meta.lastModified" of the provided SCIM objects.
+// Objects without a valid "meta.lastModified" are ignored.
+func (c *IncrementalSyncState) observe(objects []map[string]any) {
+	var maxLastModified time.Time
+	if c.MaxLastModified != "" {
+		maxLastModified, _ = time.Parse(time.RFC3339Nano, c.MaxLastModified)
+	}
+
+	for _, object := range objects {
+		meta, _ := object["meta"].(map[string]any)
+		value, _ := meta["lastModified"].(string)
+
+		lastModified, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			continue
+		}
+
+		if lastModified.After(maxLastModified) {
+			maxLastModified = lastModified
+		}
+	}
+
+	if !maxLastModified.IsZero() {
+		c.MaxLastModified = formatLastModified(maxLastModified)
+	}
+}
+
+func formatLastModified(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,291 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// nolint: lll
+package scim_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
+	"github.com/sgnl-ai/sample-adapter/pkg/scim"
+)
+
+// newLastModifiedHandler returns a handler that serves 3 users over 2 pages of 2 users, and records
+// the filter and attributes query parameters of each request.
+func newLastModifiedHandler(filters *[]string, attributes *[]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Users" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		*filters = append(*filters, r.URL.Query().Get("filter"))
+		*attributes = append(*attributes, r.URL.Query().Get("attributes"))
+
+		w.WriteHeader(http.StatusOK)
+
+		switch r.URL.Query().Get("startIndex") {
+		case "1":
+			fmt.Fprint(w, `{
+				"totalResults": 3,
+				"itemsPerPage": 2,
+				"startIndex": 1,
+				"Resources": [
+					{"id": "user1", "meta": {"lastModified": "2025-06-02T08:00:00Z"}},
+					{"id": "user2", "meta": {"lastModified": "2025-06-03T09:30:00.5Z"}}
+				]
+			}`)
+		default:
+			fmt.Fprint(w, `{
+				"totalResults": 3,
+				"itemsPerPage": 1,
+				"startIndex": 3,
+				"Resources": [
+					{"id": "user3", "meta": {"lastModified": "2025-06-03T11:30:00+02:00"}}
+				]
+			}`)
+		}
+	})
+}
+
+func TestAdapterGetPageIncrementalSync(t *testing.T) {
+	tests := map[string]struct {
+		incrementalSync scim.IncrementalSyncConfig
+		filter          string
+		wantFilters     []string
+		wantWatermark   string
+	}{
+		"since_with_overlap_and_filter": {
+			incrementalSync: scim.IncrementalSyncConfig{
+				Since:          "2025-06-01T12:00:00Z",
+				OverlapSeconds: 300,
+			},
+			filter: `userType eq "Employee"`,
+			wantFilters: []string{
+				`userType eq "Employee" and meta.lastModified gt "2025-06-01T11:55:00Z"`,
+				`userType eq "Employee" and meta.lastModified gt "2025-06-01T11:55:00Z"`,
+			},
+			wantWatermark: "2025-06-03T09:30:00.5Z",
+		},
+		"since_with_or_filter": {
+			incrementalSync: scim.IncrementalSyncConfig{
+				Since: "2025-06-01T12:00:00Z",
+			},
+			filter: `userType eq "Employee" or userType eq "Contractor"`,
+			wantFilters: []string{
+				`(userType eq "Employee" or userType eq "Contractor") and meta.lastModified gt "2025-06-01T12:00:00Z"`,
+				`(userType eq "Employee" or userType eq "Contractor") and meta.lastModified gt "2025-06-01T12:00:00Z"`,
+			},
+			wantWatermark: "2025-06-03T09:30:00.5Z",
+		},
+		"since_without_filter": {
+			incrementalSync: scim.IncrementalSyncConfig{
+				Since: "2025-06-01T12:00:00+02:00",
+			},
+			wantFilters: []string{
+				`meta.lastModified gt "2025-06-01T10:00:00Z"`,
+				`meta.lastModified gt "2025-06-01T10:00:00Z"`,
+			},
+			wantWatermark: "2025-06-03T09:30:00.5Z",
+		},
+		"since_later_than_resources": {
+			incrementalSync: scim.IncrementalSyncConfig{
+				Since: "2025-07-01T00:00:00Z",
+			},
+			wantFilters: []string{
+				`meta.lastModified gt "2025-07-01T00:00:00Z"`,
+				`meta.lastModified gt "2025-07-01T00:00:00Z"`,
+			},
+			wantWatermark: "2025-07-01T00:00:00Z",
+		},
+		"initial_full_sync": {
+			incrementalSync: scim.IncrementalSyncConfig{},
+			wantFilters:     []string{"", ""},
+			wantWatermark:   "2025-06-03T09:30:00.5Z",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var gotFilters, gotAttributes []string
+
+			server := httptest.NewTLSServer(newLastModifiedHandler(&gotFilters, &gotAttributes))
+			defer server.Close()
+
+			var gotWatermarks []scim.IncrementalSyncConfig
+
+			adapter := scim.NewAdapter(
+				&scim.Datasource{
+					Client: server.Client(),
+				},
+				scim.WithWatermarkHandler(func(datasourceAddress, entityExternalID string, next scim.IncrementalSyncConfig) {
+					if datasourceAddress != server.URL || entityExternalID != scimUser {
+						t.Errorf("Unexpected watermark for entity %s of %s", entityExternalID, datasourceAddress)
+					}
+
+					gotWatermarks = append(gotWatermarks, next)
+				}),
+			)
+
+			request := &framework.Request[scim.Config]{
+				Address: server.URL,
+				Auth: &framework.DatasourceAuthCredentials{
+					HTTPAuthorization: "Bearer token",
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+							UniqueId:   true,
+						},
+					},
+				},
+				Config: &scim.Config{
+					QueryParams: map[string]scim.QueryParams{
+						scimUser: {
+							Filter: tt.filter,
+						},
+					},
+					IncrementalSync: map[string]scim.IncrementalSyncConfig{
+						scimUser: tt.incrementalSync,
+					},
+				},
+				PageSize: 2,
+			}
+
+			firstPage := adapter.GetPage(context.Background(), request)
+			if firstPage.Error != nil {
+				t.Fatalf("Unexpected error on the first page: %v", firstPage.Error)
+			}
+
+			if firstPage.Success.NextCursor == "" {
+				t.Fatal("Expected a cursor for the second page")
+			}
+
+			if len(gotWatermarks) != 0 {
+				t.Errorf("Watermark reported before the end of the sync: %v", gotWatermarks)
+			}
+
+			request.Cursor = firstPage.Success.NextCursor
+
+			secondPage := adapter.GetPage(context.Background(), request)
+			if secondPage.Error != nil {
+				t.Fatalf("Unexpected error on the second page: %v", secondPage.Error)
+			}
+
+			wantSecondPage := &framework.Page{
+				Objects: []framework.Object{
+					{"id": "user3"},
+				},
+			}
+
+			if !reflect.DeepEqual(secondPage.Success, wantSecondPage) {
+				t.Errorf("gotPage: %v, wantPage: %v", secondPage.Success, wantSecondPage)
+			}
+
+			if !reflect.DeepEqual(gotFilters, tt.wantFilters) {
+				t.Errorf("gotFilters: %q, wantFilters: %q", gotFilters, tt.wantFilters)
+			}
+
+			wantAttributes := []string{"id,meta.lastModified", "id,meta.lastModified"}
+			if !reflect.DeepEqual(gotAttributes, wantAttributes) {
+				t.Errorf("gotAttributes: %q, wantAttributes: %q", gotAttributes, wantAttributes)
+			}
+
+			wantWatermarks := []scim.IncrementalSyncConfig{
+				{Since: tt.wantWatermark, OverlapSeconds: tt.incrementalSync.OverlapSeconds},
+			}
+
+			if !reflect.DeepEqual(gotWatermarks, wantWatermarks) {
+				t.Errorf("gotWatermarks: %v, wantWatermarks: %v", gotWatermarks, wantWatermarks)
+			}
+		})
+	}
+}
+
+func TestAdapterGetPageIncrementalSyncErrors(t *testing.T) {
+	tests := map[string]struct {
+		incrementalSync scim.IncrementalSyncConfig
+		cursor          string
+		wantErr         *framework.Error
+	}{
+		"invalid_since": {
+			incrementalSync: scim.IncrementalSyncConfig{
+				Since: "2025-06-01",
+			},
+			wantErr: &framework.Error{
+				Message: `Invalid incremental sync start "2025-06-01" for entity Users. It must be an RFC 3339 timestamp.`,
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			},
+		},
+		"negative_overlap": {
+			incrementalSync: scim.IncrementalSyncConfig{
+				Since:          "2025-06-01T12:00:00Z",
+				OverlapSeconds: -1,
+			},
+			wantErr: &framework.Error{
+				Message: "The incremental sync overlap for entity Users must not be negative.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			},
+		},
+		"invalid_cursor": {
+			incrementalSync: scim.IncrementalSyncConfig{
+				Since: "2025-06-01T12:00:00Z",
+			},
+			cursor: "not a cursor",
+			wantErr: &framework.Error{
+				Message: "Invalid cursor: illegal base64 data at input byte 3.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_PAGE_REQUEST_CONFIG,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var filters, attributes []string
+
+			server := httptest.NewTLSServer(newLastModifiedHandler(&filters, &attributes))
+			defer server.Close()
+
+			adapter := scim.NewAdapter(&scim.Datasource{
+				Client: server.Client(),
+			})
+
+			gotResponse := adapter.GetPage(context.Background(), &framework.Request[scim.Config]{
+				Address: server.URL,
+				Auth: &framework.DatasourceAuthCredentials{
+					HTTPAuthorization: "Bearer token",
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimUser,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+						},
+					},
+				},
+				Config: &scim.Config{
+					IncrementalSync: map[string]scim.IncrementalSyncConfig{
+						scimUser: tt.incrementalSync,
+					},
+				},
+				PageSize: 2,
+				Cursor:   tt.cursor,
+			})
+
+			if !reflect.DeepEqual(gotResponse.Error, tt.wantErr) {
+				t.Errorf("gotErr: %v, wantErr: %v", gotResponse.Error, tt.wantErr)
+			}
+		})
+	}
+}
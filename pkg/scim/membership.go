@@ -0,0 +1,281 @@
+// Copyright 2025 SGNL.ai, Inc.
+package scim
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+)
+
+const (
+	// UserGroupsAttribute is the attribute of the User resource listing the groups the user is a member of.
+	// A child entity with this external ID is ingested as group memberships.
+	UserGroupsAttribute = "groups"
+
+	// MembershipIDAttribute is the attribute of a group membership child object containing a unique
+	// identifier for the membership, i.e. "{userId}:{groupId}".
+	MembershipIDAttribute = "id"
+
+	// MembershipUserIDAttribute is the attribute of a group membership child object containing the ID
+	// of the user.
+	MembershipUserIDAttribute = "userId"
+
+	// GroupMembersAttribute is the attribute of the Group resource listing the members of the group.
+	// If Config.GroupMembers is set, a child entity with this external ID is ingested as group memberships.
+	GroupMembersAttribute = "members"
+
+	// MembershipGroupIDAttribute is the attribute of a group member child object containing the ID
+	// of the group.
+	MembershipGroupIDAttribute = "groupId"
+)
+
+// ExpandUserGroupMemberships rewrites the `groups` attribute of each User object into one group
+// membership child object per group the user is a member of, if the entity requests a child entity
+// with the UserGroupsAttribute external ID.
+//
+// Each group membership child object contains the attributes of the SCIM `groups` sub-attributes
+// i.e. `value` (the ID of the group), `$ref`, `display` and `type` ("direct" or "indirect"),
+// in addition to MembershipUserIDAttribute and MembershipIDAttribute.
+//
+// Group memberships which are not JSON objects or lack a group ID are ignored.
+// The objects are modified in place, however the original group membership objects are not.
+func ExpandUserGroupMemberships(entity *framework.EntityConfig, objects []map[string]any) {
+	if !hasChildEntity(entity, UserGroupsAttribute) {
+		return
+	}
+
+	for _, object := range objects {
+		userID, _ := object["id"].(string)
+
+		groups, ok := object[UserGroupsAttribute].([]any)
+		if !ok {
+			continue
+		}
+
+		memberships := make([]any, 0, len(groups))
+
+		for _, group := range groups {
+			groupObject, ok := group.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			groupID, _ := groupObject["value"].(string)
+			if groupID == "" {
+				continue
+			}
+
+			membership := make(map[string]any, len(groupObject)+2)
+			for key, value := range groupObject {
+				membership[key] = value
+			}
+
+			membership[MembershipUserIDAttribute] = userID
+			membership[MembershipIDAttribute] = userID + ":" + groupID
+
+			memberships = append(memberships, membership)
+		}
+
+		object[UserGroupsAttribute] = memberships
+	}
+}
+
+// TruncatedGroupMembersHandler is called for each group of an entity with more members than the maximum
+// number of members per group, with the number of members returned by the SCIM server. Only the first
+// maxMembers members of the group are ingested.
+type TruncatedGroupMembersHandler func(
+	datasourceAddress string, entityExternalID string, groupID string, members int, maxMembers int,
+)
+
+// ExpandGroupMembers requests the `members` attribute of each Group object and rewrites it into one
+// group member child object per member of the group, if the entity requests a child entity with the
+// GroupMembersAttribute external ID.
+//
+// Members are requested separately for each group, so the request listing the groups can exclude the
+// `members` attribute, which may be very large. This costs one additional request per group of the page.
+// At most GroupMembersConfig.MaxConcurrentRequests of these requests are in flight at once, and each waits
+// for the datasource's rate limit, if any.
+//
+// Each group member child object contains the attributes of the SCIM `members` sub-attributes
+// i.e. `value` (the ID of the user or group), `$ref`, `display` and `type` ("User" or "Group"),
+// in addition to MembershipGroupIDAttribute and MembershipIDAttribute i.e. "{groupId}:{memberId}".
+// If `type` is not returned by the SCIM server, it is derived from `$ref`, if possible.
+//
+// Members which are not JSON objects or lack an ID are ignored.
+// If a group has more than GroupMembersConfig.MaxMembers members, only the first MaxMembers members are kept,
+// and the group is reported to the adapter's TruncatedGroupMembersHandler.
+func (a *Adapter) ExpandGroupMembers(
+	ctx context.Context,
+	req *Request,
+	request *framework.Request[Config],
+	objects []map[string]any,
+) *framework.Error {
+	if request.Config == nil || request.Config.GroupMembers == nil ||
+		!hasChildEntity(&request.Entity, GroupMembersAttribute) {
+		return nil
+	}
+
+	maxMembers := request.Config.GroupMembers.MaxMembers
+	if maxMembers <= 0 {
+		maxMembers = DefaultMaxGroupMembers
+	}
+
+	maxConcurrentRequests := request.Config.GroupMembers.MaxConcurrentRequests
+	if maxConcurrentRequests <= 0 {
+		maxConcurrentRequests = DefaultGroupMembersConcurrentRequests
+	}
+
+	members, err := a.getGroupMembers(ctx, req, objects, maxConcurrentRequests)
+	if err != nil {
+		return err
+	}
+
+	for i, object := range objects {
+		groupID, _ := object["id"].(string)
+		if groupID == "" {
+			continue
+		}
+
+		if len(members[i]) > maxMembers {
+			if a.TruncatedGroupMembersHandler != nil {
+				a.TruncatedGroupMembersHandler(
+					request.Address, request.Entity.ExternalId, groupID, len(members[i]), maxMembers,
+				)
+			}
+
+			members[i] = members[i][:maxMembers]
+		}
+
+		object[GroupMembersAttribute] = groupMemberships(groupID, members[i])
+	}
+
+	return nil
+}
+
+// getGroupMembers requests the `members` attribute of each Group object with an ID, with at most
+// maxConcurrentRequests requests in flight at once. The members of objects[i] are returned at index i.
+// Once a request fails, no further request is sent, and the error of the first failed request is returned.
+func (a *Adapter) getGroupMembers(
+	ctx context.Context,
+	req *Request,
+	objects []map[string]any,
+	maxConcurrentRequests int,
+) ([][]any, *framework.Error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr *framework.Error
+	)
+
+	members := make([][]any, len(objects))
+	slots := make(chan struct{}, maxConcurrentRequests)
+
+	for i, object := range objects {
+		groupID, _ := object["id"].(string)
+		if groupID == "" {
+			continue
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			groupMembers, err := a.Client.GetGroupMembers(ctx, req, groupID)
+			if err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+
+				if firstErr == nil {
+					firstErr = err
+
+					cancel()
+				}
+
+				return
+			}
+
+			members[i] = groupMembers
+		}()
+	}
+
+	wg.Wait()
+
+	return members, firstErr
+}
+
+// groupMemberships returns one group member child object per member of a group.
+func groupMemberships(groupID string, members []any) []any {
+	memberships := make([]any, 0, len(members))
+
+	for _, member := range members {
+		memberObject, ok := member.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		memberID, _ := memberObject["value"].(string)
+		if memberID == "" {
+			continue
+		}
+
+		membership := make(map[string]any, len(memberObject)+3)
+		for key, value := range memberObject {
+			membership[key] = value
+		}
+
+		if _, found := membership["type"]; !found {
+			if memberType := memberTypeFromRef(membership["$ref"]); memberType != "" {
+				membership["type"] = memberType
+			}
+		}
+
+		membership[MembershipGroupIDAttribute] = groupID
+		membership[MembershipIDAttribute] = groupID + ":" + memberID
+
+		memberships = append(memberships, membership)
+	}
+
+	return memberships
+}
+
+// memberTypeFromRef returns the type of a group member, i.e. "User" or "Group", from the URI of the member,
+// or "" if the type cannot be derived.
+func memberTypeFromRef(ref any) string {
+	refString, _ := ref.(string)
+
+	switch {
+	case strings.Contains(refString, "/Users/"):
+		return "User"
+	case strings.Contains(refString, "/Groups/"):
+		return "Group"
+	default:
+		return ""
+	}
+}
+
+// hasChildEntity returns true if the entity has a child entity with the provided external ID.
+func hasChildEntity(entity *framework.EntityConfig, externalID string) bool {
+	for _, childEntity := range entity.ChildEntities {
+		if childEntity.ExternalId == externalID {
+			return true
+		}
+	}
+
+	return false
+}
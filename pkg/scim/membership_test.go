@@ -0,0 +1,415 @@
+// Copyright 2025 SGNL.ai, Inc.
+package scim_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	"github.com/sgnl-ai/sample-adapter/pkg/scim"
+)
+
+func TestExpandUserGroupMemberships(t *testing.T) {
+	groupsEntity := &framework.EntityConfig{
+		ExternalId: scimUser,
+		ChildEntities: []*framework.EntityConfig{
+			{
+				ExternalId: "groups",
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		entity      *framework.EntityConfig
+		objects     []map[string]any
+		wantObjects []map[string]any
+	}{
+		"expands_memberships": {
+			entity: groupsEntity,
+			objects: []map[string]any{
+				{
+					"id": "user1",
+					"groups": []any{
+						map[string]any{"value": "group1", "type": "indirect"},
+					},
+				},
+			},
+			wantObjects: []map[string]any{
+				{
+					"id": "user1",
+					"groups": []any{
+						map[string]any{"id": "user1:group1", "userId": "user1", "value": "group1", "type": "indirect"},
+					},
+				},
+			},
+		},
+		"ignores_invalid_memberships": {
+			entity: groupsEntity,
+			objects: []map[string]any{
+				{
+					"id": "user1",
+					"groups": []any{
+						"group1",
+						map[string]any{"display": "Group Without ID"},
+						map[string]any{"value": "group2"},
+					},
+				},
+				{
+					"id": "user2",
+				},
+			},
+			wantObjects: []map[string]any{
+				{
+					"id": "user1",
+					"groups": []any{
+						map[string]any{"id": "user1:group2", "userId": "user1", "value": "group2"},
+					},
+				},
+				{
+					"id": "user2",
+				},
+			},
+		},
+		"groups_child_entity_not_requested": {
+			entity: &framework.EntityConfig{
+				ExternalId: scimUser,
+			},
+			objects: []map[string]any{
+				{
+					"id": "user1",
+					"groups": []any{
+						map[string]any{"value": "group1"},
+					},
+				},
+			},
+			wantObjects: []map[string]any{
+				{
+					"id": "user1",
+					"groups": []any{
+						map[string]any{"value": "group1"},
+					},
+				},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			scim.ExpandUserGroupMemberships(tt.entity, tt.objects)
+
+			if !reflect.DeepEqual(tt.objects, tt.wantObjects) {
+				t.Errorf("gotObjects: %v, wantObjects: %v", tt.objects, tt.wantObjects)
+			}
+		})
+	}
+}
+
+func TestAdapterGetPageGroupMembers(t *testing.T) {
+	server := httptest.NewTLSServer(TestServerHandler)
+	defer server.Close()
+
+	var gotTruncated []string
+
+	adapter := scim.NewAdapter(
+		&scim.Datasource{
+			Client: server.Client(),
+		},
+		scim.WithTruncatedGroupMembersHandler(func(_, entityExternalID, groupID string, members, maxMembers int) {
+			gotTruncated = append(gotTruncated, fmt.Sprintf("%s %s: %d > %d", entityExternalID, groupID, members, maxMembers))
+		}),
+	)
+
+	groupEntity := framework.EntityConfig{
+		ExternalId: scimGroup,
+		Attributes: []*framework.AttributeConfig{
+			{
+				ExternalId: "id",
+				Type:       framework.AttributeTypeString,
+			},
+			{
+				ExternalId: "displayName",
+				Type:       framework.AttributeTypeString,
+			},
+		},
+		ChildEntities: []*framework.EntityConfig{
+			{
+				ExternalId: "members",
+				Attributes: []*framework.AttributeConfig{
+					{
+						ExternalId: "id",
+						Type:       framework.AttributeTypeString,
+						UniqueId:   true,
+					},
+					{
+						ExternalId: "groupId",
+						Type:       framework.AttributeTypeString,
+					},
+					{
+						ExternalId: "value",
+						Type:       framework.AttributeTypeString,
+					},
+					{
+						ExternalId: "type",
+						Type:       framework.AttributeTypeString,
+					},
+				},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		config         *scim.Config
+		cursor         string
+		wantResponse   framework.Response
+		wantNextCursor *scim.Cursor
+		wantTruncated  []string
+	}{
+		"first_page": {
+			config: &scim.Config{
+				GroupMembers: &scim.GroupMembersConfig{
+					MaxConcurrentRequests: 2,
+				},
+			},
+			wantResponse: framework.Response{
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{
+							"id":          "c3a26dd3-27a0-4dec-a2ac-ce211e105f97",
+							"displayName": "Group A",
+							"members": []framework.Object{
+								{
+									"id":      "c3a26dd3-27a0-4dec-a2ac-ce211e105f97:6c5bb468-14b2-4183-baf2-06d523e03bd3",
+									"groupId": "c3a26dd3-27a0-4dec-a2ac-ce211e105f97",
+									"value":   "6c5bb468-14b2-4183-baf2-06d523e03bd3",
+									"type":    "Group",
+								},
+							},
+						},
+						{
+							"id":          "6c5bb468-14b2-4183-baf2-06d523e03bd3",
+							"displayName": "Group B",
+						},
+					},
+				},
+			},
+			wantNextCursor: &scim.Cursor{
+				PagingMode:   scim.PagingModeIndex,
+				StartIndex:   3,
+				TotalResults: 5,
+			},
+		},
+		"last_page": {
+			config: &scim.Config{
+				GroupMembers: &scim.GroupMembersConfig{
+					MaxMembers: 2,
+				},
+			},
+			cursor: "5",
+			wantResponse: framework.Response{
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{
+							"id":          "e9e30dba-f08f-4109-8486-d5c6a331660a",
+							"displayName": "Tour Guides",
+							"members": []framework.Object{
+								{
+									"id":      "e9e30dba-f08f-4109-8486-d5c6a331660a:2819c223-7f76-453a-919d-413861904646",
+									"groupId": "e9e30dba-f08f-4109-8486-d5c6a331660a",
+									"value":   "2819c223-7f76-453a-919d-413861904646",
+									"type":    "User",
+								},
+								{
+									"id":      "e9e30dba-f08f-4109-8486-d5c6a331660a:6c5bb468-14b2-4183-baf2-06d523e03bd3",
+									"groupId": "e9e30dba-f08f-4109-8486-d5c6a331660a",
+									"value":   "6c5bb468-14b2-4183-baf2-06d523e03bd3",
+									"type":    "Group",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"group_exceeds_max_members": {
+			config: &scim.Config{
+				GroupMembers: &scim.GroupMembersConfig{
+					MaxMembers: 1,
+				},
+			},
+			cursor: "5",
+			wantResponse: framework.Response{
+				Success: &framework.Page{
+					Objects: []framework.Object{
+						{
+							"id":          "e9e30dba-f08f-4109-8486-d5c6a331660a",
+							"displayName": "Tour Guides",
+							"members": []framework.Object{
+								{
+									"id":      "e9e30dba-f08f-4109-8486-d5c6a331660a:2819c223-7f76-453a-919d-413861904646",
+									"groupId": "e9e30dba-f08f-4109-8486-d5c6a331660a",
+									"value":   "2819c223-7f76-453a-919d-413861904646",
+									"type":    "User",
+								},
+							},
+						},
+					},
+				},
+			},
+			wantTruncated: []string{"Groups e9e30dba-f08f-4109-8486-d5c6a331660a: 2 > 1"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			request := &framework.Request[scim.Config]{
+				Address: server.URL,
+				Auth: &framework.DatasourceAuthCredentials{
+					Basic: &framework.BasicAuthCredentials{
+						Username: testUsername,
+						Password: testPassword,
+					},
+				},
+				Entity:   groupEntity,
+				Config:   tt.config,
+				PageSize: 2,
+				Cursor:   tt.cursor,
+			}
+
+			if tt.wantNextCursor != nil {
+				tt.wantResponse.Success.NextCursor = encodeTestCursor(request, tt.wantNextCursor)
+			}
+
+			gotTruncated = nil
+
+			gotResponse := adapter.GetPage(context.Background(), request)
+
+			if !reflect.DeepEqual(gotResponse, tt.wantResponse) {
+				t.Errorf("gotResponse: %v, wantResponse: %v", gotResponse, tt.wantResponse)
+			}
+
+			if !reflect.DeepEqual(gotTruncated, tt.wantTruncated) {
+				t.Errorf("gotTruncated: %v, wantTruncated: %v", gotTruncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestAdapterGetPageGroupMembersConcurrentRequests(t *testing.T) {
+	tests := map[string]struct {
+		failedGroup  string
+		wantErrorMsg string
+	}{
+		"all_groups_expanded": {},
+		"failed_group_fails_page": {
+			failedGroup:  "group-3",
+			wantErrorMsg: "Datasource encountered an internal error. Contact datasource support for assistance.",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var inFlight, maxInFlight atomic.Int32
+
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/Groups" {
+					w.Write([]byte(`{"totalResults": 4, "Resources": [{"id": "group-1"}, {"id": "group-2"}, {"id": "group-3"}, {"id": "group-4"}]}`))
+
+					return
+				}
+
+				current := inFlight.Add(1)
+				defer inFlight.Add(-1)
+
+				for {
+					previous := maxInFlight.Load()
+					if current <= previous || maxInFlight.CompareAndSwap(previous, current) {
+						break
+					}
+				}
+
+				time.Sleep(20 * time.Millisecond)
+
+				groupID := strings.TrimPrefix(r.URL.Path, "/Groups/")
+				if groupID == tt.failedGroup {
+					w.WriteHeader(http.StatusInternalServerError)
+
+					return
+				}
+
+				fmt.Fprintf(w, `{"members": [{"value": "user-%s", "type": "User"}]}`, groupID)
+			}))
+			defer server.Close()
+
+			adapter := scim.NewAdapter(&scim.Datasource{
+				Client: server.Client(),
+			})
+
+			gotResponse := adapter.GetPage(context.Background(), &framework.Request[scim.Config]{
+				Address: server.URL,
+				Auth: &framework.DatasourceAuthCredentials{
+					HTTPAuthorization: "Bearer token",
+				},
+				Entity: framework.EntityConfig{
+					ExternalId: scimGroup,
+					Attributes: []*framework.AttributeConfig{
+						{
+							ExternalId: "id",
+							Type:       framework.AttributeTypeString,
+						},
+					},
+					ChildEntities: []*framework.EntityConfig{
+						{
+							ExternalId: "members",
+							Attributes: []*framework.AttributeConfig{
+								{
+									ExternalId: "id",
+									Type:       framework.AttributeTypeString,
+									UniqueId:   true,
+								},
+							},
+						},
+					},
+				},
+				Config: &scim.Config{
+					GroupMembers: &scim.GroupMembersConfig{
+						MaxConcurrentRequests: 2,
+					},
+				},
+				PageSize: 4,
+			})
+
+			if got := maxInFlight.Load(); got > 2 {
+				t.Errorf("got %d concurrent member requests, want at most 2", got)
+			}
+
+			if tt.wantErrorMsg != "" {
+				if gotResponse.Error == nil || gotResponse.Error.Message != tt.wantErrorMsg {
+					t.Fatalf("gotError: %v, wantErrorMsg: %s", gotResponse.Error, tt.wantErrorMsg)
+				}
+
+				return
+			}
+
+			if gotResponse.Error != nil {
+				t.Fatalf("Unexpected error: %v", gotResponse.Error)
+			}
+
+			for _, object := range gotResponse.Success.Objects {
+				wantMembers := []framework.Object{
+					{"id": fmt.Sprintf("%s:user-%s", object["id"], object["id"])},
+				}
+
+				if !reflect.DeepEqual(object["members"], wantMembers) {
+					t.Errorf("gotMembers: %v, wantMembers: %v", object["members"], wantMembers)
+				}
+			}
+		})
+	}
+}
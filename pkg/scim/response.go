@@ -0,0 +1,315 @@
+// Copyright 2025 SGNL.ai, Inc.
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
+)
+
+// ResponseTooLargeError is returned when reading a response body larger than the maximum response body size.
+type ResponseTooLargeError struct {
+	// MaxBytes is the maximum response body size.
+	MaxBytes int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds the maximum size of %d bytes", e.MaxBytes)
+}
+
+// errInvalidListResponse is returned when decoding a response body which is valid JSON, but not a ListResponse.
+var errInvalidListResponse = errors.New("invalid SCIM ListResponse")
+
+// maxBytesReader reads at most a maximum number of bytes from a reader, and fails with a ResponseTooLargeError
+// if the reader contains more bytes.
+type maxBytesReader struct {
+	r        io.Reader
+	maxBytes int64
+
+	// remaining is the number of bytes which can still be read, or -1 once the maximum has been exceeded.
+	remaining int64
+}
+
+// NewMaxBytesReader returns a reader which reads at most maxBytes bytes from r, and fails with
+// a ResponseTooLargeError if r contains more bytes.
+// If maxBytes is not positive, r is returned as is.
+func NewMaxBytesReader(r io.Reader, maxBytes int64) io.Reader {
+	if maxBytes <= 0 {
+		return r
+	}
+
+	return &maxBytesReader{
+		r:         r,
+		maxBytes:  maxBytes,
+		remaining: maxBytes,
+	}
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if m.remaining < 0 {
+		return 0, &ResponseTooLargeError{MaxBytes: m.maxBytes}
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// Read one more byte than remaining to detect whether the maximum is exceeded.
+	if int64(len(p)) > m.remaining+1 {
+		p = p[:m.remaining+1]
+	}
+
+	n, err := m.r.Read(p)
+	if int64(n) <= m.remaining {
+		m.remaining -= int64(n)
+
+		return n, err
+	}
+
+	n = int(m.remaining)
+	m.remaining = -1
+
+	return n, &ResponseTooLargeError{MaxBytes: m.maxBytes}
+}
+
+// ParseResponse parses a SCIM ListResponse and returns its resources, its total number of results and
+// the cursor for the next page, if any, according to the provided paging mode.
+// If the paging mode is PagingModeAuto, the paging mode is detected from the response and returned.
+//
+// When using index paging, startIndex is the start index of the requested page, or 0 if unknown.
+// The next start index is computed from the number of resources actually returned, as SCIM servers may
+// omit or misreport "itemsPerPage". An error is returned if the SCIM server returns a page starting at
+// another index than requested, or returns no resources before the reported total number of results,
+// which would never complete the sync.
+//
+// The response body is decoded as a stream, one resource at a time, so the body is never held in memory
+// as a whole. The size of the body should be bounded with NewMaxBytesReader.
+func ParseResponse(
+	body io.Reader,
+	pageSize int64,
+	pagingMode PagingMode,
+	startIndex int64,
+) (
+	objects []map[string]any,
+	totalResults int64,
+	nextCursor string,
+	resolvedPagingMode PagingMode,
+	err *framework.Error,
+) {
+	scimResponse, decodeErr := decodeListResponse(json.NewDecoder(body))
+	if decodeErr != nil {
+		return nil, 0, "", "", responseDecodeError(decodeErr)
+	}
+
+	returned := int64(len(scimResponse.Resources))
+
+	if scimResponse.ItemsPerPage > pageSize || returned > pageSize {
+		return nil, 0, "", "", &framework.Error{
+			Message: fmt.Sprintf(
+				"SCIM SoR returned more than the requested page size: %v.", max(scimResponse.ItemsPerPage, returned),
+			),
+			Code: api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_FAILED,
+		}
+	}
+
+	// A cursor-paged response contains a "nextCursor" unless it is the last page, and never contains
+	// a "startIndex", which is required in index-paged responses.
+	if pagingMode == PagingModeAuto {
+		if scimResponse.NextCursor == "" && scimResponse.StartIndex > 0 {
+			pagingMode = PagingModeIndex
+			startIndex = 1
+		} else {
+			pagingMode = PagingModeCursor
+		}
+	}
+
+	if pagingMode == PagingModeCursor {
+		return scimResponse.Resources, scimResponse.TotalResults, scimResponse.NextCursor, pagingMode, nil
+	}
+
+	nextStartIndex, frameworkErr := nextIndexPage(scimResponse, startIndex)
+	if frameworkErr != nil {
+		return nil, 0, "", "", frameworkErr
+	}
+
+	if nextStartIndex > 0 {
+		nextCursor = strconv.FormatInt(nextStartIndex, 10)
+	}
+
+	return scimResponse.Resources, scimResponse.TotalResults, nextCursor, pagingMode, nil
+}
+
+// nextIndexPage returns the start index of the page following an index-paged response to a request
+// for the page at startIndex, or 0 if this is the last page.
+func nextIndexPage(scimResponse *Response, startIndex int64) (int64, *framework.Error) {
+	// Some SCIM servers omit "startIndex", in which case the requested start index is assumed.
+	responseStartIndex := scimResponse.StartIndex
+	if responseStartIndex == 0 {
+		responseStartIndex = startIndex
+	}
+
+	if startIndex > 0 && responseStartIndex != startIndex {
+		return 0, &framework.Error{
+			Message: fmt.Sprintf(
+				"SCIM SoR returned a page starting at index %d instead of the requested start index %d.",
+				responseStartIndex, startIndex,
+			),
+			Code: api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_FAILED,
+		}
+	}
+
+	// Start indexes are 1-based.
+	if responseStartIndex < 1 {
+		responseStartIndex = 1
+	}
+
+	returned := int64(len(scimResponse.Resources))
+	nextStartIndex := responseStartIndex + returned
+
+	// The last page is reached once all the results have been returned. If the total number of results
+	// changed since the previous page, this is based on the total number of results of this page.
+	if nextStartIndex > scimResponse.TotalResults {
+		return 0, nil
+	}
+
+	// The same page would be requested forever.
+	if returned == 0 {
+		return 0, &framework.Error{
+			Message: fmt.Sprintf(
+				"SCIM SoR returned no resources at start index %d, but reported %d total results.",
+				responseStartIndex, scimResponse.TotalResults,
+			),
+			Code: api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_FAILED,
+		}
+	}
+
+	return nextStartIndex, nil
+}
+
+// decodeListResponse decodes a SCIM ListResponse from a stream of JSON tokens, decoding the resources
+// one at a time. Attribute names are matched case-insensitively, as by json.Unmarshal.
+// https://datatracker.ietf.org/doc/html/rfc7644#section-3.4.2
+func decodeListResponse(dec *json.Decoder) (*Response, error) {
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+
+	response := &Response{}
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		key, _ := token.(string)
+
+		switch {
+		case strings.EqualFold(key, "Resources"):
+			err = decodeResources(dec, response)
+		case strings.EqualFold(key, "totalResults"):
+			err = dec.Decode(&response.TotalResults)
+		case strings.EqualFold(key, "startIndex"):
+			err = dec.Decode(&response.StartIndex)
+		case strings.EqualFold(key, "itemsPerPage"):
+			err = dec.Decode(&response.ItemsPerPage)
+		case strings.EqualFold(key, "nextCursor"):
+			err = dec.Decode(&response.NextCursor)
+		default:
+			var ignored json.RawMessage
+
+			err = dec.Decode(&ignored)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// decodeResources decodes the "Resources" array of a ListResponse one resource at a time.
+func decodeResources(dec *json.Decoder, response *Response) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	// Resources may be null if there are no results.
+	if token == nil {
+		return nil
+	}
+
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("%w: Resources must be an array", errInvalidListResponse)
+	}
+
+	for dec.More() {
+		var resource map[string]any
+
+		if err := dec.Decode(&resource); err != nil {
+			return err
+		}
+
+		response.Resources = append(response.Resources, resource)
+	}
+
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("%w: expected %q, got %v", errInvalidListResponse, want, token)
+	}
+
+	return nil
+}
+
+// responseDecodeError returns the framework.Error for an error decoding a response body.
+func responseDecodeError(err error) *framework.Error {
+	var (
+		tooLargeErr  *ResponseTooLargeError
+		syntaxErr    *json.SyntaxError
+		typeErr      *json.UnmarshalTypeError
+		unmarshalErr = errors.As(err, &syntaxErr) || errors.As(err, &typeErr) ||
+			errors.Is(err, errInvalidListResponse) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+	)
+
+	switch {
+	case errors.As(err, &tooLargeErr):
+		return &framework.Error{
+			Message: fmt.Sprintf(
+				"The datasource response exceeds the maximum size of %d bytes. "+
+					"Reduce the page size or increase maxResponseBodyBytes in the datasource config.",
+				tooLargeErr.MaxBytes,
+			),
+			Code: api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_FAILED,
+		}
+	case unmarshalErr:
+		return &framework.Error{
+			Message: fmt.Sprintf("Failed to unmarshal the datasource response: %v.", err),
+			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+		}
+	default:
+		return &framework.Error{
+			Message: fmt.Sprintf("Failed to read response body: %v.", err),
+			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_FAILED,
+		}
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// nolint: lll
+package scim_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
+	"github.com/sgnl-ai/sample-adapter/pkg/scim"
+)
+
+func TestParseResponse(t *testing.T) {
+	tests := map[string]struct {
+		body               string
+		maxBytes           int64
+		pageSize           int64
+		pagingMode         scim.PagingMode
+		startIndex         int64
+		wantObjects        []map[string]any
+		wantTotalResults   int64
+		wantNextCursor     string
+		wantResolvedPaging scim.PagingMode
+		wantErr            *framework.Error
+	}{
+		"index_paging": {
+			body: `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+				"totalResults": 3,
+				"itemsPerPage": 2,
+				"startIndex": 1,
+				"Resources": [
+					{"id": "user1", "emails": [{"value": "a@example.com"}]},
+					{"id": "user2", "meta": {"resourceType": "User"}}
+				]
+			}`,
+			pageSize:   2,
+			pagingMode: scim.PagingModeIndex,
+			wantObjects: []map[string]any{
+				{"id": "user1", "emails": []any{map[string]any{"value": "a@example.com"}}},
+				{"id": "user2", "meta": map[string]any{"resourceType": "User"}},
+			},
+			wantNextCursor:     "3",
+			wantTotalResults:   3,
+			wantResolvedPaging: scim.PagingModeIndex,
+		},
+		"index_paging_last_page": {
+			body:               `{"totalResults": 3, "itemsPerPage": 1, "startIndex": 3, "Resources": [{"id": "user3"}]}`,
+			pageSize:           2,
+			pagingMode:         scim.PagingModeIndex,
+			wantObjects:        []map[string]any{{"id": "user3"}},
+			wantTotalResults:   3,
+			wantResolvedPaging: scim.PagingModeIndex,
+		},
+		"index_paging_missing_items_per_page": {
+			body:               `{"totalResults": 5, "startIndex": 3, "Resources": [{"id": "user3"}, {"id": "user4"}]}`,
+			pageSize:           2,
+			pagingMode:         scim.PagingModeIndex,
+			startIndex:         3,
+			wantObjects:        []map[string]any{{"id": "user3"}, {"id": "user4"}},
+			wantNextCursor:     "5",
+			wantTotalResults:   5,
+			wantResolvedPaging: scim.PagingModeIndex,
+		},
+		"index_paging_fewer_resources_than_items_per_page": {
+			body:               `{"totalResults": 5, "itemsPerPage": 2, "startIndex": 3, "Resources": [{"id": "user3"}]}`,
+			pageSize:           2,
+			pagingMode:         scim.PagingModeIndex,
+			startIndex:         3,
+			wantObjects:        []map[string]any{{"id": "user3"}},
+			wantNextCursor:     "4",
+			wantTotalResults:   5,
+			wantResolvedPaging: scim.PagingModeIndex,
+		},
+		"index_paging_missing_start_index": {
+			body:               `{"totalResults": 5, "itemsPerPage": 2, "Resources": [{"id": "user3"}, {"id": "user4"}]}`,
+			pageSize:           2,
+			pagingMode:         scim.PagingModeIndex,
+			startIndex:         3,
+			wantObjects:        []map[string]any{{"id": "user3"}, {"id": "user4"}},
+			wantNextCursor:     "5",
+			wantTotalResults:   5,
+			wantResolvedPaging: scim.PagingModeIndex,
+		},
+		"index_paging_start_index_mismatch": {
+			body:       `{"totalResults": 5, "itemsPerPage": 2, "startIndex": 1, "Resources": [{"id": "user1"}, {"id": "user2"}]}`,
+			pageSize:   2,
+			pagingMode: scim.PagingModeIndex,
+			startIndex: 3,
+			wantErr: &framework.Error{
+				Message: "SCIM SoR returned a page starting at index 1 instead of the requested start index 3.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_FAILED,
+			},
+		},
+		"index_paging_no_progress": {
+			body:       `{"totalResults": 5, "itemsPerPage": 0, "startIndex": 3, "Resources": []}`,
+			pageSize:   2,
+			pagingMode: scim.PagingModeIndex,
+			startIndex: 3,
+			wantErr: &framework.Error{
+				Message: "SCIM SoR returned no resources at start index 3, but reported 5 total results.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_FAILED,
+			},
+		},
+		// Resources were deleted since the previous page, so the sync completes early.
+		"index_paging_total_results_decreased": {
+			body:               `{"totalResults": 2, "itemsPerPage": 0, "startIndex": 3, "Resources": []}`,
+			pageSize:           2,
+			pagingMode:         scim.PagingModeIndex,
+			startIndex:         3,
+			wantTotalResults:   2,
+			wantResolvedPaging: scim.PagingModeIndex,
+		},
+		"more_resources_than_page_size": {
+			body:       `{"totalResults": 5, "startIndex": 1, "Resources": [{"id": "user1"}, {"id": "user2"}, {"id": "user3"}]}`,
+			pageSize:   2,
+			pagingMode: scim.PagingModeIndex,
+			startIndex: 1,
+			wantErr: &framework.Error{
+				Message: "SCIM SoR returned more than the requested page size: 3.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_FAILED,
+			},
+		},
+		"cursor_paging": {
+			body:               `{"Resources": [{"id": "user1"}], "itemsPerPage": 1, "nextCursor": "abc"}`,
+			pageSize:           2,
+			pagingMode:         scim.PagingModeCursor,
+			wantObjects:        []map[string]any{{"id": "user1"}},
+			wantNextCursor:     "abc",
+			wantResolvedPaging: scim.PagingModeCursor,
+		},
+		"auto_paging_detects_index_paging": {
+			body:               `{"totalResults": 3, "itemsPerPage": 1, "startIndex": 1, "Resources": [{"id": "user1"}]}`,
+			pageSize:           1,
+			pagingMode:         scim.PagingModeAuto,
+			wantObjects:        []map[string]any{{"id": "user1"}},
+			wantNextCursor:     "2",
+			wantTotalResults:   3,
+			wantResolvedPaging: scim.PagingModeIndex,
+		},
+		"case_insensitive_attribute_names": {
+			body:               `{"TOTALRESULTS": 3, "ItemsPerPage": 1, "StartIndex": 1, "resources": [{"id": "user1"}]}`,
+			pageSize:           1,
+			pagingMode:         scim.PagingModeIndex,
+			wantObjects:        []map[string]any{{"id": "user1"}},
+			wantNextCursor:     "2",
+			wantTotalResults:   3,
+			wantResolvedPaging: scim.PagingModeIndex,
+		},
+		"null_resources": {
+			body:               `{"totalResults": 0, "startIndex": 1, "Resources": null}`,
+			pageSize:           1,
+			pagingMode:         scim.PagingModeIndex,
+			wantResolvedPaging: scim.PagingModeIndex,
+		},
+		"missing_resources": {
+			body:               `{"totalResults": 0, "startIndex": 1}`,
+			pageSize:           1,
+			pagingMode:         scim.PagingModeIndex,
+			wantResolvedPaging: scim.PagingModeIndex,
+		},
+		"within_max_bytes": {
+			body:               `{"startIndex": 1, "Resources": []}`,
+			maxBytes:           int64(len(`{"startIndex": 1, "Resources": []}`)),
+			pageSize:           1,
+			pagingMode:         scim.PagingModeIndex,
+			wantResolvedPaging: scim.PagingModeIndex,
+		},
+		"exceeds_max_bytes": {
+			body:       `{"Resources": [{"id": "user1", "description": "` + strings.Repeat("a", 1024) + `"}]}`,
+			maxBytes:   512,
+			pageSize:   1,
+			pagingMode: scim.PagingModeIndex,
+			wantErr: &framework.Error{
+				Message: "The datasource response exceeds the maximum size of 512 bytes. Reduce the page size or increase maxResponseBodyBytes in the datasource config.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_FAILED,
+			},
+		},
+		"more_than_page_size": {
+			body:       `{"itemsPerPage": 3, "Resources": []}`,
+			pageSize:   2,
+			pagingMode: scim.PagingModeIndex,
+			wantErr: &framework.Error{
+				Message: "SCIM SoR returned more than the requested page size: 3.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_FAILED,
+			},
+		},
+		"invalid_json": {
+			body:       `{"Resources": [{"id": }]}`,
+			pageSize:   1,
+			pagingMode: scim.PagingModeIndex,
+			wantErr: &framework.Error{
+				Message: "Failed to unmarshal the datasource response: invalid character '}' looking for beginning of value.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+		},
+		"truncated_json": {
+			body:       `{"Resources": [{"id": "user1"}`,
+			pageSize:   1,
+			pagingMode: scim.PagingModeIndex,
+			wantErr: &framework.Error{
+				Message: "Failed to unmarshal the datasource response: unexpected end of JSON input.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+		},
+		"not_an_object": {
+			body:       `null`,
+			pageSize:   1,
+			pagingMode: scim.PagingModeIndex,
+			wantErr: &framework.Error{
+				Message: `Failed to unmarshal the datasource response: invalid SCIM ListResponse: expected "{", got <nil>.`,
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+		},
+		"resources_not_an_array": {
+			body:       `{"Resources": {"id": "user1"}}`,
+			pageSize:   1,
+			pagingMode: scim.PagingModeIndex,
+			wantErr: &framework.Error{
+				Message: "Failed to unmarshal the datasource response: invalid SCIM ListResponse: Resources must be an array.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+		},
+		"resource_not_an_object": {
+			body:       `{"Resources": ["user1"]}`,
+			pageSize:   1,
+			pagingMode: scim.PagingModeIndex,
+			wantErr: &framework.Error{
+				Message: "Failed to unmarshal the datasource response: json: cannot unmarshal string into Go value of type map[string]interface {}.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			body := scim.NewMaxBytesReader(strings.NewReader(tt.body), tt.maxBytes)
+
+			gotObjects, gotTotalResults, gotNextCursor, gotResolvedPaging, gotErr := scim.ParseResponse(body, tt.pageSize, tt.pagingMode, tt.startIndex)
+
+			if !reflect.DeepEqual(gotObjects, tt.wantObjects) {
+				t.Errorf("gotObjects: %v, wantObjects: %v", gotObjects, tt.wantObjects)
+			}
+
+			if gotTotalResults != tt.wantTotalResults {
+				t.Errorf("gotTotalResults: %v, wantTotalResults: %v", gotTotalResults, tt.wantTotalResults)
+			}
+
+			if gotNextCursor != tt.wantNextCursor {
+				t.Errorf("gotNextCursor: %v, wantNextCursor: %v", gotNextCursor, tt.wantNextCursor)
+			}
+
+			if gotResolvedPaging != tt.wantResolvedPaging {
+				t.Errorf("gotResolvedPaging: %v, wantResolvedPaging: %v", gotResolvedPaging, tt.wantResolvedPaging)
+			}
+
+			if !reflect.DeepEqual(gotErr, tt.wantErr) {
+				t.Errorf("gotErr: %v, wantErr: %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaxBytesReader(t *testing.T) {
+	tests := map[string]struct {
+		body     string
+		maxBytes int64
+		wantBody string
+		wantErr  bool
+	}{
+		"shorter_than_max": {
+			body:     "abc",
+			maxBytes: 4,
+			wantBody: "abc",
+		},
+		"exactly_max": {
+			body:     "abcd",
+			maxBytes: 4,
+			wantBody: "abcd",
+		},
+		"longer_than_max": {
+			body:     "abcde",
+			maxBytes: 4,
+			wantBody: "abcd",
+			wantErr:  true,
+		},
+		"unbounded": {
+			body:     "abcde",
+			maxBytes: 0,
+			wantBody: "abcde",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotBody, err := io.ReadAll(scim.NewMaxBytesReader(strings.NewReader(tt.body), tt.maxBytes))
+
+			var tooLargeErr *scim.ResponseTooLargeError
+			if gotErr := errors.As(err, &tooLargeErr); gotErr != tt.wantErr {
+				t.Errorf("gotErr: %v, wantErr: %v", err, tt.wantErr)
+			}
+
+			if string(gotBody) != tt.wantBody {
+				t.Errorf("gotBody: %q, wantBody: %q", gotBody, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestAdapterGetPageMaxResponseBodyBytes(t *testing.T) {
+	server := httptest.NewTLSServer(TestServerHandler)
+	defer server.Close()
+
+	adapter := scim.NewAdapter(&scim.Datasource{
+		Client: server.Client(),
+	})
+
+	gotResponse := adapter.GetPage(context.Background(), &framework.Request[scim.Config]{
+		Address: server.URL,
+		Auth: &framework.DatasourceAuthCredentials{
+			Basic: &framework.BasicAuthCredentials{
+				Username: testUsername,
+				Password: testPassword,
+			},
+		},
+		Entity: framework.EntityConfig{
+			ExternalId: scimUser,
+			Attributes: []*framework.AttributeConfig{
+				{
+					ExternalId: "id",
+					Type:       framework.AttributeTypeString,
+				},
+			},
+		},
+		Config: &scim.Config{
+			MaxResponseBodyBytes: 64,
+		},
+		PageSize: 2,
+	})
+
+	wantResponse := framework.NewGetPageResponseError(&framework.Error{
+		Message: "The datasource response exceeds the maximum size of 64 bytes. Reduce the page size or increase maxResponseBodyBytes in the datasource config.",
+		Code:    api_adapter_v1.ErrorCode_ERROR_CODE_DATASOURCE_FAILED,
+	})
+
+	if !reflect.DeepEqual(gotResponse, wantResponse) {
+		t.Errorf("gotResponse: %v, wantResponse: %v", gotResponse, wantResponse)
+	}
+}
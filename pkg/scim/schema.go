@@ -0,0 +1,374 @@
+// Copyright 2025 SGNL.ai, Inc.
+package scim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
+	customerror "github.com/sgnl-ai/sample-adapter/pkg/errors"
+)
+
+// SchemasCacheTTL is the duration for which the Schemas of a SCIM server are cached before being fetched again.
+var SchemasCacheTTL = time.Hour
+
+// ResourceType is a SCIM ResourceType resource, describing the endpoint and schemas of a type of resources.
+// https://datatracker.ietf.org/doc/html/rfc7643#section-6
+type ResourceType struct {
+	// ID is the resource type's identifier, e.g. "User".
+	ID string `json:"id"`
+
+	// Name is the resource type's name, e.g. "User".
+	Name string `json:"name"`
+
+	// Endpoint is the resource type's endpoint relative to the base URL, e.g. "/Users".
+	Endpoint string `json:"endpoint"`
+
+	// Schema is the URI of the resource type's core schema.
+	Schema string `json:"schema"`
+
+	// SchemaExtensions are the schema extensions of the resource type, if any.
+	SchemaExtensions []SchemaExtension `json:"schemaExtensions,omitempty"`
+}
+
+// SchemaExtension is a schema extension of a resource type.
+type SchemaExtension struct {
+	// Schema is the URI of the extension schema.
+	Schema string `json:"schema"`
+
+	// Required is true if resources of the resource type must include the extension.
+	Required bool `json:"required"`
+}
+
+// Schema is a SCIM Schema resource, describing the attributes of a core or extension schema.
+// https://datatracker.ietf.org/doc/html/rfc7643#section-7
+type Schema struct {
+	// ID is the schema's URI, e.g. "urn:ietf:params:scim:schemas:core:2.0:User".
+	ID string `json:"id"`
+
+	// Name is the schema's name, e.g. "User".
+	Name string `json:"name,omitempty"`
+
+	// Attributes are the schema's attributes.
+	Attributes []SchemaAttribute `json:"attributes"`
+}
+
+// SchemaAttribute describes an attribute of a schema, or a sub-attribute of a complex attribute.
+type SchemaAttribute struct {
+	// Name is the attribute's name.
+	Name string `json:"name"`
+
+	// Type is the attribute's data type: "string", "boolean", "decimal", "integer", "dateTime", "reference",
+	// "binary" or "complex".
+	Type string `json:"type"`
+
+	// MultiValued is true if the attribute has a list of values.
+	MultiValued bool `json:"multiValued"`
+
+	// CaseExact is true if the attribute's string values are case-sensitive.
+	CaseExact bool `json:"caseExact"`
+
+	// Returned is when the attribute is returned: "always", "never", "default" or "request".
+	Returned string `json:"returned,omitempty"`
+
+	// SubAttributes are the sub-attributes of a complex attribute.
+	SubAttributes []SchemaAttribute `json:"subAttributes,omitempty"`
+}
+
+// SchemaAttributeType returns the framework.AttributeType of the values of a SCIM attribute data type.
+// Returns false for complex attributes, and for unknown data types.
+// https://datatracker.ietf.org/doc/html/rfc7643#section-2.3
+func SchemaAttributeType(schemaType string) (framework.AttributeType, bool) {
+	switch strings.ToLower(schemaType) {
+	case "string", "reference", "binary":
+		return framework.AttributeTypeString, true
+	case "boolean":
+		return framework.AttributeTypeBool, true
+	case "decimal":
+		return framework.AttributeTypeDouble, true
+	case "integer":
+		return framework.AttributeTypeInt64, true
+	case "datetime":
+		return framework.AttributeTypeDateTime, true
+	default:
+		return 0, false
+	}
+}
+
+// schemasResult is a cached response to a Schemas request.
+type schemasResult struct {
+	schemas []Schema
+	err     *framework.Error
+}
+
+// GetResourceTypes returns the ResourceTypes of the SCIM server at the request's BaseURL.
+// If the request fails, or the response status code is not successful, an appropriate framework.Error is returned.
+func (d *Datasource) GetResourceTypes(ctx context.Context, request *Request) ([]ResourceType, *framework.Error) {
+	resourceTypes, _, err := getDiscoveryResources[ResourceType](ctx, d, request, "/ResourceTypes")
+
+	return resourceTypes, err
+}
+
+// GetSchemas returns the Schemas of the SCIM server at the request's BaseURL, including extension schemas.
+// If the request fails, or the response status code is not successful, an appropriate framework.Error is returned.
+// Responses are cached for SchemasCacheTTL for each BaseURL, including 404 and 501 responses from SCIM servers
+// which do not implement the Schemas endpoint.
+func (d *Datasource) GetSchemas(ctx context.Context, request *Request) ([]Schema, *framework.Error) {
+	if result, found := d.schemas.get(request.BaseURL); found {
+		return result.schemas, result.err
+	}
+
+	schemas, statusCode, err := getDiscoveryResources[Schema](ctx, d, request, "/Schemas")
+
+	switch {
+	case err == nil, statusCode == http.StatusNotFound, statusCode == http.StatusNotImplemented:
+		d.schemas.set(request.BaseURL, schemasResult{schemas: schemas, err: err}, SchemasCacheTTL)
+	}
+
+	return schemas, err
+}
+
+// getDiscoveryResources requests the resources of a SCIM discovery endpoint. The resources are returned in
+// a ListResponse, or in a bare JSON array by some SCIM servers.
+// Also returns the status code of the response, or 0 if the request failed.
+func getDiscoveryResources[T any](
+	ctx context.Context,
+	d *Datasource,
+	request *Request,
+	endpoint string,
+) ([]T, int, *framework.Error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, request.BaseURL+endpoint, nil)
+	if err != nil {
+		return nil, 0, &framework.Error{
+			Message: "Failed to create HTTP request to datasource.",
+			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+		}
+	}
+
+	// Timeout API calls that take longer than the configured timeout.
+	apiCtx, cancel := context.WithTimeout(ctx, time.Duration(request.RequestTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	req = req.WithContext(apiCtx)
+	req.Header.Add("Accept", "application/scim+json")
+	req.Header.Add("Authorization", request.AuthorizationHeader)
+
+	res, err := d.send(req, request)
+	if err != nil {
+		return nil, 0, customerror.UpdateError(&framework.Error{
+			Message: fmt.Sprintf("Failed to execute SCIM %s request: %v.", strings.TrimPrefix(endpoint, "/"), err),
+			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+		},
+			customerror.WithRequestTimeoutMessage(err, request.RequestTimeoutSeconds),
+			WithTokenErrorCode(err),
+			WithConfigErrorCode(err),
+		)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, MaxErrorResponseBodyBytes))
+
+		return nil, res.StatusCode, HTTPError(res.StatusCode, res.Header.Get("Retry-After"), ParseErrorResponse(body))
+	}
+
+	body, err := io.ReadAll(NewMaxBytesReader(res.Body, request.MaxResponseBodyBytes))
+	if err != nil {
+		return nil, res.StatusCode, responseDecodeError(err)
+	}
+
+	var resources []T
+
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &resources); err != nil {
+			return nil, res.StatusCode, responseDecodeError(err)
+		}
+
+		return resources, res.StatusCode, nil
+	}
+
+	var listResponse struct {
+		Resources []T `json:"Resources"`
+	}
+
+	if err := json.Unmarshal(body, &listResponse); err != nil {
+		return nil, res.StatusCode, responseDecodeError(err)
+	}
+
+	return listResponse.Resources, res.StatusCode, nil
+}
+
+// EntityConfig returns the configuration of the entity of a resource type, derived from its schemas:
+//   - An attribute for each single-valued attribute, and for each sub-attribute of single-valued complex
+//     attributes, e.g. "$.name.givenName". Multi-valued attributes of simple types are list attributes.
+//   - A child entity for each multi-valued complex attribute, e.g. "emails", with an attribute for each
+//     sub-attribute.
+//   - The "id" attribute as the unique ID, and the common "externalId", "meta.created" and
+//     "meta.lastModified" attributes.
+//
+// Attribute external IDs are JSONPaths if they are not top-level attributes of the core schema, and extension
+// attributes are prefixed with their schema URI, e.g. `$["urn:...:enterprise:2.0:User"].employeeNumber`.
+// Attributes which are never returned, e.g. "password", are omitted. An error is returned if the core schema
+// of the resource type is not in schemas. Extension schemas which are not in schemas are omitted.
+func EntityConfig(resourceType *ResourceType, schemas []Schema) (*framework.EntityConfig, error) {
+	schemasByID := make(map[string]*Schema, len(schemas))
+	for i := range schemas {
+		schemasByID[strings.ToLower(schemas[i].ID)] = &schemas[i]
+	}
+
+	coreSchema, found := schemasByID[strings.ToLower(resourceType.Schema)]
+	if !found {
+		return nil, fmt.Errorf("schema %s of resource type %s not found", resourceType.Schema, resourceType.Name)
+	}
+
+	entity := &framework.EntityConfig{
+		ExternalId: strings.TrimPrefix(resourceType.Endpoint, "/"),
+		Attributes: []*framework.AttributeConfig{
+			{ExternalId: "id", Type: framework.AttributeTypeString, UniqueId: true},
+			{ExternalId: "externalId", Type: framework.AttributeTypeString},
+			{ExternalId: "$.meta.created", Type: framework.AttributeTypeDateTime},
+			{ExternalId: "$.meta.lastModified", Type: framework.AttributeTypeDateTime},
+		},
+	}
+
+	builder := entityConfigBuilder{
+		entity: entity,
+		seen:   make(map[string]struct{}),
+	}
+
+	for _, attribute := range entity.Attributes {
+		builder.seen[strings.ToLower(attribute.ExternalId)] = struct{}{}
+	}
+
+	builder.addSchema(coreSchema, "")
+
+	for _, extension := range resourceType.SchemaExtensions {
+		if schema, found := schemasByID[strings.ToLower(extension.Schema)]; found {
+			builder.addSchema(schema, `$["`+schema.ID+`"]`)
+		}
+	}
+
+	return entity, nil
+}
+
+// entityConfigBuilder adds the attributes of schemas to an entity configuration.
+type entityConfigBuilder struct {
+	entity *framework.EntityConfig
+
+	// seen contains the lowercase external IDs of the attributes and child entities already added,
+	// as schemas may redefine the common attributes.
+	seen map[string]struct{}
+}
+
+// addSchema adds the attributes of a schema. prefix is the JSONPath of the object containing the attributes,
+// or empty for the core schema.
+func (b *entityConfigBuilder) addSchema(schema *Schema, prefix string) {
+	for _, attribute := range schema.Attributes {
+		if attribute.Returned == "never" {
+			continue
+		}
+
+		externalID := attribute.Name
+		if prefix != "" {
+			externalID = prefix + jsonPathMember(attribute.Name)
+		}
+
+		isComplex := strings.EqualFold(attribute.Type, "complex")
+
+		switch {
+		case isComplex && attribute.MultiValued:
+			b.addChildEntity(externalID, attribute.SubAttributes)
+		case isComplex:
+			// Sub-attributes are not top-level attributes, so their external IDs are always JSONPaths.
+			parentPath := prefix
+			if parentPath == "" {
+				parentPath = "$"
+			}
+
+			for _, subAttribute := range attribute.SubAttributes {
+				if subAttribute.Returned != "never" {
+					b.addAttribute(parentPath+jsonPathMember(attribute.Name)+jsonPathMember(subAttribute.Name), subAttribute)
+				}
+			}
+		default:
+			b.addAttribute(externalID, attribute)
+		}
+	}
+}
+
+func (b *entityConfigBuilder) addAttribute(externalID string, attribute SchemaAttribute) {
+	attributeType, ok := SchemaAttributeType(attribute.Type)
+	if !ok || !b.add(externalID) {
+		return
+	}
+
+	b.entity.Attributes = append(b.entity.Attributes, &framework.AttributeConfig{
+		ExternalId: externalID,
+		Type:       attributeType,
+		List:       attribute.MultiValued,
+	})
+}
+
+func (b *entityConfigBuilder) addChildEntity(externalID string, subAttributes []SchemaAttribute) {
+	childEntity := &framework.EntityConfig{
+		ExternalId: externalID,
+	}
+
+	for _, subAttribute := range subAttributes {
+		attributeType, ok := SchemaAttributeType(subAttribute.Type)
+		if !ok || subAttribute.Returned == "never" {
+			continue
+		}
+
+		// External IDs starting with "$" are JSONPaths, so "$ref" must be quoted.
+		subAttributeID := subAttribute.Name
+		if strings.HasPrefix(subAttributeID, "$") {
+			subAttributeID = "$" + jsonPathMember(subAttributeID)
+		}
+
+		childEntity.Attributes = append(childEntity.Attributes, &framework.AttributeConfig{
+			ExternalId: subAttributeID,
+			Type:       attributeType,
+			List:       subAttribute.MultiValued,
+		})
+	}
+
+	if len(childEntity.Attributes) == 0 || !b.add(externalID) {
+		return
+	}
+
+	b.entity.ChildEntities = append(b.entity.ChildEntities, childEntity)
+}
+
+// jsonPathMember returns the JSONPath segment selecting a member name, in dot notation if the name is an
+// identifier, or in bracket notation otherwise, e.g. `["$ref"]`, which JSONPath parsers reject after a dot.
+func jsonPathMember(name string) string {
+	for i, c := range name {
+		isIdentifierChar := c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || (i > 0 && '0' <= c && c <= '9')
+		if !isIdentifierChar {
+			return `["` + name + `"]`
+		}
+	}
+
+	return "." + name
+}
+
+// add returns false if an attribute or child entity with the external ID was already added.
+func (b *entityConfigBuilder) add(externalID string) bool {
+	key := strings.ToLower(externalID)
+	if _, found := b.seen[key]; found {
+		return false
+	}
+
+	b.seen[key] = struct{}{}
+
+	return true
+}
@@ -0,0 +1,313 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// nolint: lll
+package scim_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	framework "github.com/sgnl-ai/adapter-framework"
+	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
+	"github.com/sgnl-ai/sample-adapter/pkg/scim"
+)
+
+const (
+	testUserSchema = `{
+		"id": "urn:ietf:params:scim:schemas:core:2.0:User",
+		"name": "User",
+		"attributes": [
+			{"name": "userName", "type": "string", "multiValued": false, "returned": "default"},
+			{"name": "name", "type": "complex", "multiValued": false, "subAttributes": [
+				{"name": "givenName", "type": "string", "multiValued": false},
+				{"name": "familyName", "type": "string", "multiValued": false}
+			]},
+			{"name": "active", "type": "boolean", "multiValued": false},
+			{"name": "password", "type": "string", "multiValued": false, "returned": "never"},
+			{"name": "emails", "type": "complex", "multiValued": true, "subAttributes": [
+				{"name": "value", "type": "string", "multiValued": false},
+				{"name": "primary", "type": "boolean", "multiValued": false}
+			]},
+			{"name": "groups", "type": "complex", "multiValued": true, "subAttributes": [
+				{"name": "value", "type": "string", "multiValued": false},
+				{"name": "$ref", "type": "reference", "multiValued": false}
+			]},
+			{"name": "entitlements", "type": "string", "multiValued": true}
+		]
+	}`
+
+	testEnterpriseUserSchema = `{
+		"id": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+		"name": "EnterpriseUser",
+		"attributes": [
+			{"name": "employeeNumber", "type": "string", "multiValued": false},
+			{"name": "costCenter", "type": "integer", "multiValued": false},
+			{"name": "startDate", "type": "dateTime", "multiValued": false},
+			{"name": "manager", "type": "complex", "multiValued": false, "subAttributes": [
+				{"name": "value", "type": "string", "multiValued": false},
+				{"name": "$ref", "type": "reference", "multiValued": false},
+				{"name": "displayName", "type": "string", "multiValued": false}
+			]}
+		]
+	}`
+
+	testUserResourceType = `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:ResourceType"],
+		"id": "User",
+		"name": "User",
+		"endpoint": "/Users",
+		"schema": "urn:ietf:params:scim:schemas:core:2.0:User",
+		"schemaExtensions": [
+			{"schema": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User", "required": false}
+		]
+	}`
+)
+
+func TestGetSchemas(t *testing.T) {
+	tests := map[string]struct {
+		statusCode  int
+		body        string
+		wantSchemas []scim.Schema
+		wantErr     *framework.Error
+	}{
+		"list_response": {
+			statusCode: http.StatusOK,
+			body:       `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"], "totalResults": 1, "Resources": [` + testEnterpriseUserSchema + `]}`,
+			wantSchemas: []scim.Schema{
+				{
+					ID:   "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+					Name: "EnterpriseUser",
+					Attributes: []scim.SchemaAttribute{
+						{Name: "employeeNumber", Type: "string"},
+						{Name: "costCenter", Type: "integer"},
+						{Name: "startDate", Type: "dateTime"},
+						{Name: "manager", Type: "complex", SubAttributes: []scim.SchemaAttribute{{Name: "value", Type: "string"}, {Name: "$ref", Type: "reference"}, {Name: "displayName", Type: "string"}}},
+					},
+				},
+			},
+		},
+		"bare_array": {
+			statusCode: http.StatusOK,
+			body:       `[{"id": "urn:ietf:params:scim:schemas:core:2.0:Group", "attributes": [{"name": "displayName", "type": "string"}]}]`,
+			wantSchemas: []scim.Schema{
+				{
+					ID:         "urn:ietf:params:scim:schemas:core:2.0:Group",
+					Attributes: []scim.SchemaAttribute{{Name: "displayName", Type: "string"}},
+				},
+			},
+		},
+		"not_found": {
+			statusCode: http.StatusNotFound,
+			body:       `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"], "status": "404", "detail": "Not found"}`,
+			wantErr: &framework.Error{
+				Message: "Datasource rejected request, returned status code: 404. SCIM error detail: Not found.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INTERNAL,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/Schemas" {
+					w.WriteHeader(http.StatusNotFound)
+
+					return
+				}
+
+				w.WriteHeader(tt.statusCode)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			scimClient := &scim.Datasource{Client: server.Client()}
+
+			gotSchemas, gotErr := scimClient.GetSchemas(context.Background(), &scim.Request{
+				BaseURL:               server.URL,
+				RequestTimeoutSeconds: 5,
+			})
+
+			if !reflect.DeepEqual(gotSchemas, tt.wantSchemas) {
+				t.Errorf("gotSchemas: %+v, wantSchemas: %+v", gotSchemas, tt.wantSchemas)
+			}
+
+			if !reflect.DeepEqual(gotErr, tt.wantErr) {
+				t.Errorf("gotErr: %v, wantErr: %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetResourceTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"totalResults": 1, "Resources": [` + testUserResourceType + `]}`))
+	}))
+	defer server.Close()
+
+	scimClient := &scim.Datasource{Client: server.Client()}
+
+	gotResourceTypes, gotErr := scimClient.GetResourceTypes(context.Background(), &scim.Request{
+		BaseURL:               server.URL,
+		RequestTimeoutSeconds: 5,
+	})
+	if gotErr != nil {
+		t.Fatalf("Unexpected error: %v", gotErr)
+	}
+
+	wantResourceTypes := []scim.ResourceType{
+		{
+			ID:       "User",
+			Name:     "User",
+			Endpoint: "/Users",
+			Schema:   "urn:ietf:params:scim:schemas:core:2.0:User",
+			SchemaExtensions: []scim.SchemaExtension{
+				{Schema: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(gotResourceTypes, wantResourceTypes) {
+		t.Errorf("gotResourceTypes: %+v, wantResourceTypes: %+v", gotResourceTypes, wantResourceTypes)
+	}
+}
+
+func TestEntityConfig(t *testing.T) {
+	tests := map[string]struct {
+		resourceType *scim.ResourceType
+		schemas      []scim.Schema
+		wantEntity   *framework.EntityConfig
+		wantErr      error
+	}{
+		"user_with_extension": {
+			resourceType: &scim.ResourceType{
+				Name:     "User",
+				Endpoint: "/Users",
+				Schema:   "urn:ietf:params:scim:schemas:core:2.0:User",
+				SchemaExtensions: []scim.SchemaExtension{
+					{Schema: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"},
+					{Schema: "urn:example:unknown"},
+				},
+			},
+			schemas: mustDecodeSchemas(t, testUserSchema, testEnterpriseUserSchema),
+			wantEntity: &framework.EntityConfig{
+				ExternalId: "Users",
+				Attributes: []*framework.AttributeConfig{
+					{ExternalId: "id", Type: framework.AttributeTypeString, UniqueId: true},
+					{ExternalId: "externalId", Type: framework.AttributeTypeString},
+					{ExternalId: "$.meta.created", Type: framework.AttributeTypeDateTime},
+					{ExternalId: "$.meta.lastModified", Type: framework.AttributeTypeDateTime},
+					{ExternalId: "userName", Type: framework.AttributeTypeString},
+					{ExternalId: "$.name.givenName", Type: framework.AttributeTypeString},
+					{ExternalId: "$.name.familyName", Type: framework.AttributeTypeString},
+					{ExternalId: "active", Type: framework.AttributeTypeBool},
+					{ExternalId: "entitlements", Type: framework.AttributeTypeString, List: true},
+					{ExternalId: `$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].employeeNumber`, Type: framework.AttributeTypeString},
+					{ExternalId: `$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].costCenter`, Type: framework.AttributeTypeInt64},
+					{ExternalId: `$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].startDate`, Type: framework.AttributeTypeDateTime},
+					{ExternalId: `$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].manager.value`, Type: framework.AttributeTypeString},
+					{ExternalId: `$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].manager["$ref"]`, Type: framework.AttributeTypeString},
+					{ExternalId: `$["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].manager.displayName`, Type: framework.AttributeTypeString},
+				},
+				ChildEntities: []*framework.EntityConfig{
+					{
+						ExternalId: "emails",
+						Attributes: []*framework.AttributeConfig{
+							{ExternalId: "value", Type: framework.AttributeTypeString},
+							{ExternalId: "primary", Type: framework.AttributeTypeBool},
+						},
+					},
+					{
+						ExternalId: "groups",
+						Attributes: []*framework.AttributeConfig{
+							{ExternalId: "value", Type: framework.AttributeTypeString},
+							{ExternalId: `$["$ref"]`, Type: framework.AttributeTypeString},
+						},
+					},
+				},
+			},
+		},
+		"common_attributes_not_duplicated": {
+			resourceType: &scim.ResourceType{
+				Name:     "Group",
+				Endpoint: "/Groups",
+				Schema:   "urn:ietf:params:scim:schemas:core:2.0:Group",
+			},
+			schemas: mustDecodeSchemas(t, `{
+				"id": "urn:ietf:params:scim:schemas:core:2.0:Group",
+				"attributes": [
+					{"name": "id", "type": "string"},
+					{"name": "displayName", "type": "string"},
+					{"name": "meta", "type": "complex", "subAttributes": [
+						{"name": "created", "type": "dateTime"},
+						{"name": "version", "type": "string"}
+					]}
+				]
+			}`),
+			wantEntity: &framework.EntityConfig{
+				ExternalId: "Groups",
+				Attributes: []*framework.AttributeConfig{
+					{ExternalId: "id", Type: framework.AttributeTypeString, UniqueId: true},
+					{ExternalId: "externalId", Type: framework.AttributeTypeString},
+					{ExternalId: "$.meta.created", Type: framework.AttributeTypeDateTime},
+					{ExternalId: "$.meta.lastModified", Type: framework.AttributeTypeDateTime},
+					{ExternalId: "displayName", Type: framework.AttributeTypeString},
+					{ExternalId: "$.meta.version", Type: framework.AttributeTypeString},
+				},
+			},
+		},
+		"missing_core_schema": {
+			resourceType: &scim.ResourceType{
+				Name:     "Device",
+				Endpoint: "/Devices",
+				Schema:   "urn:example:Device",
+			},
+			schemas: mustDecodeSchemas(t, testUserSchema),
+			wantErr: errors.New("schema urn:example:Device of resource type Device not found"),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotEntity, gotErr := scim.EntityConfig(tt.resourceType, tt.schemas)
+
+			if !reflect.DeepEqual(gotEntity, tt.wantEntity) {
+				t.Errorf("gotEntity: %s, wantEntity: %s", mustMarshal(t, gotEntity), mustMarshal(t, tt.wantEntity))
+			}
+
+			if !reflect.DeepEqual(gotErr, tt.wantErr) {
+				t.Errorf("gotErr: %v, wantErr: %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func mustDecodeSchemas(t *testing.T, schemas ...string) []scim.Schema {
+	t.Helper()
+
+	decoded := make([]scim.Schema, len(schemas))
+
+	for i, schema := range schemas {
+		if err := json.Unmarshal([]byte(schema), &decoded[i]); err != nil {
+			t.Fatalf("Failed to decode schema: %v", err)
+		}
+	}
+
+	return decoded
+}
+
+func mustMarshal(t *testing.T, v any) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	return string(data)
+}
@@ -2,10 +2,18 @@
 package scim
 
 import (
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	framework "github.com/sgnl-ai/adapter-framework"
 	api_adapter_v1 "github.com/sgnl-ai/adapter-framework/api/adapter/v1"
+	"github.com/sgnl-ai/sample-adapter/pkg/auth"
+	"github.com/sgnl-ai/sample-adapter/pkg/config"
+	"github.com/sgnl-ai/sample-adapter/pkg/retry"
+	"github.com/sgnl-ai/sample-adapter/pkg/scim/filter"
+	"github.com/sgnl-ai/sample-adapter/pkg/transport"
 )
 
 // ValidateGetPageRequest validates the fields of the GetPage Request.
@@ -17,23 +25,195 @@ func (a *Adapter) ValidateGetPageRequest(request *framework.Request[Config]) *fr
 		}
 	}
 
-	// SCIM server can use any of the Auth mechanisms
-	if request.Auth == nil || (request.Auth.HTTPAuthorization == "" && request.Auth.Basic == nil) {
+	if request.Config != nil && request.Config.OAuth2 != nil {
+		if err := validateOAuth2(request); err != nil {
+			return err
+		}
+	} else {
+		// SCIM server can use any of the Auth mechanisms
+		if request.Auth == nil || (request.Auth.HTTPAuthorization == "" && request.Auth.Basic == nil) {
+			return &framework.Error{
+				Message: "SCIM auth is missing required credentials.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			}
+		}
+
+		if request.Auth.Basic != nil && (request.Auth.Basic.Username == "" || request.Auth.Basic.Password == "") {
+			return &framework.Error{
+				Message: "One of username or password required for basic auth is empty.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			}
+		}
+	}
+
+	if request.Config != nil {
+		if request.Config.MaxURLLength < 0 {
+			return &framework.Error{
+				Message: "The maximum URL length must not be negative.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			}
+		}
+
+		if request.Config.MaxResponseBodyBytes < 0 {
+			return &framework.Error{
+				Message: "The maximum response body size must not be negative.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			}
+		}
+
+		if request.Config.CommonConfig != nil && request.Config.Retry != nil {
+			// Validate a copy of the retry config with defaults, as the request's config is only
+			// updated with defaults when requesting the page.
+			retryConfig := *request.Config.Retry
+
+			commonConfig := config.SetMissingCommonConfigDefaults(&config.CommonConfig{Retry: &retryConfig})
+
+			if _, err := retry.NewPolicy(commonConfig.Retry); err != nil {
+				return &framework.Error{
+					Message: fmt.Sprintf("Invalid retry config: %v.", err),
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+				}
+			}
+		}
+
+		if request.Config.CommonConfig != nil && request.Config.RateLimit != nil {
+			rateLimit := request.Config.RateLimit
+
+			if rateLimit.RequestsPerSecond < 0 || rateLimit.Burst < 0 || rateLimit.MaxConcurrentRequests < 0 {
+				return &framework.Error{
+					Message: "The rate limit requests per second, burst and max concurrent requests must not be negative.",
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+				}
+			}
+		}
+
+		if request.Config.CommonConfig != nil && request.Config.TLS != nil {
+			if _, err := transport.NewTLSConfig(nil, request.Config.TLS); err != nil {
+				return &framework.Error{
+					Message: fmt.Sprintf("Invalid TLS config: %v.", errors.Unwrap(err)),
+					Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+				}
+			}
+		}
+
+		switch request.Config.TypeCoercion {
+		case "", TypeCoercionNone, TypeCoercionEntity, TypeCoercionSchema:
+		default:
+			return &framework.Error{
+				Message: fmt.Sprintf(
+					"Unsupported type coercion mode %q. Supported type coercion modes are %q, %q and %q.",
+					request.Config.TypeCoercion, TypeCoercionNone, TypeCoercionEntity, TypeCoercionSchema,
+				),
+				Code: api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			}
+		}
+
+		for entityExternalID, queryParams := range request.Config.QueryParams {
+			switch queryParams.PagingMode {
+			case "", PagingModeIndex, PagingModeCursor, PagingModeAuto:
+			default:
+				return &framework.Error{
+					Message: fmt.Sprintf(
+						"Unsupported paging mode %q for entity %s. Supported paging modes are %q, %q and %q.",
+						queryParams.PagingMode, entityExternalID, PagingModeIndex, PagingModeCursor, PagingModeAuto,
+					),
+					Code: api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+				}
+			}
+
+			if queryParams.Filter != "" {
+				if _, err := filter.Parse(queryParams.Filter); err != nil {
+					return &framework.Error{
+						Message: fmt.Sprintf("Invalid filter for entity %s: %v.", entityExternalID, err),
+						Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+					}
+				}
+			}
+		}
+
+		for entityExternalID, incrementalSync := range request.Config.IncrementalSync {
+			if incrementalSync.Since != "" {
+				if _, err := time.Parse(time.RFC3339Nano, incrementalSync.Since); err != nil {
+					return &framework.Error{
+						Message: fmt.Sprintf(
+							"Invalid incremental sync start %q for entity %s. It must be an RFC 3339 timestamp.",
+							incrementalSync.Since, entityExternalID,
+						),
+						Code: api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+					}
+				}
+			}
+
+			if incrementalSync.OverlapSeconds < 0 {
+				return &framework.Error{
+					Message: fmt.Sprintf(
+						"The incremental sync overlap for entity %s must not be negative.", entityExternalID,
+					),
+					Code: api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+				}
+			}
+		}
+	}
+
+	// Add checks for Ordered here, if any.
+	// Sort and max page size depend on the SCIM server implementation, hence they are
+	// validated against the server's ServiceProviderConfig in ValidateServiceProviderCapabilities.
+
+	return nil
+}
+
+// ValidateServiceProviderCapabilities validates the query parameters of a request against the
+// capabilities advertised in the SCIM server's ServiceProviderConfig.
+// A filter is not validated, as it is evaluated in the adapter if the SCIM server does not support filtering.
+func ValidateServiceProviderCapabilities(
+	serviceProviderConfig *ServiceProviderConfig,
+	entityExternalID string,
+	queryParams QueryParams,
+) *framework.Error {
+	if queryParams.SortBy != "" && !serviceProviderConfig.Sort.Supported {
 		return &framework.Error{
-			Message: "SCIM auth is missing required credentials.",
+			Message: fmt.Sprintf(
+				"A sortBy is configured for entity %s, but the SCIM server does not support sorting.",
+				entityExternalID,
+			),
+			Code: api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+		}
+	}
+
+	return nil
+}
+
+// validateOAuth2 validates the OAuth2 config of a request, and that the client ID and secret are provided
+// either in the OAuth2 config or as basic auth credentials.
+func validateOAuth2(request *framework.Request[Config]) *framework.Error {
+	// The client credentials are validated as resolved from the basic auth credentials, as the issuer and subject
+	// of JWT assertions default to the client ID.
+	oauth2Config := *request.Config.OAuth2
+	oauth2Config.ClientID, oauth2Config.ClientSecret = oauth2ClientCredentials(request)
+
+	if err := oauth2Config.Validate(); err != nil {
+		return &framework.Error{
+			Message: fmt.Sprintf("Invalid OAuth2 config: %v.", err),
 			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
 		}
 	}
 
-	if request.Auth.Basic != nil && (request.Auth.Basic.Username == "" || request.Auth.Basic.Password == "") {
+	if request.Auth != nil && request.Auth.HTTPAuthorization != "" {
 		return &framework.Error{
-			Message: "One of username or password required for basic auth is empty.",
+			Message: "An Authorization header cannot be provided together with an OAuth2 config.",
 			Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
 		}
 	}
 
-	// Add checks for Ordered and MaxPageSize here, if any.
-	// Depends on the SCIM server implementation hence excluded in the validation.
+	switch oauth2Config.ResolvedClientAuthMethod() {
+	case auth.ClientSecretBasic, auth.ClientSecretPost:
+		if oauth2Config.ClientID == "" || oauth2Config.ClientSecret == "" {
+			return &framework.Error{
+				Message: "OAuth2 requires a client ID and secret, in the OAuth2 config or as basic auth credentials.",
+				Code:    api_adapter_v1.ErrorCode_ERROR_CODE_INVALID_DATASOURCE_CONFIG,
+			}
+		}
+	}
 
 	return nil
 }
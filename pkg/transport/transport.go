@@ -0,0 +1,395 @@
+// Copyright 2025 SGNL.ai, Inc.
+package transport
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sgnl-ai/sample-adapter/pkg/config"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// ConfigError is returned if a TLSConfig is invalid, e.g. if a certificate cannot be parsed.
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid TLS config: " + e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// VerificationError is returned if the certificate chain presented by a server cannot be verified, e.g. if it is
+// not signed by a trusted CA, or does not contain any pinned public key.
+type VerificationError struct {
+	// Certificates is the certificate chain presented by the server, starting with the server's certificate.
+	Certificates []*x509.Certificate
+
+	// Err is the cause of the error.
+	Err error
+}
+
+func (e *VerificationError) Error() string {
+	chain := make([]string, 0, len(e.Certificates))
+
+	for _, cert := range e.Certificates {
+		chain = append(chain, fmt.Sprintf("%q expiring %s", cert.Subject, cert.NotAfter.UTC().Format(time.RFC3339)))
+	}
+
+	return fmt.Sprintf("%v (presented certificate chain: %s)", e.Err, strings.Join(chain, ", "))
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// WrapVerificationError returns a *VerificationError wrapping the error of a request, if the request failed
+// because the server's certificate chain could not be verified. Otherwise, the error is returned unchanged.
+func WrapVerificationError(err error) error {
+	var verificationErr *VerificationError
+	if errors.As(err, &verificationErr) {
+		return err
+	}
+
+	var certErr *tls.CertificateVerificationError
+	if !errors.As(err, &certErr) {
+		return err
+	}
+
+	return &VerificationError{
+		Certificates: certErr.UnverifiedCertificates,
+		Err:          err,
+	}
+}
+
+// DefaultRegistrySize is the maximum number of HTTP clients held by a Registry if MaxSize is not set.
+const DefaultRegistrySize = 100
+
+// Registry holds the HTTP clients of datasources with a TLSConfig, keyed by datasource address.
+// The zero value is ready to use.
+type Registry struct {
+	// MaxSize is the maximum number of HTTP clients. Once reached, the client of the least recently used address
+	// is evicted and its idle connections are closed when the client of another address is created.
+	// Optional. Defaults to DefaultRegistrySize if not set.
+	MaxSize int
+
+	mu sync.Mutex
+
+	// clients are the elements of recent, keyed by address.
+	clients map[string]*list.Element
+
+	// recent is the list of HTTP clients, from the most recently used to the least recently used.
+	recent list.List
+}
+
+// client is an HTTP client with its own transport, configured with a TLSConfig.
+type client struct {
+	address string
+
+	// configHash is the hash of the TLSConfig of the client, as returned by configHash.
+	configHash string
+
+	client *http.Client
+}
+
+// configHash returns the hash of a TLSConfig, so that the certificates and keys are not retained by the
+// Registry to detect config changes.
+func configHash(cfg *config.TLSConfig) string {
+	// A TLSConfig only contains strings, which can always be marshalled.
+	data, _ := json.Marshal(cfg)
+	hash := sha256.Sum256(data)
+
+	return hex.EncodeToString(hash[:])
+}
+
+// Client returns the HTTP client to use for requests to a datasource address with the provided TLSConfig.
+// The client is a copy of the base client with its own transport, built once per address and TLSConfig, so
+// that connections are only reused by requests with the same TLS config.
+//
+// If the TLSConfig is nil, the base client is returned. If the TLSConfig of an address changed, the client of
+// the previous TLSConfig is evicted and its idle connections are closed, so that its certificates and keys are
+// not retained. Requests in flight with the evicted client are not interrupted.
+// The client of the least recently used address is evicted once MaxSize clients of more recently used
+// addresses are held.
+// Errors caused by an invalid TLSConfig are returned as *ConfigError.
+func (r *Registry) Client(address string, base *http.Client, cfg *config.TLSConfig) (*http.Client, error) {
+	if cfg == nil {
+		return base, nil
+	}
+
+	hash := configHash(cfg)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	element, found := r.clients[address]
+	if found && element.Value.(*client).configHash == hash {
+		r.recent.MoveToFront(element)
+
+		return element.Value.(*client).client, nil
+	}
+
+	httpClient, err := NewClient(base, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if found {
+		previous := element.Value.(*client)
+		previous.client.CloseIdleConnections()
+
+		previous.configHash, previous.client = hash, httpClient
+		r.recent.MoveToFront(element)
+
+		return httpClient, nil
+	}
+
+	if r.clients == nil {
+		r.clients = make(map[string]*list.Element)
+	}
+
+	r.clients[address] = r.recent.PushFront(&client{
+		address:    address,
+		configHash: hash,
+		client:     httpClient,
+	})
+
+	maxSize := r.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultRegistrySize
+	}
+
+	for r.recent.Len() > maxSize {
+		evicted := r.recent.Remove(r.recent.Back()).(*client)
+		delete(r.clients, evicted.address)
+
+		evicted.client.CloseIdleConnections()
+	}
+
+	return httpClient, nil
+}
+
+// TokenEndpointConfig returns the TLSConfig of the connections to the token endpoint of a datasource with the
+// provided TLSConfig, i.e. with its client certificate and additional CA certificates. The CA certificates
+// replacing the default trust store and the pinned public keys only apply to the datasource, as the token endpoint
+// is usually served by an identity provider with a certificate issued by a public CA, and different keys.
+// Returns nil if the TLSConfig is nil.
+func TokenEndpointConfig(cfg *config.TLSConfig) *config.TLSConfig {
+	if cfg == nil {
+		return nil
+	}
+
+	tokenConfig := *cfg
+	tokenConfig.CACertificates = ""
+	tokenConfig.PinnedPublicKeys = nil
+
+	return &tokenConfig
+}
+
+// NewClient returns a copy of the base client with its own transport, configured with the TLSConfig.
+// The base client's transport must be nil or an *http.Transport, whose settings are kept.
+// Errors caused by an invalid TLSConfig are returned as *ConfigError.
+func NewClient(base *http.Client, cfg *config.TLSConfig) (*http.Client, error) {
+	var transport *http.Transport
+
+	switch baseTransport := base.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = baseTransport.Clone()
+	default:
+		return nil, fmt.Errorf("cannot configure TLS on an HTTP client transport of type %T", base.Transport)
+	}
+
+	tlsConfig, err := NewTLSConfig(transport.TLSClientConfig, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	httpClient := *base
+	httpClient.Transport = transport
+
+	return &httpClient, nil
+}
+
+// NewTLSConfig returns a copy of the base TLS config, which may be nil, with the client certificate, the
+// trusted CA certificates and the pinned public keys of the TLSConfig. Pinned public keys are checked after
+// the base config's connection verification, if any, and fail with *VerificationError.
+// Errors caused by an invalid TLSConfig are returned as *ConfigError.
+func NewTLSConfig(base *tls.Config, cfg *config.TLSConfig) (*tls.Config, error) {
+	var tlsConfig *tls.Config
+
+	if base != nil {
+		tlsConfig = base.Clone()
+	} else {
+		tlsConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	certificate, err := clientCertificate(cfg)
+	if err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+
+	if certificate != nil {
+		tlsConfig.Certificates = []tls.Certificate{*certificate}
+	}
+
+	if cfg.CACertificates != "" {
+		rootCAs := x509.NewCertPool()
+
+		if !rootCAs.AppendCertsFromPEM([]byte(cfg.CACertificates)) {
+			return nil, &ConfigError{Err: errors.New("the CA certificates do not contain any PEM-encoded certificate")}
+		}
+
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if cfg.AdditionalCACertificates != "" {
+		var rootCAs *x509.CertPool
+
+		// The pool of the base config is shared, so the additional CAs are added to a copy.
+		if tlsConfig.RootCAs != nil {
+			rootCAs = tlsConfig.RootCAs.Clone()
+		} else if rootCAs, err = x509.SystemCertPool(); err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+
+		if !rootCAs.AppendCertsFromPEM([]byte(cfg.AdditionalCACertificates)) {
+			return nil, &ConfigError{
+				Err: errors.New("the additional CA certificates do not contain any PEM-encoded certificate"),
+			}
+		}
+
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if len(cfg.PinnedPublicKeys) > 0 {
+		pins, err := parsePinnedPublicKeys(cfg.PinnedPublicKeys)
+		if err != nil {
+			return nil, &ConfigError{Err: err}
+		}
+
+		tlsConfig.VerifyConnection = verifyPinnedPublicKeys(tlsConfig.VerifyConnection, pins)
+	}
+
+	return tlsConfig, nil
+}
+
+// parsePinnedPublicKeys decodes base64-encoded SHA-256 hashes of public keys.
+func parsePinnedPublicKeys(encoded []string) (map[[sha256.Size]byte]struct{}, error) {
+	pins := make(map[[sha256.Size]byte]struct{}, len(encoded))
+
+	for _, pin := range encoded {
+		hash, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("the pinned public key %q is not a base64-encoded SHA-256 hash", pin)
+		}
+
+		pins[[sha256.Size]byte(hash)] = struct{}{}
+	}
+
+	return pins, nil
+}
+
+// verifyPinnedPublicKeys returns a TLS connection verification function checking that a verified certificate
+// chain of the server contains one of the pinned public keys, after the next verification function, if any.
+// The certificate chain is only checked after it was verified against the trusted CAs.
+func verifyPinnedPublicKeys(
+	next func(tls.ConnectionState) error, pins map[[sha256.Size]byte]struct{},
+) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if next != nil {
+			if err := next(cs); err != nil {
+				return err
+			}
+		}
+
+		chains := cs.VerifiedChains
+
+		// The chain presented by the server is checked if verification is skipped.
+		if len(chains) == 0 {
+			chains = [][]*x509.Certificate{cs.PeerCertificates}
+		}
+
+		for _, chain := range chains {
+			for _, cert := range chain {
+				if _, found := pins[sha256.Sum256(cert.RawSubjectPublicKeyInfo)]; found {
+					return nil
+				}
+			}
+		}
+
+		return &VerificationError{
+			Certificates: cs.PeerCertificates,
+			Err:          errors.New("tls: the certificate chain does not contain any pinned public key"),
+		}
+	}
+}
+
+// clientCertificate returns the client certificate of a TLSConfig, from its PEM-encoded certificate and key, or
+// from its PKCS #12 archive. Returns nil if no client certificate is configured.
+func clientCertificate(cfg *config.TLSConfig) (*tls.Certificate, error) {
+	switch {
+	case cfg.ClientPKCS12 != "" && (cfg.ClientCertificate != "" || cfg.ClientKey != ""):
+		return nil, errors.New("a client certificate cannot be provided both as PEM and as PKCS #12")
+	case cfg.ClientPKCS12 != "":
+		return pkcs12Certificate(cfg.ClientPKCS12, cfg.ClientPKCS12Password)
+	case cfg.ClientPKCS12Password != "":
+		return nil, errors.New("a PKCS #12 password cannot be provided without a PKCS #12 client certificate")
+	case cfg.ClientCertificate == "" && cfg.ClientKey == "":
+		return nil, nil
+	case cfg.ClientCertificate == "" || cfg.ClientKey == "":
+		return nil, errors.New("the client certificate and key must be provided together")
+	}
+
+	certificate, err := tls.X509KeyPair([]byte(cfg.ClientCertificate), []byte(cfg.ClientKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %w", err)
+	}
+
+	return &certificate, nil
+}
+
+// pkcs12Certificate returns the client certificate, private key and intermediate certificates of a
+// base64-encoded PKCS #12 archive.
+func pkcs12Certificate(encoded string, password string) (*tls.Certificate, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("the PKCS #12 client certificate is not base64-encoded: %w", err)
+	}
+
+	privateKey, leaf, caCerts, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PKCS #12 client certificate: %w", err)
+	}
+
+	certificate := &tls.Certificate{
+		Certificate: [][]byte{leaf.Raw},
+		PrivateKey:  privateKey,
+		Leaf:        leaf,
+	}
+
+	for _, caCert := range caCerts {
+		certificate.Certificate = append(certificate.Certificate, caCert.Raw)
+	}
+
+	return certificate, nil
+}
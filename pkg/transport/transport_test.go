@@ -0,0 +1,503 @@
+// Copyright 2025 SGNL.ai, Inc.
+
+// nolint: lll
+package transport_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sgnl-ai/sample-adapter/pkg/config"
+	"github.com/sgnl-ai/sample-adapter/pkg/transport"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// testCertificate is a certificate and its private key, generated for tests.
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func (c *testCertificate) certificatePEM() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}))
+}
+
+func (c *testCertificate) keyPEM(t *testing.T) string {
+	der, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatalf("Failed to marshal private key: %v", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+}
+
+func (c *testCertificate) pkcs12(t *testing.T, password string) string {
+	data, err := pkcs12.Modern.Encode(c.key, c.cert, nil, password)
+	if err != nil {
+		t.Fatalf("Failed to encode PKCS #12 archive: %v", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// newTestCertificate generates a certificate signed by the parent, or a self-signed CA certificate if the
+// parent is nil.
+func newTestCertificate(t *testing.T, commonName string, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	issuer, issuerKey := template, key
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		issuer, issuerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+
+	return &testCertificate{cert: cert, key: key}
+}
+
+// newMutualTLSServer starts a server requiring client certificates signed by the CA.
+func newMutualTLSServer(ca *testCertificate) *httptest.Server {
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+
+	server.StartTLS()
+
+	return server
+}
+
+func TestNewClient(t *testing.T) {
+	ca := newTestCertificate(t, "Test CA", nil)
+	clientCert := newTestCertificate(t, "adapter", ca)
+	otherCA := newTestCertificate(t, "Other CA", nil)
+	otherClientCert := newTestCertificate(t, "other", otherCA)
+
+	server := newMutualTLSServer(ca)
+	defer server.Close()
+
+	serverCertPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	tests := map[string]struct {
+		base           *http.Client
+		config         *config.TLSConfig
+		wantCommonName string
+		wantErr        bool
+	}{
+		"pem_client_certificate": {
+			base: server.Client(),
+			config: &config.TLSConfig{
+				ClientCertificate: clientCert.certificatePEM(),
+				ClientKey:         clientCert.keyPEM(t),
+			},
+			wantCommonName: "adapter",
+		},
+		"pkcs12_client_certificate": {
+			base: server.Client(),
+			config: &config.TLSConfig{
+				ClientPKCS12:         clientCert.pkcs12(t, "secret"),
+				ClientPKCS12Password: "secret",
+			},
+			wantCommonName: "adapter",
+		},
+		"ca_certificates": {
+			base: &http.Client{},
+			config: &config.TLSConfig{
+				ClientCertificate: clientCert.certificatePEM(),
+				ClientKey:         clientCert.keyPEM(t),
+				CACertificates:    serverCertPEM,
+			},
+			wantCommonName: "adapter",
+		},
+		"untrusted_client_certificate": {
+			base: server.Client(),
+			config: &config.TLSConfig{
+				ClientCertificate: otherClientCert.certificatePEM(),
+				ClientKey:         otherClientCert.keyPEM(t),
+			},
+			wantErr: true,
+		},
+		"no_client_certificate": {
+			base:    server.Client(),
+			config:  &config.TLSConfig{},
+			wantErr: true,
+		},
+		"untrusted_server_certificate": {
+			base: server.Client(),
+			config: &config.TLSConfig{
+				ClientCertificate: clientCert.certificatePEM(),
+				ClientKey:         clientCert.keyPEM(t),
+				CACertificates:    ca.certificatePEM(),
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client, err := transport.NewClient(tt.base, tt.config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			res, err := client.Get(server.URL)
+			if tt.wantErr {
+				if err == nil {
+					res.Body.Close()
+
+					t.Fatal("Expected the request to fail")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			defer res.Body.Close()
+
+			body := make([]byte, 64)
+			n, _ := res.Body.Read(body)
+
+			if gotCommonName := string(body[:n]); gotCommonName != tt.wantCommonName {
+				t.Errorf("gotCommonName: %v, wantCommonName: %v", gotCommonName, tt.wantCommonName)
+			}
+		})
+	}
+}
+
+func TestNewTLSConfigErrors(t *testing.T) {
+	ca := newTestCertificate(t, "Test CA", nil)
+	clientCert := newTestCertificate(t, "adapter", ca)
+	otherClientCert := newTestCertificate(t, "other", ca)
+
+	tests := map[string]struct {
+		config  *config.TLSConfig
+		wantErr string
+	}{
+		"certificate_without_key": {
+			config:  &config.TLSConfig{ClientCertificate: clientCert.certificatePEM()},
+			wantErr: "invalid TLS config: the client certificate and key must be provided together",
+		},
+		"key_without_certificate": {
+			config:  &config.TLSConfig{ClientKey: clientCert.keyPEM(t)},
+			wantErr: "invalid TLS config: the client certificate and key must be provided together",
+		},
+		"pem_and_pkcs12": {
+			config: &config.TLSConfig{
+				ClientCertificate: clientCert.certificatePEM(),
+				ClientKey:         clientCert.keyPEM(t),
+				ClientPKCS12:      clientCert.pkcs12(t, ""),
+			},
+			wantErr: "invalid TLS config: a client certificate cannot be provided both as PEM and as PKCS #12",
+		},
+		"password_without_pkcs12": {
+			config:  &config.TLSConfig{ClientPKCS12Password: "secret"},
+			wantErr: "invalid TLS config: a PKCS #12 password cannot be provided without a PKCS #12 client certificate",
+		},
+		"invalid_certificate": {
+			config: &config.TLSConfig{
+				ClientCertificate: "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n",
+				ClientKey:         clientCert.keyPEM(t),
+			},
+			wantErr: "invalid TLS config: invalid client certificate: x509: malformed certificate",
+		},
+		"mismatched_key": {
+			config: &config.TLSConfig{
+				ClientCertificate: clientCert.certificatePEM(),
+				ClientKey:         otherClientCert.keyPEM(t),
+			},
+			wantErr: "invalid TLS config: invalid client certificate: tls: private key does not match public key",
+		},
+		"pkcs12_not_base64": {
+			config:  &config.TLSConfig{ClientPKCS12: "not base64!"},
+			wantErr: "invalid TLS config: the PKCS #12 client certificate is not base64-encoded: illegal base64 data at input byte 3",
+		},
+		"pkcs12_wrong_password": {
+			config: &config.TLSConfig{
+				ClientPKCS12:         clientCert.pkcs12(t, "secret"),
+				ClientPKCS12Password: "wrong",
+			},
+			wantErr: "invalid TLS config: invalid PKCS #12 client certificate: pkcs12: decryption password incorrect",
+		},
+		"ca_certificates_without_certificates": {
+			config:  &config.TLSConfig{CACertificates: clientCert.keyPEM(t)},
+			wantErr: "invalid TLS config: the CA certificates do not contain any PEM-encoded certificate",
+		},
+		"additional_ca_certificates_without_certificates": {
+			config:  &config.TLSConfig{AdditionalCACertificates: "ca"},
+			wantErr: "invalid TLS config: the additional CA certificates do not contain any PEM-encoded certificate",
+		},
+		"pinned_public_key_not_base64": {
+			config:  &config.TLSConfig{PinnedPublicKeys: []string{"not base64!"}},
+			wantErr: `invalid TLS config: the pinned public key "not base64!" is not a base64-encoded SHA-256 hash`,
+		},
+		"pinned_public_key_not_sha256": {
+			config:  &config.TLSConfig{PinnedPublicKeys: []string{base64.StdEncoding.EncodeToString([]byte("sha1"))}},
+			wantErr: `invalid TLS config: the pinned public key "c2hhMQ==" is not a base64-encoded SHA-256 hash`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, gotErr := transport.NewTLSConfig(nil, tt.config)
+
+			if gotErr == nil || gotErr.Error() != tt.wantErr {
+				t.Fatalf("gotErr: %v, wantErr: %s", gotErr, tt.wantErr)
+			}
+
+			var configErr *transport.ConfigError
+			if !errors.As(gotErr, &configErr) {
+				t.Errorf("gotErr: %T, wantErr: *transport.ConfigError", gotErr)
+			}
+		})
+	}
+}
+
+func TestRegistryClient(t *testing.T) {
+	ca := newTestCertificate(t, "Test CA", nil)
+	clientCert := newTestCertificate(t, "adapter", ca)
+	rotatedClientCert := newTestCertificate(t, "adapter", ca)
+
+	var registry transport.Registry
+
+	base := &http.Client{Timeout: time.Minute}
+
+	cfg := &config.TLSConfig{
+		ClientCertificate: clientCert.certificatePEM(),
+		ClientKey:         clientCert.keyPEM(t),
+	}
+
+	client, err := registry.Client("https://scim.example.com", base, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if client == base || client.Timeout != base.Timeout {
+		t.Error("The client is not a copy of the base client")
+	}
+
+	// An equal config returns the same client.
+	sameClient, _ := registry.Client("https://scim.example.com", base, &config.TLSConfig{
+		ClientCertificate: clientCert.certificatePEM(),
+		ClientKey:         clientCert.keyPEM(t),
+	})
+
+	if sameClient != client {
+		t.Error("The client of an equal config was not reused")
+	}
+
+	// Another address has its own client.
+	if otherClient, _ := registry.Client("https://other.example.com", base, cfg); otherClient == client {
+		t.Error("The client of another address was reused")
+	}
+
+	// A changed config has its own client.
+	rotatedClient, _ := registry.Client("https://scim.example.com", base, &config.TLSConfig{
+		ClientCertificate: rotatedClientCert.certificatePEM(),
+		ClientKey:         rotatedClientCert.keyPEM(t),
+	})
+
+	if rotatedClient == client {
+		t.Error("The client of a changed config was reused")
+	}
+
+	// The client of the previous config was evicted, so it is not reused once the config is changed back.
+	if previousClient, _ := registry.Client("https://scim.example.com", base, cfg); previousClient == client {
+		t.Error("The client of the previous config was not evicted")
+	}
+
+	// The client of another address is kept.
+	if otherClient, _ := registry.Client("https://other.example.com", base, cfg); otherClient == client {
+		t.Error("The client of another address was reused")
+	}
+
+	// Changed pinned public keys have their own client.
+	pinnedCfg := &config.TLSConfig{PinnedPublicKeys: []string{base64.StdEncoding.EncodeToString(make([]byte, 32))}}
+
+	pinnedClient, _ := registry.Client("https://scim.example.com", base, pinnedCfg)
+
+	// The pinned public keys of the config are modified in place.
+	pinnedCfg.PinnedPublicKeys[0] = base64.StdEncoding.EncodeToString(append(make([]byte, 31), 1))
+
+	if repinnedClient, _ := registry.Client("https://scim.example.com", base, pinnedCfg); repinnedClient == pinnedClient {
+		t.Error("The client of changed pinned public keys was reused")
+	}
+
+	// Without a config, the base client is returned.
+	if baseClient, _ := registry.Client("https://scim.example.com", base, nil); baseClient != base {
+		t.Error("The base client was not returned without a config")
+	}
+
+	// An invalid config is not cached.
+	if _, err := registry.Client("https://scim.example.com", base, &config.TLSConfig{ClientKey: "key"}); err == nil {
+		t.Error("Expected an invalid config to fail")
+	}
+}
+
+func TestRegistryClientConfigChange(t *testing.T) {
+	closed := make(chan struct{}, 1)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed <- struct{}{}
+		}
+	}
+
+	server.StartTLS()
+	defer server.Close()
+
+	var registry transport.Registry
+
+	pin := sha256.Sum256(server.Certificate().RawSubjectPublicKeyInfo)
+	cfg := &config.TLSConfig{PinnedPublicKeys: []string{base64.StdEncoding.EncodeToString(pin[:])}}
+
+	client, err := registry.Client(server.URL, server.Client(), cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	// Changing the config evicts the previous client, closing its idle connection.
+	changedCfg := &config.TLSConfig{PinnedPublicKeys: append(cfg.PinnedPublicKeys, base64.StdEncoding.EncodeToString(make([]byte, 32)))}
+
+	if changedClient, _ := registry.Client(server.URL, server.Client(), changedCfg); changedClient == client {
+		t.Fatal("The client of a changed config was reused")
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Error("The idle connection of the previous client was not closed")
+	}
+}
+
+func TestRegistryClientEviction(t *testing.T) {
+	registry := &transport.Registry{MaxSize: 2}
+
+	base := &http.Client{}
+
+	configs := make([]*config.TLSConfig, 3)
+	for i := range configs {
+		configs[i] = &config.TLSConfig{PinnedPublicKeys: []string{base64.StdEncoding.EncodeToString(append(make([]byte, 31), byte(i)))}}
+	}
+
+	a, _ := registry.Client("https://a.example.com", base, configs[0])
+	b, _ := registry.Client("https://b.example.com", base, configs[1])
+
+	// Using a makes b the least recently used client.
+	registry.Client("https://a.example.com", base, configs[0])
+	registry.Client("https://c.example.com", base, configs[2])
+
+	if got, _ := registry.Client("https://a.example.com", base, configs[0]); got != a {
+		t.Error("The client of a was evicted instead of the least recently used client")
+	}
+
+	if got, _ := registry.Client("https://b.example.com", base, configs[1]); got == b {
+		t.Error("The least recently used client of b was not evicted")
+	}
+}
+
+func TestTokenEndpointConfig(t *testing.T) {
+	tests := map[string]struct {
+		config *config.TLSConfig
+		want   *config.TLSConfig
+	}{
+		"nil": {},
+		"client_certificate_and_additional_ca_certificates_are_kept": {
+			config: &config.TLSConfig{
+				ClientCertificate:        "certificate",
+				ClientKey:                "key",
+				AdditionalCACertificates: "additional",
+			},
+			want: &config.TLSConfig{
+				ClientCertificate:        "certificate",
+				ClientKey:                "key",
+				AdditionalCACertificates: "additional",
+			},
+		},
+		"ca_certificates_are_removed": {
+			config: &config.TLSConfig{
+				ClientPKCS12:   "pkcs12",
+				CACertificates: "ca",
+			},
+			want: &config.TLSConfig{
+				ClientPKCS12: "pkcs12",
+			},
+		},
+		"pinned_public_keys_are_removed": {
+			config: &config.TLSConfig{
+				AdditionalCACertificates: "additional",
+				PinnedPublicKeys:         []string{"pin"},
+			},
+			want: &config.TLSConfig{
+				AdditionalCACertificates: "additional",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := transport.TokenEndpointConfig(tt.config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got: %+v, want: %+v", got, tt.want)
+			}
+		})
+	}
+}